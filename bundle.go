@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/armsnyder/openapi-language-server/internal/analysis"
+)
+
+// bundleCommand bundles a spec that is split across files into a single
+// document, with the targets of external $refs copied into its components.
+func bundleCommand(args []string) int {
+	flags := flag.NewFlagSet("bundle", flag.ExitOnError)
+	output := flags.String("o", "", "Write the bundle to a file instead of standard output")
+	format := flags.String("format", "", "Output format, yaml or json (default: inferred from the output file, or yaml)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: openapi-language-server bundle [flags] root.yaml")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	bundleFormat := analysis.BundleFormat(*format)
+	if bundleFormat == "" {
+		bundleFormat = analysis.BundleFormatFor(*output)
+	}
+
+	root, err := filepath.Abs(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 2
+	}
+
+	uri := (&url.URL{Scheme: "file", Path: filepath.ToSlash(root)}).String()
+
+	bundled, err := analysis.Bundle(uri, bundleFormat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error bundling %s: %v\n", flags.Arg(0), err)
+		return 1
+	}
+
+	if *output == "" {
+		_, _ = os.Stdout.Write(bundled)
+		return 0
+	}
+
+	if err := os.WriteFile(*output, bundled, 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, "Error writing file:", err)
+		return 2
+	}
+
+	return 0
+}
@@ -0,0 +1,397 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/armsnyder/openapi-language-server/internal/analysis"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+// checkCommand runs the diagnostics that the language server publishes, so
+// that CI sees the same problems as the editor. Without files, it checks every
+// YAML file beneath the current directory. Remote $refs are only checked on
+// the hosts that are allowed by a flag, as they are by the remote settings of
+// the editor.
+func checkCommand(args []string) int {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	format := flags.String("format", "text", "Output format: text, json or sarif")
+	remoteAllow := flags.String("remote-allow", "", "Comma-separated glob patterns of the hosts that remote $refs are fetched from")
+	offline := flags.Bool("offline", false, "Only use remote documents that are already cached")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: openapi-language-server check [flags] [files...]")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+
+	write, ok := checkFormats[*format]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown format %q\n", *format)
+		flags.Usage()
+		return 2
+	}
+
+	settings := analysis.Settings{Remote: analysis.RemoteSettings{Offline: *offline}}
+	if *remoteAllow != "" {
+		settings.Remote.Allow = strings.Split(*remoteAllow, ",")
+	}
+
+	options, err := json.Marshal(settings)
+	if err == nil {
+		_, err = analysis.ParseSettings(options)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid flags:", err)
+		flags.Usage()
+		return 2
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		var err error
+		if paths, err = findYAMLFiles("."); err != nil {
+			fmt.Fprintln(os.Stderr, "Error finding files:", err)
+			return 2
+		}
+	}
+
+	results, err := check(paths, options)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 2
+	}
+
+	if err := write(os.Stdout, results); err != nil {
+		fmt.Fprintln(os.Stderr, "Error writing output:", err)
+		return 2
+	}
+
+	for _, r := range results {
+		if r.Diagnostic.Severity == types.SeverityError {
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// checkResult is a diagnostic of a checked file.
+type checkResult struct {
+	Path       string
+	Diagnostic types.Diagnostic
+}
+
+// check opens the files in a handler, as an editor would, and returns their
+// diagnostics in the order of the files, followed by the errors in config
+// files. The current directory is the root of the workspace, and the settings
+// are passed to the handler as initialization options.
+func check(paths []string, settings json.RawMessage) ([]checkResult, error) {
+	var h analysis.Handler
+	defer h.Close()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.HandleInitialize(types.InitializeParams{
+		RootURI:               (&url.URL{Scheme: "file", Path: filepath.ToSlash(wd)}).String(),
+		InitializationOptions: settings,
+	}); err != nil {
+		return nil, err
+	}
+
+	uris := make(map[string]string, len(paths))
+
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+
+		uri := (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String()
+		uris[uri] = path
+
+		if err := h.HandleOpen(types.DidOpenTextDocumentParams{
+			TextDocument: types.TextDocumentItem{URI: uri, Text: string(src)},
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	published, err := h.Diagnostics()
+	if err != nil {
+		return nil, err
+	}
+
+	// Remote documents are fetched in the background, as they are for the
+	// editor, and $refs to them are not reported until they arrive. Wait for
+	// the fetches, including those of documents that fetched documents refer
+	// to. The diagnostics that are published later replace the earlier ones.
+	for h.Fetching() {
+		task := <-h.Tasks()
+		task()
+
+		more, err := h.Diagnostics()
+		if err != nil {
+			return nil, err
+		}
+		published = append(published, more...)
+	}
+
+	byPath := map[string][]types.Diagnostic{}
+	var others []string
+
+	for _, p := range published {
+		path, ok := uris[p.URI]
+		if !ok {
+			// The diagnostics of files that were not opened are the errors
+			// in config files.
+			u, err := url.Parse(p.URI)
+			if err != nil {
+				continue
+			}
+
+			if path, err = filepath.Rel(wd, filepath.FromSlash(u.Path)); err != nil {
+				path = filepath.FromSlash(u.Path)
+			}
+
+			if _, ok := byPath[path]; !ok {
+				others = append(others, path)
+			}
+		}
+
+		byPath[path] = p.Diagnostics
+	}
+
+	var results []checkResult
+
+	for _, path := range append(paths, others...) {
+		for _, d := range byPath[path] {
+			results = append(results, checkResult{Path: path, Diagnostic: d})
+		}
+		delete(byPath, path)
+	}
+
+	return results, nil
+}
+
+// findYAMLFiles returns the YAML files beneath a directory, skipping hidden
+// directories.
+func findYAMLFiles(root string) ([]string, error) {
+	var paths []string
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if path != root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+			paths = append(paths, path)
+		}
+
+		return nil
+	})
+
+	return paths, err
+}
+
+var checkFormats = map[string]func(w io.Writer, results []checkResult) error{
+	"text":  writeCheckText,
+	"json":  writeCheckJSON,
+	"sarif": writeCheckSARIF,
+}
+
+var severityNames = map[types.DiagnosticSeverity]string{
+	types.SeverityError:       "error",
+	types.SeverityWarning:     "warning",
+	types.SeverityInformation: "info",
+	types.SeverityHint:        "hint",
+}
+
+// writeCheckText writes one line per diagnostic, with 1-based positions, in the
+// style of compilers so that editors and terminals can link to them.
+func writeCheckText(w io.Writer, results []checkResult) error {
+	for _, r := range results {
+		d := r.Diagnostic
+		message := d.Message
+		if d.Code != "" {
+			message += " (" + d.Code + ")"
+		}
+
+		if _, err := fmt.Fprintf(w, "%s:%d:%d: %s: %s\n", r.Path, d.Range.Start.Line+1, d.Range.Start.Character+1, severityNames[d.Severity], message); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type jsonCheckResult struct {
+	Path      string `json:"path"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	EndLine   int    `json:"endLine"`
+	EndColumn int    `json:"endColumn"`
+	Severity  string `json:"severity"`
+	Code      string `json:"code,omitempty"`
+	Message   string `json:"message"`
+}
+
+// writeCheckJSON writes a JSON array of diagnostics, with 1-based positions.
+func writeCheckJSON(w io.Writer, results []checkResult) error {
+	out := make([]jsonCheckResult, len(results))
+
+	for i, r := range results {
+		d := r.Diagnostic
+		out[i] = jsonCheckResult{
+			Path:      r.Path,
+			Line:      d.Range.Start.Line + 1,
+			Column:    d.Range.Start.Character + 1,
+			EndLine:   d.Range.End.Line + 1,
+			EndColumn: d.Range.End.Character + 1,
+			Severity:  severityNames[d.Severity],
+			Code:      d.Code,
+			Message:   d.Message,
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(out)
+}
+
+// SARIF is the Static Analysis Results Interchange Format, which code scanning
+// tools such as GitHub's can display. Only the properties that are needed to
+// locate and describe each result are written.
+// See https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html.
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules,omitempty"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId,omitempty"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+var sarifLevels = map[types.DiagnosticSeverity]string{
+	types.SeverityError:       "error",
+	types.SeverityWarning:     "warning",
+	types.SeverityInformation: "note",
+	types.SeverityHint:        "note",
+}
+
+func writeCheckSARIF(w io.Writer, results []checkResult) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "openapi-language-server",
+			Version:        version,
+			InformationURI: "https://github.com/armsnyder/openapi-language-server",
+		}},
+		Results: []sarifResult{},
+	}
+
+	rules := map[string]bool{}
+
+	for _, r := range results {
+		d := r.Diagnostic
+
+		if d.Code != "" && !rules[d.Code] {
+			rules[d.Code] = true
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: d.Code})
+		}
+
+		run.Results = append(run.Results, sarifResult{
+			RuleID:  d.Code,
+			Level:   sarifLevels[d.Severity],
+			Message: sarifMessage{Text: d.Message},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(r.Path)},
+				Region: sarifRegion{
+					StartLine:   d.Range.Start.Line + 1,
+					StartColumn: d.Range.Start.Character + 1,
+					EndLine:     d.Range.End.Line + 1,
+					EndColumn:   d.Range.End.Character + 1,
+				},
+			}}},
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const checkSpec = `openapi: 3.0.0
+paths:
+  /pets/{petId}:
+    get: {}
+`
+
+// chdir changes the current directory for the rest of the test.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		if err := os.Chdir(wd); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// writeTestFile writes a file beneath the current directory.
+func writeTestFile(t *testing.T, name, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(name, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// runCommand runs a command with the given standard input, and returns what
+// it writes to standard output together with its exit status.
+func runCommand(t *testing.T, command func(args []string) int, stdin string, args ...string) (string, int) {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	in, err := os.Create(filepath.Join(dir, "stdin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+
+	if _, err := in.WriteString(stdin); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := in.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := os.Create(filepath.Join(dir, "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	stdinFile, stdoutFile := os.Stdin, os.Stdout
+	os.Stdin, os.Stdout = in, out
+	status := command(args)
+	os.Stdin, os.Stdout = stdinFile, stdoutFile
+
+	if _, err := out.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := io.ReadAll(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(content), status
+}
+
+// runCheck runs the check command, and returns what it writes to standard
+// output together with its exit status.
+func runCheck(t *testing.T, args ...string) (string, int) {
+	t.Helper()
+
+	return runCommand(t, checkCommand, "", args...)
+}
+
+func TestCheckCommand_Text(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeTestFile(t, "api.yaml", checkSpec)
+
+	got, status := runCheck(t, "api.yaml")
+
+	want := "api.yaml:3:3: error: Path parameter \"petId\" is not defined (missing-path-parameter)\n"
+	if got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+
+	if status != 1 {
+		t.Errorf("status = %d, want 1", status)
+	}
+}
+
+func TestCheckCommand_JSON(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeTestFile(t, "api.yaml", checkSpec)
+
+	out, _ := runCheck(t, "-format", "json", "api.yaml")
+
+	var got []jsonCheckResult
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out)
+	}
+
+	want := []jsonCheckResult{{
+		Path:      "api.yaml",
+		Line:      3,
+		Column:    3,
+		EndLine:   3,
+		EndColumn: 16,
+		Severity:  "error",
+		Code:      "missing-path-parameter",
+		Message:   `Path parameter "petId" is not defined`,
+	}}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("results = %+v, want %+v", got, want)
+	}
+}
+
+func TestCheckCommand_SARIF(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeTestFile(t, "api.yaml", checkSpec)
+
+	out, _ := runCheck(t, "-format", "sarif", "api.yaml")
+
+	var got sarifLog
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out)
+	}
+
+	if got.Version != "2.1.0" || len(got.Runs) != 1 {
+		t.Fatalf("log = %+v, want a single run of SARIF 2.1.0", got)
+	}
+
+	run := got.Runs[0]
+
+	if want := []sarifRule{{ID: "missing-path-parameter"}}; !reflect.DeepEqual(run.Tool.Driver.Rules, want) {
+		t.Errorf("rules = %+v, want %+v", run.Tool.Driver.Rules, want)
+	}
+
+	want := []sarifResult{{
+		RuleID:  "missing-path-parameter",
+		Level:   "error",
+		Message: sarifMessage{Text: `Path parameter "petId" is not defined`},
+		Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+			ArtifactLocation: sarifArtifactLocation{URI: "api.yaml"},
+			Region:           sarifRegion{StartLine: 3, StartColumn: 3, EndLine: 3, EndColumn: 16},
+		}}},
+	}}
+
+	if !reflect.DeepEqual(run.Results, want) {
+		t.Errorf("results = %+v, want %+v", run.Results, want)
+	}
+}
+
+func TestCheckCommand_NoErrors(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeTestFile(t, "api.yaml", `openapi: 3.0.0
+paths:
+  /pets:
+    get: {}
+`)
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{format: "text", want: ""},
+		{format: "json", want: "[]\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got, status := runCheck(t, "-format", tt.format, "api.yaml")
+
+			if got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+
+			if status != 0 {
+				t.Errorf("status = %d, want 0", status)
+			}
+		})
+	}
+}
+
+func TestCheckCommand_UnknownFormat(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeTestFile(t, "api.yaml", checkSpec)
+
+	if _, status := runCheck(t, "-format", "xml", "api.yaml"); status != 2 {
+		t.Errorf("status = %d, want 2", status)
+	}
+}
+
+func TestCheckCommand_DefaultFiles(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeTestFile(t, "api.yaml", checkSpec)
+	writeTestFile(t, filepath.Join("specs", "store.yml"), checkSpec)
+	writeTestFile(t, filepath.Join(".github", "workflows", "ci.yaml"), checkSpec)
+	writeTestFile(t, "notes.txt", checkSpec)
+
+	got, status := runCheck(t)
+
+	// Hidden directories and other files are skipped.
+
+	want := "api.yaml:3:3: error: Path parameter \"petId\" is not defined (missing-path-parameter)\n" +
+		filepath.Join("specs", "store.yml") + ":3:3: error: Path parameter \"petId\" is not defined (missing-path-parameter)\n"
+	if got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+
+	if status != 1 {
+		t.Errorf("status = %d, want 1", status)
+	}
+}
+
+func TestCheckCommand_RemoteRefs(t *testing.T) {
+	chdir(t, t.TempDir())
+
+	// Remote documents are cached under the cache directory of the user.
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/common.yaml", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`Error:
+  $ref: ./nested.yaml#/Error
+`))
+	})
+	mux.HandleFunc("/nested.yaml", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`Error:
+  type: object
+`))
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	writeTestFile(t, "api.yaml", `openapi: 3.0.0
+components:
+  schemas:
+    Error:
+      $ref: `+server.URL+`/common.yaml#/Error
+    Missing:
+      $ref: `+server.URL+`/common.yaml#/Missing
+    Gone:
+      $ref: `+server.URL+`/gone.yaml#/Error
+`)
+
+	tests := []struct {
+		name       string
+		args       []string
+		want       string
+		wantStatus int
+	}{
+		{
+			name: "fetched",
+			args: []string{"-remote-allow", "127.0.0.1"},
+			want: "api.yaml:7:13: error: Unresolved $ref: /Missing not found in " + server.URL + "/common.yaml (unresolved-ref)\n" +
+				"api.yaml:9:13: error: Unresolved $ref: error fetching " + server.URL + "/gone.yaml: unexpected status 404 Not Found (unresolved-ref)\n",
+			wantStatus: 1,
+		},
+		{
+			name: "not allowed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, status := runCheck(t, append(tt.args, "api.yaml")...)
+
+			if got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestCheckCommand_InvalidRemoteAllow(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeTestFile(t, "api.yaml", checkSpec)
+
+	if _, status := runCheck(t, "-remote-allow", "[", "api.yaml"); status != 2 {
+		t.Errorf("status = %d, want 2", status)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/armsnyder/openapi-language-server/internal/analysis"
+)
+
+// formatCommand formats OpenAPI documents with the same formatter that the
+// language server uses, so that editors and CI agree. Without files, it
+// formats standard input as YAML. JSON files are skipped, as in the editor.
+func formatCommand(args []string) int {
+	flags := flag.NewFlagSet("format", flag.ExitOnError)
+	write := flags.Bool("w", false, "Write the result to each file instead of standard output")
+	list := flags.Bool("l", false, "List the files whose formatting differs, and exit with status 1 if there are any")
+	tabSize := flags.Int("tab-size", 2, "Number of spaces per indentation level")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: openapi-language-server format [flags] [files...]")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		src, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error reading standard input:", err)
+			return 2
+		}
+
+		formatted, err := analysis.Format(src, *tabSize)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error formatting standard input:", err)
+			return 2
+		}
+
+		_, _ = os.Stdout.Write(formatted)
+		return 0
+	}
+
+	status := 0
+
+	for _, path := range flags.Args() {
+		if !analysis.CanFormat(path) {
+			fmt.Fprintf(os.Stderr, "Skipping %s: only YAML documents are formatted\n", path)
+			continue
+		}
+
+		src, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error reading file:", err)
+			status = 2
+			continue
+		}
+
+		formatted, err := analysis.Format(src, *tabSize)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error formatting %s: %v\n", path, err)
+			status = 2
+			continue
+		}
+
+		changed := !bytes.Equal(src, formatted)
+
+		switch {
+		case *list:
+			if changed {
+				fmt.Fprintln(os.Stdout, path)
+				status = max(status, 1)
+			}
+		case *write:
+			if changed {
+				if err := os.WriteFile(path, formatted, 0o644); err != nil {
+					fmt.Fprintln(os.Stderr, "Error writing file:", err)
+					status = 2
+				}
+			}
+		default:
+			_, _ = os.Stdout.Write(formatted)
+		}
+	}
+
+	return status
+}
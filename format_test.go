@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+const (
+	unformatted = "openapi: 3.0.0\npaths:\n    /pets:\n        get: {}\n"
+	formatted   = "openapi: 3.0.0\npaths:\n  /pets:\n    get: {}\n"
+)
+
+// readTestFile reads a file beneath the current directory.
+func readTestFile(t *testing.T, name string) string {
+	t.Helper()
+
+	content, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(content)
+}
+
+func TestFormatCommand_Stdout(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeTestFile(t, "api.yaml", unformatted)
+
+	got, status := runCommand(t, formatCommand, "", "api.yaml")
+
+	if got != formatted || status != 0 {
+		t.Errorf("output = %q, status %d, want %q, status 0", got, status, formatted)
+	}
+
+	if content := readTestFile(t, "api.yaml"); content != unformatted {
+		t.Errorf("file = %q, want it unchanged", content)
+	}
+}
+
+func TestFormatCommand_Stdin(t *testing.T) {
+	got, status := runCommand(t, formatCommand, unformatted)
+
+	if got != formatted || status != 0 {
+		t.Errorf("output = %q, status %d, want %q, status 0", got, status, formatted)
+	}
+}
+
+func TestFormatCommand_Write(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeTestFile(t, "api.yaml", unformatted)
+	writeTestFile(t, "pet.yaml", formatted)
+
+	got, status := runCommand(t, formatCommand, "", "-w", "api.yaml", "pet.yaml")
+
+	if got != "" || status != 0 {
+		t.Errorf("output = %q, status %d, want no output, status 0", got, status)
+	}
+
+	for _, name := range []string{"api.yaml", "pet.yaml"} {
+		if content := readTestFile(t, name); content != formatted {
+			t.Errorf("%s = %q, want %q", name, content, formatted)
+		}
+	}
+}
+
+func TestFormatCommand_List(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeTestFile(t, "api.yaml", unformatted)
+	writeTestFile(t, "pet.yaml", formatted)
+
+	got, status := runCommand(t, formatCommand, "", "-l", "api.yaml", "pet.yaml")
+
+	if got != "api.yaml\n" || status != 1 {
+		t.Errorf("output = %q, status %d, want %q, status 1", got, status, "api.yaml\n")
+	}
+
+	if content := readTestFile(t, "api.yaml"); content != unformatted {
+		t.Errorf("file = %q, want it unchanged", content)
+	}
+
+	if got, status := runCommand(t, formatCommand, "", "-l", "pet.yaml"); got != "" || status != 0 {
+		t.Errorf("output = %q, status %d, want no output, status 0", got, status)
+	}
+}
+
+func TestFormatCommand_JSON(t *testing.T) {
+	chdir(t, t.TempDir())
+
+	// The formatter only understands YAML, so JSON files are left alone, as
+	// they are in the editor.
+
+	src := "{\n  \"openapi\": \"3.0.0\",\n  \"paths\": {\n    \"/pets\": {}\n  }\n}\n"
+	writeTestFile(t, "api.json", src)
+
+	for _, args := range [][]string{
+		{"-tab-size", "4", "api.json"},
+		{"-w", "-tab-size", "4", "api.json"},
+		{"-l", "-tab-size", "4", "api.json"},
+	} {
+		got, status := runCommand(t, formatCommand, "", args...)
+
+		if got != "" || status != 0 {
+			t.Errorf("format %v: output = %q, status %d, want no output, status 0", args, got, status)
+		}
+
+		if content := readTestFile(t, "api.json"); content != src {
+			t.Errorf("format %v: file = %q, want it unchanged", args, content)
+		}
+	}
+}
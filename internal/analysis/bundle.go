@@ -0,0 +1,683 @@
+package analysis
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/armsnyder/openapi-language-server/internal/analysis/yaml"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+// Bundling produces a single document from a spec that is split across files.
+// The targets of external $refs are copied into the components of the root
+// document, and the $refs are rewritten to point at the copies. A target whose
+// kind of component cannot be determined from the position of the $ref, such
+// as a path item, is copied in place of the $ref instead.
+
+// BundleFormat is the output format of a bundled document.
+type BundleFormat string
+
+const (
+	BundleYAML BundleFormat = "yaml"
+	BundleJSON BundleFormat = "json"
+)
+
+// bundleCommand is the workspace command that bundles a spec. Its argument is
+// a bundleArguments object.
+const bundleCommand = "openapi.bundle"
+
+type bundleArguments struct {
+	// URI is the root document of the spec.
+	URI string `json:"uri"`
+
+	// Output is the URI of the file to write the bundle to, which must be in a
+	// workspace folder. If it is empty, the bundle is returned to the client
+	// instead.
+	Output string `json:"output,omitempty"`
+
+	// Format is the output format. If it is empty, it is inferred from the
+	// extension of the output file, defaulting to YAML.
+	Format BundleFormat `json:"format,omitempty"`
+}
+
+// bundleResult is the result of the bundle command.
+type bundleResult struct {
+	Content string `json:"content,omitempty"`
+	Output  string `json:"output,omitempty"`
+}
+
+func (h *Handler) HandleExecuteCommand(params types.ExecuteCommandParams) (any, error) {
+	if params.Command != bundleCommand {
+		h.logf("HandleExecuteCommand: Unknown command %q", params.Command)
+		return nil, nil
+	}
+
+	var args bundleArguments
+	if len(params.Arguments) != 1 {
+		h.logf("HandleExecuteCommand: Expected 1 argument for %s, got %d", params.Command, len(params.Arguments))
+		return nil, nil
+	}
+	if err := json.Unmarshal(params.Arguments[0], &args); err != nil {
+		h.logf("HandleExecuteCommand: Invalid arguments for %s: %v", params.Command, err)
+		return nil, nil
+	}
+
+	format := args.Format
+	if format == "" {
+		format = BundleFormatFor(args.Output)
+	}
+
+	content, err := h.bundle(args.URI, format)
+	if err != nil {
+		h.showf("Error bundling %q: %v", args.URI, err)
+		return nil, nil
+	}
+
+	if args.Output == "" {
+		return bundleResult{Content: string(content)}, nil
+	}
+
+	path, err := uriToPath(args.Output)
+	if err != nil {
+		h.showf("Invalid output %q: %v", args.Output, err)
+		return nil, nil
+	}
+
+	// The client may be a page in a browser, or otherwise not trusted with the
+	// whole file system, so the bundle is only written into the workspace.
+	if !h.inWorkspace(path) {
+		h.showf("Cannot write the bundle to %q, which is outside of the workspace folders", args.Output)
+		return nil, nil
+	}
+
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		h.showf("Error writing bundle: %v", err)
+		return nil, nil
+	}
+
+	return bundleResult{Output: args.Output}, nil
+}
+
+// inWorkspace returns true if a file path is inside one of the workspace
+// folders, after resolving ".." elements and symbolic links.
+func (h *Handler) inWorkspace(name string) bool {
+	dir, err := filepath.EvalSymlinks(filepath.Dir(filepath.Clean(name)))
+	if err != nil {
+		return false
+	}
+
+	for _, folder := range h.folders {
+		root, err := uriToPath(folder)
+		if err != nil {
+			continue
+		}
+
+		if root, err = filepath.EvalSymlinks(root); err != nil {
+			continue
+		}
+
+		rel, err := filepath.Rel(root, dir)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// BundleFormatFor returns the bundle format for an output file name, based on
+// its extension.
+func BundleFormatFor(name string) BundleFormat {
+	if strings.EqualFold(path.Ext(name), ".json") {
+		return BundleJSON
+	}
+
+	return BundleYAML
+}
+
+// Bundle bundles the spec with the given root document from disk.
+func Bundle(rootURI string, format BundleFormat) ([]byte, error) {
+	var h Handler
+	return h.bundle(rootURI, format)
+}
+
+func (h *Handler) bundle(rootURI string, format BundleFormat) ([]byte, error) {
+	b := bundler{
+		h:       h,
+		rootURI: rootURI,
+		names:   map[string]string{},
+		taken:   map[string]map[string]bool{},
+		entries: map[string][][]string{},
+	}
+
+	out, err := b.run()
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case BundleYAML, "":
+		return out, nil
+	case BundleJSON:
+		return yaml.ToJSON(out)
+	default:
+		return nil, fmt.Errorf("unknown bundle format %q", format)
+	}
+}
+
+// loadText returns the contents of the document with the given URI. Open files
+// take precedence over the contents on disk.
+func (h *Handler) loadText(uri string) ([]byte, error) {
+	if f, ok := h.files[uri]; ok {
+		if f.language != languageYAML {
+			return nil, fmt.Errorf("%s: bundling JSON documents is not supported", uri)
+		}
+		return f.file.Bytes(), nil
+	}
+
+	if detectLanguage("", uri) != languageYAML {
+		return nil, fmt.Errorf("%s: bundling JSON documents is not supported", uri)
+	}
+
+	path, err := uriToPath(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.ReadFile(path)
+}
+
+// componentSections maps the kinds of OpenAPI 3 components to the Swagger 2
+// root keys that hold them. Kinds that are missing have no Swagger 2
+// equivalent.
+var componentSections = map[string]string{
+	"schemas":    "definitions",
+	"parameters": "parameters",
+	"responses":  "responses",
+}
+
+type bundler struct {
+	h        *Handler
+	rootURI  string
+	swagger2 bool
+	step     int
+
+	// names maps each copied target to its component name.
+	names map[string]string
+
+	// taken holds the component names that are in use, by kind.
+	taken map[string]map[string]bool
+
+	// order and entries hold the lines of the copied components, by kind, in
+	// the order that they were first referenced.
+	order   []string
+	entries map[string][][]string
+
+	queue []pendingComponent
+}
+
+// pendingComponent is a target that has been named but not yet copied.
+type pendingComponent struct {
+	kind   string
+	name   string
+	target reference
+}
+
+// source is a document that lines are copied from.
+type source struct {
+	uri      string
+	document yaml.Document
+	lines    []string
+}
+
+func (b *bundler) load(uri string) (source, error) {
+	document, err := b.h.loadDocument(uri)
+	if err != nil {
+		return source{}, err
+	}
+
+	text, err := b.h.loadText(uri)
+	if err != nil {
+		return source{}, err
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(text), "\n"), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSuffix(lines[i], "\r")
+	}
+
+	return source{uri: uri, document: document, lines: lines}, nil
+}
+
+func (b *bundler) run() ([]byte, error) {
+	root, err := b.load(b.rootURI)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isRoot(root.document) {
+		return nil, fmt.Errorf("%s is not an OpenAPI root document", b.rootURI)
+	}
+
+	b.swagger2 = root.document.Root["swagger"] != nil
+	b.step = 2
+	if paths := root.document.Root["paths"]; paths != nil {
+		b.step = indentStep(paths)
+	}
+
+	// Copies must not take the names of the components of the root document.
+	kinds := []string{"schemas", "parameters", "responses"}
+	if components := root.document.Root["components"]; components != nil && !b.swagger2 {
+		kinds = kinds[:0]
+		for kind := range components.Children {
+			kinds = append(kinds, kind)
+		}
+	}
+
+	for _, kind := range kinds {
+		if section := b.section(root.document, kind); section != nil {
+			for name := range section.Children {
+				b.uniqueName(kind, name)
+			}
+		}
+	}
+
+	out, err := b.copyLines(root, 0, len(root.lines)-1, 0, false, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for len(b.queue) > 0 {
+		c := b.queue[0]
+		b.queue = b.queue[1:]
+
+		lines, err := b.copyComponent(c)
+		if err != nil {
+			return nil, err
+		}
+
+		if b.entries[c.kind] == nil {
+			b.order = append(b.order, c.kind)
+		}
+		b.entries[c.kind] = append(b.entries[c.kind], lines)
+	}
+
+	return b.insertComponents(out)
+}
+
+// section returns the line that holds the components of the given kind, or nil
+// if there is none.
+func (b *bundler) section(document yaml.Document, kind string) *yaml.Line {
+	if b.swagger2 {
+		return document.Root[componentSections[kind]]
+	}
+
+	if components := document.Root["components"]; components != nil {
+		return components.Children[kind]
+	}
+
+	return nil
+}
+
+// copyLines copies the lines from first to last, inclusive, shifting their
+// indentation by the given amount and rewriting any $refs. The schema flag is
+// set when the lines are within a schema.
+func (b *bundler) copyLines(src source, first, last, shift int, schema bool, depth int) ([]string, error) {
+	var out []string
+
+	for i := first; i <= last && i < len(src.lines); i++ {
+		line := src.document.Lines[i]
+
+		if line.IsBlockScalar() {
+			end := src.document.BlockScalarEnd(line)
+			for j := i; j <= end; j++ {
+				out = append(out, shiftLine(src.lines[j], shift))
+			}
+			i = end
+			continue
+		}
+
+		if line.Key != "$ref" || line.Value == "" {
+			out = append(out, shiftLine(src.lines[i], shift))
+			continue
+		}
+
+		lines, err := b.copyRef(src, line, shift, schema || inSchemaPosition(line, nil), depth)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, lines...)
+	}
+
+	return out, nil
+}
+
+// copyRef copies a $ref line, either rewriting the $ref to point into the
+// bundled document or replacing it with the target.
+func (b *bundler) copyRef(src source, line *yaml.Line, shift int, schema bool, depth int) ([]string, error) {
+	text := shiftLine(src.lines[line.Number], shift)
+	rewrite := func(ref string) []string {
+		return []string{rewriteRef(text, line, shift, ref)}
+	}
+
+	uri, fragment, err := b.h.resolveRef(src.uri, line.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	if uri == b.rootURI {
+		return rewrite("#" + fragment), nil
+	}
+
+	target, err := b.h.resolve(src.uri, line.Value)
+	if err != nil {
+		return nil, fmt.Errorf("unresolved $ref %q in %s: %w", line.Value, src.uri, err)
+	}
+
+	if kind := b.refKind(line, schema); kind != "" {
+		return rewrite(b.componentRef(kind, target)), nil
+	}
+
+	if depth >= maxRefDepth {
+		return nil, fmt.Errorf("too many nested $refs at %q in %s", line.Value, src.uri)
+	}
+
+	// Copy the target in place of the $ref.
+	indent := line.Indent + shift
+	if line.Item {
+		indent += len("- ")
+	}
+
+	lines, err := b.copyTarget(target, indent, schema, depth+1)
+	if err != nil {
+		return nil, err
+	}
+
+	if line.Item {
+		markItem(lines, indent)
+	}
+
+	return lines, nil
+}
+
+// refKind returns the kind of component that a $ref refers to, based on where
+// the $ref is, or "" if the target should be copied in place.
+func (b *bundler) refKind(line *yaml.Line, schema bool) string {
+	holder := line
+	if !line.Item {
+		holder = line.Parent
+	}
+
+	if holder == nil || isComponent(holder) {
+		return ""
+	}
+
+	var kind string
+
+	switch container := holder.Parent; {
+	case schema:
+		kind = "schemas"
+	case holder.Key == "requestBody":
+		kind = "requestBodies"
+	case container == nil:
+	case container.Key == "parameters" && holder.Item:
+		kind = "parameters"
+	case slices.Contains([]string{"responses", "headers", "examples", "links", "callbacks"}, container.Key) && !holder.Item:
+		kind = container.Key
+	}
+
+	if b.swagger2 && componentSections[kind] == "" {
+		return ""
+	}
+
+	return kind
+}
+
+// componentRef returns the $ref of the component that the target is copied to,
+// naming the component if it is new.
+func (b *bundler) componentRef(kind string, target reference) string {
+	key := kind + " " + target.uri + target.ref()
+
+	name, ok := b.names[key]
+	if !ok {
+		name = b.uniqueName(kind, componentName(target))
+		b.names[key] = name
+		b.queue = append(b.queue, pendingComponent{kind: kind, name: name, target: target})
+	}
+
+	if b.swagger2 {
+		return "#/" + componentSections[kind] + "/" + name
+	}
+
+	return "#/components/" + kind + "/" + name
+}
+
+// ref returns the JSON pointer fragment of the reference.
+func (r reference) ref() string {
+	if r.line == nil {
+		return "#"
+	}
+
+	return r.line.KeyRef()
+}
+
+var invalidNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// componentName returns a name for a copied target, which is its key, or the
+// file name if the target is a whole document.
+func componentName(target reference) string {
+	name := ""
+	if target.line != nil && !target.line.Item {
+		name = target.line.Key
+	} else {
+		name = strings.TrimSuffix(path.Base(target.uri), path.Ext(target.uri))
+	}
+
+	if name = invalidNameChars.ReplaceAllString(name, "_"); name == "" {
+		name = "component"
+	}
+
+	return name
+}
+
+// uniqueName returns the name, with a numeric suffix if it is already taken.
+func (b *bundler) uniqueName(kind, name string) string {
+	if b.taken[kind] == nil {
+		b.taken[kind] = map[string]bool{}
+	}
+
+	unique := name
+	for i := 2; b.taken[kind][unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	b.taken[kind][unique] = true
+
+	return unique
+}
+
+// copyTarget returns the lines of the value of a target, indented by the given
+// amount.
+func (b *bundler) copyTarget(target reference, indent int, schema bool, depth int) ([]string, error) {
+	src, err := b.load(target.uri)
+	if err != nil {
+		return nil, err
+	}
+
+	first, last := 0, len(src.lines)-1
+	if target.line != nil {
+		if target.line.Value != "" || target.line.Item {
+			return nil, fmt.Errorf("cannot copy %s%s in place: only mappings are supported", target.uri, target.ref())
+		}
+		first, last = target.line.Number+1, src.document.End(target.line)
+	}
+
+	base := -1
+	for i := first; i <= last; i++ {
+		if line := src.document.Lines[i]; !line.Empty {
+			base = line.Indent
+			break
+		}
+	}
+	if base < 0 {
+		return nil, nil
+	}
+
+	return b.copyLines(src, first, last, indent-base, schema, depth)
+}
+
+// copyComponent returns the lines of a copied component, starting at indent 0.
+func (b *bundler) copyComponent(c pendingComponent) ([]string, error) {
+	if c.target.line != nil && c.target.line.Value != "" {
+		src, err := b.load(c.target.uri)
+		if err != nil {
+			return nil, err
+		}
+
+		text := src.lines[c.target.line.Number]
+		return []string{c.name + ":" + text[strings.Index(text, ":")+1:]}, nil
+	}
+
+	lines, err := b.copyTarget(c.target, b.step, c.kind == "schemas", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]string{c.name + ":"}, lines...), nil
+}
+
+// insertComponents adds the copied components to the bundled document.
+func (b *bundler) insertComponents(out []string) ([]byte, error) {
+	if len(b.order) == 0 {
+		return []byte(strings.Join(out, "\n") + "\n"), nil
+	}
+
+	document, err := yaml.Parse(strings.NewReader(strings.Join(out, "\n")))
+	if err != nil {
+		return nil, err
+	}
+
+	// Insert from the bottom up, so that earlier insertions do not move the
+	// positions of later ones.
+	type insertion struct {
+		at    int
+		lines []string
+	}
+
+	var insertions []insertion
+	var missing []string
+
+	components := document.Root["components"]
+
+	for _, kind := range b.order {
+		section := b.section(document, kind)
+		if section == nil {
+			if !b.swagger2 {
+				missing = append(missing, strings.Repeat(" ", b.step)+kind+":")
+				missing = append(missing, indentLines(b.entries[kind], 2*b.step)...)
+			} else {
+				missing = append(missing, componentSections[kind]+":")
+				missing = append(missing, indentLines(b.entries[kind], b.step)...)
+			}
+			continue
+		}
+
+		if section.Value != "" {
+			return nil, errors.New("cannot add components to " + section.KeyRef() + ": it is not a block mapping")
+		}
+
+		indent := section.Indent + b.step
+		for _, child := range section.Children {
+			indent = child.Indent
+			break
+		}
+
+		insertions = append(insertions, insertion{
+			at:    document.End(section) + 1,
+			lines: indentLines(b.entries[kind], indent),
+		})
+	}
+
+	if len(missing) > 0 {
+		switch {
+		case b.swagger2:
+			insertions = append(insertions, insertion{at: len(out), lines: missing})
+		case components != nil:
+			insertions = append(insertions, insertion{at: document.End(components) + 1, lines: missing})
+		default:
+			insertions = append(insertions, insertion{at: len(out), lines: append([]string{"components:"}, missing...)})
+		}
+	}
+
+	// Insertions at the same line are applied in reverse, so that they end up
+	// in their original order.
+	slices.Reverse(insertions)
+	slices.SortStableFunc(insertions, func(a, b insertion) int {
+		return b.at - a.at
+	})
+
+	for _, ins := range insertions {
+		out = slices.Insert(out, ins.at, ins.lines...)
+	}
+
+	return []byte(strings.Join(out, "\n") + "\n"), nil
+}
+
+// indentLines joins the lines of the given entries, indented by the given
+// amount.
+func indentLines(entries [][]string, indent int) []string {
+	var result []string
+
+	for _, lines := range entries {
+		for _, line := range lines {
+			result = append(result, shiftLine(line, indent))
+		}
+	}
+
+	return result
+}
+
+// shiftLine changes the indentation of a line by the given amount. Blank lines
+// are left empty, and a line is never unindented past its first character.
+func shiftLine(line string, shift int) string {
+	trimmed := strings.TrimLeft(line, " ")
+	if trimmed == "" {
+		return ""
+	}
+
+	return strings.Repeat(" ", max(0, len(line)-len(trimmed)+shift)) + trimmed
+}
+
+// rewriteRef returns the shifted text of a $ref line with a new value.
+func rewriteRef(text string, line *yaml.Line, shift int, ref string) string {
+	quoted := quoteRef(text, &yaml.Line{
+		Key:   line.Key,
+		Value: line.Value,
+		ValueRange: types.Range{
+			Start: types.Position{Character: line.ValueRange.Start.Character + shift},
+			End:   types.Position{Character: line.ValueRange.End.Character + shift},
+		},
+	})
+
+	return strings.Replace(quoted, `"`+line.Value+`"`, `"`+ref+`"`, 1)
+}
+
+// markItem turns the first entry of copied lines into a sequence item, for a
+// target that is copied in place of a $ref in a sequence.
+func markItem(lines []string, indent int) {
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+
+		lines[i] = line[:indent-len("- ")] + "- " + line[indent:]
+		return
+	}
+}
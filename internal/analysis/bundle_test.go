@@ -0,0 +1,290 @@
+package analysis_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/armsnyder/openapi-language-server/internal/analysis"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+func writeBundleSpec(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "api.yaml"), `openapi: 3.0.0
+info:
+  title: Pets
+  version: 1.0.0
+paths:
+  /pets:
+    $ref: ./paths/pets.yaml
+  /owners:
+    get:
+      parameters:
+        - $ref: ./common.yaml#/parameters/Limit
+      responses:
+        "200":
+          description: OK
+          content:
+            application/json:
+              schema:
+                $ref: ./schemas/owner.yaml
+components:
+  schemas:
+    Pet:
+      type: string
+`)
+	writeFile(t, filepath.Join(dir, "paths", "pets.yaml"), `get:
+  responses:
+    "200":
+      $ref: ../common.yaml#/responses/PetList
+`)
+	writeFile(t, filepath.Join(dir, "common.yaml"), `parameters:
+  Limit:
+    name: limit
+    in: query
+    schema:
+      type: integer
+responses:
+  PetList:
+    description: |
+      A list of pets.
+      $ref: not a ref
+    content:
+      application/json:
+        schema:
+          type: array
+          items:
+            $ref: ./schemas/pet.yaml#/Pet
+`)
+	writeFile(t, filepath.Join(dir, "schemas", "pet.yaml"), `Pet:
+  type: object
+  properties:
+    owner:
+      $ref: ./owner.yaml
+`)
+	writeFile(t, filepath.Join(dir, "schemas", "owner.yaml"), `type: object
+properties:
+  name:
+    type: string
+`)
+
+	return "file://" + filepath.ToSlash(dir)
+}
+
+func TestBundle(t *testing.T) {
+	base := writeBundleSpec(t)
+
+	got, err := Bundle(base+"/api.yaml", BundleYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `openapi: 3.0.0
+info:
+  title: Pets
+  version: 1.0.0
+paths:
+  /pets:
+    get:
+      responses:
+        "200":
+          $ref: "#/components/responses/PetList"
+  /owners:
+    get:
+      parameters:
+        - $ref: "#/components/parameters/Limit"
+      responses:
+        "200":
+          description: OK
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/owner"
+components:
+  schemas:
+    Pet:
+      type: string
+    owner:
+      type: object
+      properties:
+        name:
+          type: string
+    Pet2:
+      type: object
+      properties:
+        owner:
+          $ref: "#/components/schemas/owner"
+  responses:
+    PetList:
+      description: |
+        A list of pets.
+        $ref: not a ref
+      content:
+        application/json:
+          schema:
+            type: array
+            items:
+              $ref: "#/components/schemas/Pet2"
+  parameters:
+    Limit:
+      name: limit
+      in: query
+      schema:
+        type: integer
+`
+
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestBundle_JSON(t *testing.T) {
+	base := writeBundleSpec(t)
+
+	got, err := Bundle(base+"/api.yaml", BundleJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var spec struct {
+		Paths map[string]struct {
+			Get struct {
+				Responses map[string]struct {
+					Ref string `json:"$ref"`
+				} `json:"responses"`
+			} `json:"get"`
+		} `json:"paths"`
+		Components struct {
+			Schemas   map[string]any `json:"schemas"`
+			Responses map[string]struct {
+				Description string `json:"description"`
+			} `json:"responses"`
+		} `json:"components"`
+	}
+
+	if err := json.Unmarshal(got, &spec); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, got)
+	}
+
+	if ref := spec.Paths["/pets"].Get.Responses["200"].Ref; ref != "#/components/responses/PetList" {
+		t.Errorf("$ref = %q", ref)
+	}
+
+	if n := len(spec.Components.Schemas); n != 3 {
+		t.Errorf("got %d schemas, want 3", n)
+	}
+
+	if d := spec.Components.Responses["PetList"].Description; d != "A list of pets.\n$ref: not a ref\n" {
+		t.Errorf("description = %q", d)
+	}
+}
+
+func TestBundle_Unresolved(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "api.yaml"), `openapi: 3.0.0
+paths:
+  /pets:
+    $ref: ./missing.yaml
+`)
+
+	if _, err := Bundle("file://"+filepath.ToSlash(dir)+"/api.yaml", BundleYAML); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestHandler_HandleExecuteCommand_Bundle(t *testing.T) {
+	base := writeBundleSpec(t)
+
+	var h Handler
+
+	if err := h.HandleInitialize(types.InitializeParams{RootURI: base}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := h.HandleExecuteCommand(types.ExecuteCommandParams{
+		Command:   "openapi.bundle",
+		Arguments: []json.RawMessage{json.RawMessage(`{"uri":"` + base + `/api.yaml","output":"` + base + `/out.json"}`)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got == nil {
+		t.Fatal("expected a result")
+	}
+
+	content, err := os.ReadFile(filepath.FromSlash(base[len("file://"):]) + "/out.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !json.Valid(content) {
+		t.Errorf("output is not JSON:\n%s", content)
+	}
+}
+
+func TestHandler_HandleExecuteCommand_BundleOutsideWorkspace(t *testing.T) {
+	base := writeBundleSpec(t)
+	outside := t.TempDir()
+
+	var h Handler
+
+	if err := h.HandleInitialize(types.InitializeParams{RootURI: base}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, output := range []string{
+		"file://" + filepath.ToSlash(outside) + "/out.json",
+		base + "/../" + filepath.Base(outside) + "/out.json",
+	} {
+		got, err := h.HandleExecuteCommand(types.ExecuteCommandParams{
+			Command:   "openapi.bundle",
+			Arguments: []json.RawMessage{json.RawMessage(`{"uri":"` + base + `/api.yaml","output":"` + output + `"}`)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != nil {
+			t.Errorf("output %s: got result %v, want nil", output, got)
+		}
+
+		if messages := h.Messages(); len(messages) != 1 || !messages[0].Show {
+			t.Errorf("output %s: got messages %+v, want an error to show", output, messages)
+		}
+	}
+
+	if entries, err := os.ReadDir(outside); err != nil || len(entries) != 0 {
+		t.Errorf("got %v, %v in the directory outside of the workspace, want nothing", entries, err)
+	}
+}
+
+func TestHandler_HandleExecuteCommand_BundleError(t *testing.T) {
+	var h Handler
+
+	got, err := h.HandleExecuteCommand(types.ExecuteCommandParams{
+		Command:   "openapi.bundle",
+		Arguments: []json.RawMessage{json.RawMessage(`{"uri":"file:///missing/api.yaml"}`)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got != nil {
+		t.Errorf("got result %v, want nil", got)
+	}
+
+	messages := h.Messages()
+	if len(messages) != 1 || !messages[0].Show || messages[0].Type != types.MessageError {
+		t.Errorf("got messages %+v, want an error to show", messages)
+	}
+
+	if messages := h.Messages(); len(messages) != 0 {
+		t.Errorf("got messages %+v after they were taken", messages)
+	}
+}
@@ -0,0 +1,284 @@
+package analysis
+
+import (
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/armsnyder/openapi-language-server/internal/analysis/yaml"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+// The call hierarchy treats $refs as calls. Its nodes are operations, path
+// items, named components, and fragment documents. A fragment that holds a
+// collection of named objects, such as a shared schemas file, contributes a
+// node for each of its top-level keys instead. Incoming calls walk $ref usages upward
+// (schema → schema → response → operation), and outgoing calls list the nodes
+// referenced from within a node.
+
+// objectKeywords are keys that appear at the top level of fragment documents
+// that contain a single OpenAPI object, such as a schema or path item.
+var objectKeywords = append([]string{
+	"$ref", "type", "properties", "items", "allOf", "oneOf", "anyOf", "enum",
+	"description", "summary", "content", "schema", "name", "in", "required", "parameters",
+}, httpMethods...)
+
+// swagger2Components are the root keys of a Swagger 2 document that hold
+// reusable components.
+var swagger2Components = []string{"definitions", "parameters", "responses", "securityDefinitions"}
+
+// callNode is a node in the call hierarchy.
+type callNode struct {
+	uri      string
+	document yaml.Document
+
+	// line is the key line of the node, or nil if the node is the whole
+	// document.
+	line *yaml.Line
+}
+
+func (n callNode) equal(other callNode) bool {
+	return n.uri == other.uri && n.line == other.line
+}
+
+// ref returns a JSON reference URI that identifies the node within its
+// document.
+func (n callNode) ref() string {
+	if n.line == nil {
+		return "#"
+	}
+
+	return n.line.KeyRef()
+}
+
+func (n callNode) item() types.CallHierarchyItem {
+	if n.line == nil {
+		return types.CallHierarchyItem{
+			Name:  path.Base(n.uri),
+			Kind:  types.SymbolKindFile,
+			URI:   n.uri,
+			Range: types.Range{End: types.Position{Line: len(n.document.Lines)}},
+			Data:  n.ref(),
+		}
+	}
+
+	item := types.CallHierarchyItem{
+		Name:           n.line.Key,
+		Kind:           types.SymbolKindObject,
+		URI:            n.uri,
+		Range:          subtreeRange(n.document, n.line),
+		SelectionRange: n.line.KeyRange,
+		Data:           n.ref(),
+	}
+
+	switch {
+	case n.line.Parent == nil:
+		item.Detail = path.Base(n.uri)
+	case isOperation(n.line):
+		item.Name = strings.ToUpper(n.line.Key) + " " + n.line.Parent.Key
+		item.Kind = types.SymbolKindFunction
+		if id := n.line.Children["operationId"]; id != nil {
+			item.Detail = id.Value
+		}
+	case isPathItem(n.line):
+		item.Kind = types.SymbolKindNamespace
+	case isSchema(n.line):
+		item.Kind = types.SymbolKindClass
+		item.Detail = n.line.Parent.Key
+	default:
+		item.Detail = n.line.Parent.Key
+	}
+
+	return item
+}
+
+func isPathItem(line *yaml.Line) bool {
+	return line.Parent != nil && line.Parent.Key == "paths" && line.Parent.Parent == nil && !line.Item
+}
+
+func isOperation(line *yaml.Line) bool {
+	return line.Parent != nil && isPathItem(line.Parent) && slices.Contains(httpMethods, line.Key)
+}
+
+// isCollection returns true if the document is a fragment whose top-level keys
+// are named objects, rather than a single object.
+func isCollection(document yaml.Document) bool {
+	if isRoot(document) {
+		return false
+	}
+
+	for key := range document.Root {
+		if slices.Contains(objectKeywords, key) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isComponent(line *yaml.Line) bool {
+	if line.Parent == nil || line.Item {
+		return false
+	}
+
+	group := line.Parent
+
+	if group.Parent == nil {
+		return slices.Contains(swagger2Components, group.Key)
+	}
+
+	return group.Parent.Key == "components" && group.Parent.Parent == nil
+}
+
+// enclosingNode returns the innermost call hierarchy node that contains the
+// given line.
+func enclosingNode(uri string, document yaml.Document, line *yaml.Line) callNode {
+	collection := isCollection(document)
+
+	for cur := line; cur != nil; cur = cur.Parent {
+		if isOperation(cur) || isPathItem(cur) || isComponent(cur) || (collection && cur.Parent == nil) {
+			return callNode{uri: uri, document: document, line: cur}
+		}
+	}
+
+	return callNode{uri: uri, document: document}
+}
+
+// resolveNode returns the call hierarchy node that a $ref value points into.
+func (h *Handler) resolveNode(baseURI, ref string) (callNode, bool) {
+	target, err := h.resolve(baseURI, ref)
+	if err != nil {
+		return callNode{}, false
+	}
+
+	if target.line == nil {
+		return callNode{uri: target.uri, document: target.document}, true
+	}
+
+	return enclosingNode(target.uri, target.document, target.line), true
+}
+
+// itemNode returns the node of an item previously returned to the client.
+func (h *Handler) itemNode(item types.CallHierarchyItem) (callNode, bool) {
+	ref, ok := item.Data.(string)
+	if !ok {
+		h.logf("Call hierarchy item %q is missing data", item.Name)
+		return callNode{}, false
+	}
+
+	return h.resolveNode(item.URI, ref)
+}
+
+func (h *Handler) HandlePrepareCallHierarchy(params types.CallHierarchyPrepareParams) ([]types.CallHierarchyItem, error) {
+	uri := params.TextDocument.URI
+
+	document, err := h.getDocument(uri)
+	if err != nil {
+		h.logf("HandlePrepareCallHierarchy: Error getting document %q: %v", uri, err)
+		return nil, nil
+	}
+
+	if !h.related(uri) {
+		return nil, nil
+	}
+
+	line := document.At(params.Position)
+	if line == nil {
+		return nil, nil
+	}
+
+	if line.Key == "$ref" {
+		if node, ok := h.resolveNode(uri, line.Value); ok {
+			return []types.CallHierarchyItem{node.item()}, nil
+		}
+	}
+
+	node := enclosingNode(uri, document, line)
+
+	// Outside of any node, a root document is not a useful item. Fragments
+	// are, since other documents reference them as a whole.
+	if node.line == nil && isRoot(document) {
+		return nil, nil
+	}
+
+	return []types.CallHierarchyItem{node.item()}, nil
+}
+
+// call is a group of $refs from one node to another.
+type call struct {
+	node   callNode
+	ranges []types.Range
+}
+
+// addCall adds a $ref to the group of calls for the given node, preserving the
+// order in which nodes are first seen.
+func addCall(calls []call, node callNode, rng types.Range) []call {
+	for i := range calls {
+		if calls[i].node.equal(node) {
+			calls[i].ranges = append(calls[i].ranges, rng)
+			return calls
+		}
+	}
+
+	return append(calls, call{node: node, ranges: []types.Range{rng}})
+}
+
+func (h *Handler) HandleCallHierarchyIncomingCalls(params types.CallHierarchyIncomingCallsParams) ([]types.CallHierarchyIncomingCall, error) {
+	callee, ok := h.itemNode(params.Item)
+	if !ok {
+		return nil, nil
+	}
+
+	var calls []call
+
+	for _, d := range h.workspaceDocuments(h.folder(params.Item.URI)) {
+		for _, line := range d.document.Nodes() {
+			if line.Key != "$ref" {
+				continue
+			}
+
+			target, ok := h.resolveNode(d.uri, line.Value)
+			if !ok || !target.equal(callee) {
+				continue
+			}
+
+			calls = addCall(calls, enclosingNode(d.uri, d.document, line), line.ValueRange)
+		}
+	}
+
+	result := make([]types.CallHierarchyIncomingCall, len(calls))
+	for i, c := range calls {
+		result[i] = types.CallHierarchyIncomingCall{From: c.node.item(), FromRanges: c.ranges}
+	}
+
+	return result, nil
+}
+
+func (h *Handler) HandleCallHierarchyOutgoingCalls(params types.CallHierarchyOutgoingCallsParams) ([]types.CallHierarchyOutgoingCall, error) {
+	caller, ok := h.itemNode(params.Item)
+	if !ok {
+		return nil, nil
+	}
+
+	var calls []call
+
+	for _, line := range caller.document.Nodes() {
+		if line.Key != "$ref" || !enclosingNode(caller.uri, caller.document, line).equal(caller) {
+			continue
+		}
+
+		target, ok := h.resolveNode(caller.uri, line.Value)
+		if !ok {
+			continue
+		}
+
+		calls = addCall(calls, target, line.ValueRange)
+	}
+
+	result := make([]types.CallHierarchyOutgoingCall, len(calls))
+	for i, c := range calls {
+		result[i] = types.CallHierarchyOutgoingCall{To: c.node.item(), FromRanges: c.ranges}
+	}
+
+	return result, nil
+}
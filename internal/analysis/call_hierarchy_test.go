@@ -0,0 +1,233 @@
+package analysis_test
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	. "github.com/armsnyder/openapi-language-server/internal/analysis"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+const callHierarchySpec = `openapi: 3.0.0
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      responses:
+        "200":
+          $ref: "#/components/responses/PetList"
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: "#/components/schemas/Pet"
+      responses:
+        "201":
+          $ref: "./responses.yaml#/Created"
+components:
+  responses:
+    PetList:
+      content:
+        application/json:
+          schema:
+            type: array
+            items:
+              $ref: "#/components/schemas/Pet"
+  schemas:
+    Pet:
+      properties:
+        owner:
+          $ref: "#/components/schemas/Owner"
+        friend:
+          $ref: "#/components/schemas/Owner"
+    Owner:
+      type: object
+`
+
+func TestHandler_CallHierarchy(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "responses.yaml"), `Created:
+  description: Created
+`)
+
+	uri := "file://" + filepath.ToSlash(filepath.Join(dir, "api.yaml"))
+	responsesURI := "file://" + filepath.ToSlash(filepath.Join(dir, "responses.yaml"))
+
+	var h Handler
+
+	loadFile(uri, callHierarchySpec)(t, &h)
+
+	prepare := func(position string) types.CallHierarchyItem {
+		t.Helper()
+
+		items, err := h.HandlePrepareCallHierarchy(types.CallHierarchyPrepareParams{
+			TextDocumentPositionParams: positionParams(uri, position),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(items) != 1 {
+			t.Fatalf("HandlePrepareCallHierarchy(%s) = %+v, want 1 item", position, items)
+		}
+
+		return items[0]
+	}
+
+	incoming := func(item types.CallHierarchyItem) []string {
+		t.Helper()
+
+		calls, err := h.HandleCallHierarchyIncomingCalls(types.CallHierarchyIncomingCallsParams{Item: item})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var result []string
+		for _, c := range calls {
+			result = append(result, c.From.Name+" "+rangesString(c.FromRanges))
+		}
+		return result
+	}
+
+	outgoing := func(item types.CallHierarchyItem) []string {
+		t.Helper()
+
+		calls, err := h.HandleCallHierarchyOutgoingCalls(types.CallHierarchyOutgoingCallsParams{Item: item})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var result []string
+		for _, c := range calls {
+			result = append(result, c.To.Name+" "+rangesString(c.FromRanges))
+		}
+		return result
+	}
+
+	owner := prepare("33:6")
+
+	wantOwner := types.CallHierarchyItem{
+		Name:           "Owner",
+		Kind:           types.SymbolKindClass,
+		Detail:         "schemas",
+		URI:            uri,
+		Range:          newRange("33:4-35:0"),
+		SelectionRange: newRange("33:4-33:9"),
+		Data:           "#/components/schemas/Owner",
+	}
+
+	if !reflect.DeepEqual(owner, wantOwner) {
+		t.Errorf("prepare Owner = %+v, want %+v", owner, wantOwner)
+	}
+
+	// Walk up from Owner to the operations.
+
+	if got, want := incoming(owner), []string{"Pet 30:17-30:43,32:17-32:43"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("incoming calls of Owner = %q, want %q", got, want)
+	}
+
+	pet := prepare("27:4")
+
+	if got, want := incoming(pet), []string{"POST /pets 13:21-13:45", "PetList 25:21-25:45"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("incoming calls of Pet = %q, want %q", got, want)
+	}
+
+	petList := prepare("7:20")
+
+	if petList.Name != "PetList" {
+		t.Fatalf("prepare on $ref = %+v, want PetList", petList)
+	}
+
+	if got, want := incoming(petList), []string{"GET /pets 7:17-7:47"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("incoming calls of PetList = %q, want %q", got, want)
+	}
+
+	// Outgoing calls from an operation, including into another file.
+
+	post := prepare("8:4")
+
+	if post.Name != "POST /pets" || post.Kind != types.SymbolKindFunction {
+		t.Fatalf("prepare post = %+v", post)
+	}
+
+	if got, want := outgoing(post), []string{"Pet 13:21-13:45", "Created 16:17-16:42"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("outgoing calls of POST /pets = %q, want %q", got, want)
+	}
+
+	created, err := h.HandleCallHierarchyOutgoingCalls(types.CallHierarchyOutgoingCallsParams{Item: post})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := created[1].To.URI; got != responsesURI {
+		t.Errorf("outgoing call URI = %q, want %q", got, responsesURI)
+	}
+
+	if got, want := outgoing(pet), []string{"Owner 30:17-30:43,32:17-32:43"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("outgoing calls of Pet = %q, want %q", got, want)
+	}
+}
+
+func rangesString(ranges []types.Range) string {
+	var s string
+	for i, r := range ranges {
+		if i > 0 {
+			s += ","
+		}
+		s += r.String()
+	}
+	return s
+}
+
+func TestHandler_CallHierarchy_FragmentDocument(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "owner.yaml"), `type: object
+`)
+	writeFile(t, filepath.Join(dir, "openapi.yaml"), `openapi: 3.0.0
+components:
+  schemas:
+    Pet:
+      $ref: ./pet.yaml
+`)
+
+	uri := "file://" + filepath.ToSlash(filepath.Join(dir, "pet.yaml"))
+	ownerURI := "file://" + filepath.ToSlash(filepath.Join(dir, "owner.yaml"))
+
+	h := Handler{Settings: Settings{Roots: []string{filepath.Join(dir, "openapi.yaml")}}}
+
+	loadFile(uri, `type: object
+properties:
+  owner:
+    $ref: ./owner.yaml
+`)(t, &h)
+
+	items, err := h.HandlePrepareCallHierarchy(types.CallHierarchyPrepareParams{
+		TextDocumentPositionParams: positionParams(uri, "2:2"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []types.CallHierarchyItem{{
+		Name:  "pet.yaml",
+		Kind:  types.SymbolKindFile,
+		URI:   uri,
+		Range: newRange("0:0-4:0"),
+		Data:  "#",
+	}}
+
+	if !reflect.DeepEqual(items, want) {
+		t.Fatalf("HandlePrepareCallHierarchy() = %+v, want %+v", items, want)
+	}
+
+	calls, err := h.HandleCallHierarchyOutgoingCalls(types.CallHierarchyOutgoingCallsParams{Item: items[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(calls) != 1 || calls[0].To.URI != ownerURI || calls[0].To.Name != "owner.yaml" {
+		t.Errorf("HandleCallHierarchyOutgoingCalls() = %+v, want owner.yaml", calls)
+	}
+}
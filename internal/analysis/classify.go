@@ -0,0 +1,86 @@
+package analysis
+
+// role is how an open document relates to the specs in the workspace. Editors
+// start the server for all YAML and JSON files, so many open documents are
+// not OpenAPI at all.
+type role int
+
+const (
+	// roleUnrelated documents are not part of any known spec, such as
+	// Kubernetes manifests. They get no diagnostics, symbols or code actions.
+	roleUnrelated role = iota
+
+	// roleRoot documents declare the openapi or swagger version.
+	roleRoot
+
+	// roleFragment documents are reachable through $refs from a root
+	// document that is open or declared in the settings or a config file.
+	roleFragment
+)
+
+// role classifies an open document.
+func (h *Handler) role(uri string) role {
+	document, err := h.getDocument(uri)
+	if err != nil {
+		return roleUnrelated
+	}
+
+	if isRoot(document) {
+		return roleRoot
+	}
+
+	if h.fragments == nil {
+		h.fragments = h.reachableFromRoots()
+	}
+
+	if h.fragments[uri] {
+		return roleFragment
+	}
+
+	return roleUnrelated
+}
+
+// related returns true if the open document is part of a known spec.
+func (h *Handler) related(uri string) bool {
+	return h.role(uri) != roleUnrelated
+}
+
+// reachableFromRoots returns the URIs of the documents that are reachable
+// through $refs from the known root documents of any workspace folder.
+func (h *Handler) reachableFromRoots() map[string]bool {
+	reachable := map[string]bool{}
+
+	var queue []string
+	for _, uri := range h.rootURIs("") {
+		if document, err := h.loadDocument(uri); err == nil && isRoot(document) {
+			queue = append(queue, uri)
+		}
+	}
+
+	for len(queue) > 0 {
+		uri := queue[0]
+		queue = queue[1:]
+
+		if reachable[uri] {
+			continue
+		}
+		reachable[uri] = true
+
+		document, err := h.loadDocument(uri)
+		if err != nil {
+			continue
+		}
+
+		for _, line := range document.Nodes() {
+			if line.Key != "$ref" {
+				continue
+			}
+
+			if target, _, err := h.resolveRef(uri, line.Value); err == nil && !reachable[target] {
+				queue = append(queue, target)
+			}
+		}
+	}
+
+	return reachable
+}
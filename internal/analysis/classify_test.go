@@ -0,0 +1,154 @@
+package analysis_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	. "github.com/armsnyder/openapi-language-server/internal/analysis"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+func TestHandler_UnrelatedDocuments(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "schemas", "pet.yaml"), `type: object
+properties:
+  owner:
+    $ref: ./owner.yaml
+`)
+
+	base := "file://" + filepath.ToSlash(dir)
+
+	var h Handler
+
+	// The owner schema is reachable from the root through the pet schema,
+	// which is not open. The workflow refers to the pet schema, but nothing
+	// refers to the workflow.
+
+	loadFile(base+"/api.yaml", `openapi: 3.0.0
+components:
+  schemas:
+    Pet:
+      $ref: ./schemas/pet.yaml
+`)(t, &h)
+	loadFile(base+"/schemas/owner.yaml", `type: object
+properties:
+  pets:
+    items:
+      $ref: ./pet.yaml
+`)(t, &h)
+	loadFile(base+"/.github/workflows/ci.yaml", `on: push
+jobs:
+  build:
+      runs-on: ubuntu-latest
+      steps:
+        - uses: actions/checkout@v4
+          with:
+            $ref: ../../schemas/pet.yaml
+`)(t, &h)
+
+	links := func(uri string) int {
+		t.Helper()
+
+		got, err := h.HandleDocumentLink(types.DocumentLinkParams{TextDocument: types.TextDocumentIdentifier{URI: uri}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return len(got)
+	}
+
+	for uri, want := range map[string]int{
+		base + "/api.yaml":                  1,
+		base + "/schemas/owner.yaml":        1,
+		base + "/.github/workflows/ci.yaml": 0,
+	} {
+		if got := links(uri); got != want {
+			t.Errorf("HandleDocumentLink(%s) returned %d links, want %d", uri, got, want)
+		}
+	}
+
+	edits, err := h.HandleFormatting(types.DocumentFormattingParams{
+		TextDocument: types.TextDocumentIdentifier{URI: base + "/.github/workflows/ci.yaml"},
+		Options:      types.FormattingOptions{TabSize: 2, InsertSpaces: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(edits) != 0 {
+		t.Errorf("HandleFormatting() = %+v, want no edits for an unrelated document", edits)
+	}
+
+	// Once the root is closed, nothing is known to refer to the owner schema.
+
+	if err := h.HandleClose(types.DidCloseTextDocumentParams{
+		TextDocument: types.TextDocumentIdentifier{URI: base + "/api.yaml"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := links(base + "/schemas/owner.yaml"); got != 0 {
+		t.Errorf("HandleDocumentLink() returned %d links after closing the root, want 0", got)
+	}
+}
+
+func TestHandler_UnrelatedDocuments_Save(t *testing.T) {
+	dir := t.TempDir()
+	base := "file://" + filepath.ToSlash(dir)
+
+	var h Handler
+
+	loadFile(base+"/api.yaml", "openapi: 3.0.0\n")(t, &h)
+	loadFile(base+"/pet.yaml", `type: object
+properties:
+  owner:
+    $ref: ./owner.yaml
+`)(t, &h)
+
+	links := func() int {
+		t.Helper()
+
+		got, err := h.HandleDocumentLink(types.DocumentLinkParams{TextDocument: types.TextDocumentIdentifier{URI: base + "/pet.yaml"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return len(got)
+	}
+
+	if got := links(); got != 0 {
+		t.Fatalf("HandleDocumentLink() returned %d links, want 0", got)
+	}
+
+	// Finding the documents that are reachable from the roots is too slow to
+	// do on every change, so a new $ref only takes effect on save.
+
+	if err := h.HandleChange(types.DidChangeTextDocumentParams{
+		TextDocument: types.VersionedTextDocumentIdentifier{
+			TextDocumentIdentifier: types.TextDocumentIdentifier{URI: base + "/api.yaml"},
+			Version:                1,
+		},
+		ContentChanges: []types.TextDocumentContentChangeEvent{{Text: `openapi: 3.0.0
+components:
+  schemas:
+    Pet:
+      $ref: ./pet.yaml
+`}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := links(); got != 0 {
+		t.Errorf("HandleDocumentLink() returned %d links after a change, want 0", got)
+	}
+
+	if err := h.HandleSave(types.DidSaveTextDocumentParams{
+		TextDocument: types.TextDocumentIdentifier{URI: base + "/api.yaml"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := links(); got != 1 {
+		t.Errorf("HandleDocumentLink() returned %d links after save, want 1", got)
+	}
+}
@@ -0,0 +1,190 @@
+package analysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/armsnyder/openapi-language-server/internal/analysis/yaml"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+// showReferencesCommand is the client command that opens a list of locations.
+// Its arguments are the URI and position that the list belongs to, followed by
+// the locations.
+const showReferencesCommand = "editor.action.showReferences"
+
+const (
+	codeLensReferences = "references"
+	codeLensSchemas    = "schemas"
+)
+
+// schemaKeywords are keys whose values are schemas. A $ref beneath one of these
+// keys refers to a schema.
+var schemaKeywords = []string{"schema", "items", "properties", "additionalProperties", "allOf", "oneOf", "anyOf", "not"}
+
+// codeLensData is attached to unresolved code lenses so that they can be
+// resolved later. Counting is deferred because it searches the workspace.
+type codeLensData struct {
+	URI  string `json:"uri"`
+	Ref  string `json:"ref"`
+	Kind string `json:"kind"`
+}
+
+func (h *Handler) HandleCodeLens(params types.CodeLensParams) ([]types.CodeLens, error) {
+	if !h.Settings.Enabled(FeatureCodeLens) {
+		return nil, nil
+	}
+
+	uri := params.TextDocument.URI
+
+	document, err := h.getDocument(uri)
+	if err != nil {
+		h.logf("HandleCodeLens: Error getting document %q: %v", uri, err)
+		return nil, nil
+	}
+
+	if !h.related(uri) {
+		return nil, nil
+	}
+
+	var lenses []types.CodeLens
+
+	for _, line := range document.Nodes() {
+		var kind string
+
+		switch {
+		case isComponent(line):
+			kind = codeLensReferences
+		case isOperation(line):
+			kind = codeLensSchemas
+		default:
+			continue
+		}
+
+		lenses = append(lenses, types.CodeLens{
+			Range: line.KeyRange,
+			Data:  codeLensData{URI: uri, Ref: line.KeyRef(), Kind: kind},
+		})
+	}
+
+	return lenses, nil
+}
+
+func (h *Handler) HandleCodeLensResolve(params types.CodeLens) (types.CodeLens, error) {
+	var data codeLensData
+
+	// The data has made a round trip through the client, so it is no longer
+	// the struct that was attached.
+	if b, err := json.Marshal(params.Data); err != nil || json.Unmarshal(b, &data) != nil {
+		h.logf("HandleCodeLensResolve: Invalid data: %v", params.Data)
+		return params, nil
+	}
+
+	target, err := h.resolve(data.URI, data.Ref)
+	if err != nil || target.line == nil {
+		h.logf("HandleCodeLensResolve: Error resolving %s%s: %v", data.URI, data.Ref, err)
+		return params, nil
+	}
+
+	var title string
+	var locations []types.Location
+
+	switch data.Kind {
+	case codeLensReferences:
+		locations = h.findReferences(data.URI, data.Ref)
+		title = pluralize(len(locations), "reference", "references")
+
+	case codeLensSchemas:
+		for _, schema := range h.operationSchemas(enclosingNode(target.uri, target.document, target.line)) {
+			item := schema.item()
+			locations = append(locations, types.Location{URI: item.URI, Range: item.SelectionRange})
+		}
+		title = "uses " + pluralize(len(locations), "schema", "schemas")
+
+	default:
+		h.logf("HandleCodeLensResolve: Unknown kind %q", data.Kind)
+		return params, nil
+	}
+
+	if locations == nil {
+		locations = []types.Location{}
+	}
+
+	params.Command = &types.Command{
+		Title:     title,
+		Command:   showReferencesCommand,
+		Arguments: []any{data.URI, target.line.KeyRange.Start, locations},
+	}
+
+	return params, nil
+}
+
+// operationSchemas returns the schemas that are used by an operation, directly
+// or through other components.
+func (h *Handler) operationSchemas(op callNode) []callNode {
+	type visit struct {
+		node callNode
+
+		// schema is true if the node is itself a schema, which means that
+		// everything it references is a schema too.
+		schema bool
+	}
+
+	var schemas []callNode
+	seen := map[string]bool{op.uri + op.ref(): true}
+	queue := []visit{{node: op}}
+
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+
+		for _, line := range cur.node.document.Nodes() {
+			if line.Key != "$ref" || !enclosingNode(cur.node.uri, cur.node.document, line).equal(cur.node) {
+				continue
+			}
+
+			target, ok := h.resolveNode(cur.node.uri, line.Value)
+			if !ok {
+				continue
+			}
+
+			fragment := target.line == nil || target.line.Parent == nil
+			schema := isSchema(target.line) || (fragment && (cur.schema || inSchemaPosition(line, cur.node.line)))
+
+			key := target.uri + target.ref()
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			if schema {
+				schemas = append(schemas, target)
+			}
+
+			queue = append(queue, visit{node: target, schema: schema})
+		}
+	}
+
+	return schemas
+}
+
+// inSchemaPosition returns true if the $ref line is beneath a key whose value
+// is a schema, without leaving the given node.
+func inSchemaPosition(ref, node *yaml.Line) bool {
+	for cur := ref.Parent; cur != nil && cur != node; cur = cur.Parent {
+		if slices.Contains(schemaKeywords, cur.Key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func pluralize(n int, singular, plural string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, singular)
+	}
+
+	return fmt.Sprintf("%d %s", n, plural)
+}
@@ -0,0 +1,141 @@
+package analysis_test
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	. "github.com/armsnyder/openapi-language-server/internal/analysis"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+const codeLensSpec = `openapi: 3.0.0
+paths:
+  /pets:
+    get:
+      parameters:
+        - $ref: "#/components/parameters/Limit"
+      responses:
+        "200":
+          $ref: "#/components/responses/PetList"
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: "./pet.yaml"
+components:
+  parameters:
+    Limit:
+      name: limit
+      in: query
+  responses:
+    PetList:
+      content:
+        application/json:
+          schema:
+            type: array
+            items:
+              $ref: "#/components/schemas/Pet"
+  schemas:
+    Pet:
+      properties:
+        owner:
+          $ref: "#/components/schemas/Owner"
+    Owner:
+      type: object
+    Unused:
+      type: object
+`
+
+func TestHandler_CodeLens(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "pet.yaml"), `type: object
+properties:
+  owner:
+    $ref: "./api.yaml#/components/schemas/Owner"
+`)
+
+	uri := "file://" + filepath.ToSlash(filepath.Join(dir, "api.yaml"))
+	petURI := "file://" + filepath.ToSlash(filepath.Join(dir, "pet.yaml"))
+
+	var h Handler
+
+	loadFile(uri, codeLensSpec)(t, &h)
+
+	lenses, err := h.HandleCodeLens(types.CodeLensParams{
+		TextDocument: types.TextDocumentIdentifier{URI: uri},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+
+	for _, lens := range lenses {
+		if lens.Command != nil {
+			t.Errorf("code lens at %s is already resolved", lens.Range)
+		}
+
+		// Simulate the round trip through the client.
+		b, err := json.Marshal(lens)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var unresolved types.CodeLens
+		if err := json.Unmarshal(b, &unresolved); err != nil {
+			t.Fatal(err)
+		}
+
+		resolved, err := h.HandleCodeLensResolve(unresolved)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if resolved.Command == nil {
+			t.Fatalf("code lens at %s was not resolved", lens.Range)
+		}
+
+		got = append(got, lens.Range.String()+" "+resolved.Command.Title)
+	}
+
+	want := []string{
+		"3:4-3:7 uses 2 schemas",
+		"9:4-9:8 uses 2 schemas",
+		"17:4-17:9 1 reference",
+		"21:4-21:11 1 reference",
+		"29:4-29:7 1 reference",
+		"33:4-33:9 2 references",
+		"35:4-35:10 0 references",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("code lenses = %q, want %q", got, want)
+	}
+
+	// The command opens the list of references, including those in other files.
+
+	owner, err := h.HandleCodeLensResolve(lenses[5])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantCommand := &types.Command{
+		Title:   "2 references",
+		Command: "editor.action.showReferences",
+		Arguments: []any{
+			uri,
+			types.Position{Line: 33, Character: 4},
+			[]types.Location{
+				{URI: uri, Range: newRange("32:17-32:43")},
+				{URI: petURI, Range: newRange("3:11-3:47")},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(owner.Command, wantCommand) {
+		t.Errorf("Owner command = %+v, want %+v", owner.Command, wantCommand)
+	}
+}
@@ -0,0 +1,401 @@
+package analysis
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/armsnyder/openapi-language-server/internal/analysis/yaml"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+// ConfigFileName is the name of the config files that are checked into a
+// repository. A config file applies to the documents in its directory and
+// below, unless a directory has a config file of its own. Config files are not
+// merged, except that the roots of all of them are used.
+const ConfigFileName = ".openapi-language-server.yaml"
+
+// projectConfig is a parsed config file.
+type projectConfig struct {
+	uri string
+
+	// dir is the URI of the directory of the config file, with a trailing
+	// slash.
+	dir string
+
+	// roots are the URIs of the root documents of the specs.
+	roots []string
+
+	// ignore are glob patterns of documents that get no diagnostics,
+	// relative to dir.
+	ignore []string
+
+	// lint sets the level of lint rules. The settings of the editor take
+	// precedence.
+	lint map[string]Level
+
+	// extensions are glob patterns of the vendor extensions that documents
+	// may use. If there are none, any extension may be used.
+	extensions []string
+
+	// diagnostics are the errors in the config file.
+	diagnostics []types.Diagnostic
+	published   bool
+}
+
+func (c *projectConfig) errorf(rng types.Range, format string, args ...any) {
+	c.diagnostics = append(c.diagnostics, types.Diagnostic{
+		Range:    rng,
+		Severity: types.SeverityError,
+		Code:     "invalid-config",
+		Source:   diagnosticSource,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// isConfigFile returns true if the URI is the URI of a config file.
+func isConfigFile(uri string) bool {
+	return path.Base(uri) == ConfigFileName
+}
+
+// dirURI returns the URI of the directory that contains the document with the
+// given URI, with a trailing slash.
+func dirURI(uri string) string {
+	return uri[:strings.LastIndex(uri, "/")+1]
+}
+
+// configValue is a scalar in a config file, together with its location.
+type configValue struct {
+	value string
+	rng   types.Range
+}
+
+// configList returns the values of a sequence in a config file. Block
+// sequences point each value at its own line, and flow sequences point all
+// values at the sequence.
+func configList(c *projectConfig, line *yaml.Line) []configValue {
+	var values []configValue
+
+	switch {
+	case len(line.Items) > 0:
+		for _, item := range line.Items {
+			if item.Key != "" || item.Value == "" {
+				c.errorf(item.KeyRange, "%s must be a list of strings", line.Key)
+				continue
+			}
+			values = append(values, configValue{value: unquoteScalar(item.Value), rng: item.ValueRange})
+		}
+	case strings.HasPrefix(line.Value, "["):
+		for _, value := range sequence(line) {
+			values = append(values, configValue{value: value, rng: line.ValueRange})
+		}
+	case line.Value != "" || len(line.Children) > 0:
+		c.errorf(line.KeyRange, "%s must be a list of strings", line.Key)
+	}
+
+	return values
+}
+
+// parseConfig parses a config file. Errors are kept as diagnostics of the
+// config, and the parts of the config that are valid still apply.
+func parseConfig(uri string, content []byte) *projectConfig {
+	c := &projectConfig{uri: uri, dir: dirURI(uri)}
+
+	document, err := yaml.Parse(bytes.NewReader(content))
+	if err != nil {
+		c.errorf(types.Range{}, "Invalid config file: %v", err)
+		return c
+	}
+
+	for _, line := range document.Lines {
+		if line.Parent != nil || line.Empty || line.Comment || line.Key == "" {
+			continue
+		}
+
+		switch line.Key {
+		case "roots":
+			for _, root := range configList(c, line) {
+				target, _, err := resolveURI(c.dir, root.value)
+				if err != nil {
+					c.errorf(root.rng, "Invalid root %q: %v", root.value, err)
+					continue
+				}
+				c.roots = append(c.roots, target)
+			}
+		case "ignore":
+			for _, pattern := range configList(c, line) {
+				if _, err := path.Match(pattern.value, ""); err != nil {
+					c.errorf(pattern.rng, "Invalid glob pattern %q", pattern.value)
+					continue
+				}
+				c.ignore = append(c.ignore, pattern.value)
+			}
+		case "lint":
+			c.lint = map[string]Level{}
+			for rule, child := range line.Children {
+				level := Level(unquoteScalar(child.Value))
+
+				switch {
+				case !slices.Contains(lintRules, rule):
+					c.errorf(child.KeyRange, "Unknown lint rule %q", rule)
+				case levelSeverities[level] == 0 && level != LevelOff:
+					c.errorf(child.ValueRange, "Unknown level %q, expected one of error, warning, information, hint or off", level)
+				default:
+					c.lint[rule] = level
+				}
+			}
+		case "extensions":
+			for _, extension := range configList(c, line) {
+				if !strings.HasPrefix(extension.value, "x-") {
+					c.errorf(extension.rng, "Vendor extensions must start with x-, got %q", extension.value)
+					continue
+				}
+				if _, err := path.Match(extension.value, ""); err != nil {
+					c.errorf(extension.rng, "Invalid glob pattern %q", extension.value)
+					continue
+				}
+				c.extensions = append(c.extensions, extension.value)
+			}
+		default:
+			c.errorf(line.KeyRange, "Unknown config key %q", line.Key)
+		}
+	}
+
+	return c
+}
+
+// loadConfig returns the config file in the directory with the given URI, or
+// nil if there is none. Config files are cached until they change.
+func (h *Handler) loadConfig(dir string) *projectConfig {
+	if c, ok := h.configs[dir]; ok {
+		return c
+	}
+
+	var c *projectConfig
+	uri := dir + ConfigFileName
+
+	if name, err := uriToPath(uri); err == nil {
+		content, err := os.ReadFile(name)
+		switch {
+		case err == nil:
+			c = parseConfig(uri, content)
+		case !errors.Is(err, fs.ErrNotExist):
+			c = &projectConfig{uri: uri, dir: dir}
+			c.errorf(types.Range{}, "Error reading config file: %v", err)
+		}
+	}
+
+	if h.configs == nil {
+		h.configs = make(map[string]*projectConfig)
+	}
+	h.configs[dir] = c
+
+	return c
+}
+
+// config returns the config file that applies to the document with the given
+// URI, which is the closest one in its directory or a parent directory within
+// its workspace folder, or nil if there is none.
+func (h *Handler) config(uri string) *projectConfig {
+	if !strings.HasPrefix(uri, "file:") {
+		return nil
+	}
+
+	folder := h.folder(uri)
+
+	for dir := dirURI(uri); ; dir = dirURI(strings.TrimSuffix(dir, "/")) {
+		if c := h.loadConfig(dir); c != nil {
+			return c
+		}
+
+		if folder == "" || dir == folder || !strings.HasPrefix(dir, folder) {
+			return nil
+		}
+	}
+}
+
+// loadConfigs loads the config files that apply to the open documents and the
+// ones at the roots of the workspace folders.
+func (h *Handler) loadConfigs() {
+	for _, folder := range h.folders {
+		h.loadConfig(folder)
+	}
+
+	for uri := range h.files {
+		h.config(uri)
+	}
+}
+
+// sortedConfigs returns the loaded config files, ordered by URI.
+func (h *Handler) sortedConfigs() []*projectConfig {
+	var configs []*projectConfig
+
+	for _, c := range h.configs {
+		if c != nil {
+			configs = append(configs, c)
+		}
+	}
+
+	slices.SortFunc(configs, func(a, b *projectConfig) int {
+		return strings.Compare(a.uri, b.uri)
+	})
+
+	return configs
+}
+
+// reloadConfig forgets a config file that has changed, so that it is loaded
+// again when it is needed.
+func (h *Handler) reloadConfig(uri string) {
+	dir := dirURI(uri)
+
+	// The diagnostics of the old config are cleared, and those of the new
+	// config are published once it is loaded.
+	if old := h.configs[dir]; old != nil && old.published {
+		h.closed = append(h.closed, old.uri)
+	}
+
+	delete(h.configs, dir)
+
+	h.invalidateProblems()
+	h.invalidateSaveProblems()
+}
+
+// reloadConfigs forgets all config files.
+func (h *Handler) reloadConfigs() {
+	for _, c := range h.sortedConfigs() {
+		if c.published {
+			h.closed = append(h.closed, c.uri)
+		}
+	}
+
+	h.configs = nil
+
+	h.invalidateProblems()
+	h.invalidateSaveProblems()
+}
+
+func (h *Handler) HandleDidChangeWatchedFiles(params types.DidChangeWatchedFilesParams) error {
+	for _, change := range params.Changes {
+		if isConfigFile(change.URI) {
+			h.reloadConfig(change.URI)
+		}
+	}
+
+	return nil
+}
+
+// ignored returns true if the document with the given URI matches an ignore
+// pattern of its config file.
+func (h *Handler) ignored(uri string) bool {
+	c := h.config(uri)
+	if c == nil {
+		return false
+	}
+
+	rel, err := url.PathUnescape(strings.TrimPrefix(uri, c.dir))
+	if err != nil {
+		return false
+	}
+
+	for _, pattern := range c.ignore {
+		if matchGlob(pattern, rel) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchGlob matches a slash-separated path against a glob pattern, where **
+// matches any number of directories. Patterns without a slash match the base
+// name at any depth.
+func matchGlob(pattern, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		pattern = "**/" + pattern
+	}
+
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := len(name); i >= 0; i-- {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0
+}
+
+// severity returns the severity of a diagnostic with the given code and
+// default severity in the document with the given URI, according to the lint
+// levels of the settings and the config file. It returns false if the rule is
+// off.
+func (h *Handler) severity(uri, code string, severity types.DiagnosticSeverity) (types.DiagnosticSeverity, bool) {
+	level, ok := h.Settings.Lint[code]
+	if !ok {
+		if c := h.config(uri); c != nil {
+			level, ok = c.lint[code]
+		}
+	}
+
+	switch {
+	case !ok:
+		return severity, true
+	case level == LevelOff:
+		return 0, false
+	default:
+		return levelSeverities[level], true
+	}
+}
+
+// checkExtensions reports vendor extensions that the config file does not
+// declare.
+func checkExtensions(h *Handler, f *annotatedFile) []problem {
+	c := h.config(f.uri)
+	if c == nil || len(c.extensions) == 0 {
+		return nil
+	}
+
+	var problems []problem
+
+	for _, line := range f.document.Lines {
+		if !strings.HasPrefix(line.Key, "x-") || slices.ContainsFunc(c.extensions, func(pattern string) bool {
+			ok, _ := path.Match(pattern, line.Key)
+			return ok
+		}) {
+			continue
+		}
+
+		problems = append(problems, problem{diagnostic: types.Diagnostic{
+			Range:    line.KeyRange,
+			Severity: types.SeverityWarning,
+			Code:     "unknown-extension",
+			Source:   diagnosticSource,
+			Message:  fmt.Sprintf("Vendor extension %q is not declared in %s", line.Key, ConfigFileName),
+		}})
+	}
+
+	return problems
+}
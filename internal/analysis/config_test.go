@@ -0,0 +1,244 @@
+package analysis_test
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	. "github.com/armsnyder/openapi-language-server/internal/analysis"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+func TestHandler_Config(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, ConfigFileName), `# Shared settings.
+roots:
+  - specs/openapi.yaml
+ignore: ["generated/**", "*.draft.yaml"]
+lint:
+  unused-path-parameter: off
+  missing-path-parameter: warning
+extensions:
+  - x-internal
+  - x-amazon-*
+`)
+	writeFile(t, filepath.Join(dir, "specs", "openapi.yaml"), `openapi: 3.0.0
+paths:
+  /pets:
+    $ref: ../pets.yaml
+  /animals:
+    get:
+      operationId: listPets
+`)
+
+	base := "file://" + filepath.ToSlash(dir)
+
+	var h Handler
+
+	if err := h.HandleInitialize(types.InitializeParams{RootURI: base}); err != nil {
+		t.Fatal(err)
+	}
+
+	src := `get:
+  operationId: listPets
+  x-internal: true
+  x-amazon-apigateway-integration: {}
+  x-codegen: {}
+`
+
+	for _, name := range []string{"pets.yaml", "generated/pets.yaml", "pets.draft.yaml"} {
+		loadFile(base+"/"+name, src)(t, &h)
+	}
+
+	// The root from the config finds the duplicate, and only undeclared
+	// extensions are reported. Ignored files have no diagnostics.
+
+	want := []string{
+		`1:15-1:23 duplicate-operation-id: Duplicate operationId "listPets"`,
+		`4:2-4:11 unknown-extension: Vendor extension "x-codegen" is not declared in .openapi-language-server.yaml`,
+	}
+
+	params, err := h.Diagnostics()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, p := range params {
+		if p.URI != base+"/pets.yaml" {
+			t.Errorf("got diagnostics for %s, want only pets.yaml", p.URI)
+			continue
+		}
+		for _, d := range p.Diagnostics {
+			got = append(got, d.Range.String()+" "+d.Code+": "+d.Message)
+		}
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diagnostics = %q, want %q", got, want)
+	}
+
+	// Lint levels come from the config file, unless the settings of the
+	// editor set them.
+
+	loadFile(base+"/paths.yaml", `openapi: 3.0.0
+paths:
+  /pets/{id}:
+    get:
+      parameters:
+        - name: owner
+          in: path
+          required: true
+`)(t, &h)
+
+	levels := func() map[string]types.DiagnosticSeverity {
+		t.Helper()
+
+		params, err := h.Diagnostics()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result := map[string]types.DiagnosticSeverity{}
+		for _, p := range params {
+			if p.URI != base+"/paths.yaml" {
+				continue
+			}
+			for _, d := range p.Diagnostics {
+				result[d.Code] = d.Severity
+			}
+		}
+
+		return result
+	}
+
+	if got, want := levels(), map[string]types.DiagnosticSeverity{"missing-path-parameter": types.SeverityWarning}; !reflect.DeepEqual(got, want) {
+		t.Errorf("levels = %v, want %v", got, want)
+	}
+
+	if err := h.HandleDidChangeConfiguration(types.DidChangeConfigurationParams{
+		Settings: json.RawMessage(`{"lint": {"unused-path-parameter": "hint"}}`),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	wantLevels := map[string]types.DiagnosticSeverity{
+		"missing-path-parameter": types.SeverityWarning,
+		"unused-path-parameter":  types.SeverityHint,
+	}
+	if got := levels(); !reflect.DeepEqual(got, wantLevels) {
+		t.Errorf("levels = %v, want %v", got, wantLevels)
+	}
+}
+
+func TestHandler_Config_Errors(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, ConfigFileName)
+	writeFile(t, configPath, `roots: openapi.yaml
+ignore:
+  - "[z-a"
+lint:
+  no-such-rule: warning
+  unused-path-parameter: loud
+extensions: [internal]
+colour: blue
+`)
+
+	base := "file://" + filepath.ToSlash(dir)
+	configURI := base + "/" + ConfigFileName
+
+	var h Handler
+
+	if err := h.HandleInitialize(types.InitializeParams{RootURI: base}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A document in a nested directory finds the config in the workspace
+	// root.
+
+	loadFile(base+"/nested/api.yaml", "openapi: 3.0.0\n")(t, &h)
+
+	want := []string{
+		`0:0-0:5 invalid-config: roots must be a list of strings`,
+		`2:5-2:9 invalid-config: Invalid glob pattern "[z-a"`,
+		`4:2-4:14 invalid-config: Unknown lint rule "no-such-rule"`,
+		`5:25-5:29 invalid-config: Unknown level "loud", expected one of error, warning, information, hint or off`,
+		`6:12-6:22 invalid-config: Vendor extensions must start with x-, got "internal"`,
+		`7:0-7:6 invalid-config: Unknown config key "colour"`,
+	}
+
+	got := diagnosticStrings(t, &h, configURI)
+	if len(got) != len(want) {
+		t.Fatalf("diagnostics = %q, want %q", got, want)
+	}
+
+	// The order of the lint rules is not defined.
+	for _, w := range want {
+		found := false
+		for _, g := range got {
+			found = found || g == w
+		}
+		if !found {
+			t.Errorf("missing diagnostic %q in %q", w, got)
+		}
+	}
+
+	// Once the file is fixed, its diagnostics are cleared.
+
+	writeFile(t, configPath, "roots: [openapi.yaml]\n")
+
+	if err := h.HandleDidChangeWatchedFiles(types.DidChangeWatchedFilesParams{
+		Changes: []types.FileEvent{{URI: configURI, Type: types.FileChanged}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	params, err := h.Diagnostics()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantParams := []types.PublishDiagnosticsParams{{URI: configURI, Diagnostics: []types.Diagnostic{}}}
+	if !reflect.DeepEqual(params, wantParams) {
+		t.Errorf("Diagnostics() = %+v, want %+v", params, wantParams)
+	}
+}
+
+func TestHandler_Config_Nested(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, ConfigFileName), "extensions: [x-root]\n")
+
+	base := "file://" + filepath.ToSlash(dir)
+
+	var h Handler
+
+	if err := h.HandleInitialize(types.InitializeParams{RootURI: base}); err != nil {
+		t.Fatal(err)
+	}
+
+	src := "openapi: 3.0.0\nx-root: 1\nx-service: 2\n"
+
+	loadFile(base+"/service/api.yaml", src)(t, &h)
+
+	want := []string{`2:0-2:9 unknown-extension: Vendor extension "x-service" is not declared in .openapi-language-server.yaml`}
+	if got := diagnosticStrings(t, &h, base+"/service/api.yaml"); !reflect.DeepEqual(got, want) {
+		t.Errorf("diagnostics = %q, want %q", got, want)
+	}
+
+	// A config file that is created in the directory of the document takes
+	// over from the one in the workspace root.
+
+	writeFile(t, filepath.Join(dir, "service", ConfigFileName), "extensions: [x-service]\n")
+
+	if err := h.HandleDidChangeWatchedFiles(types.DidChangeWatchedFilesParams{
+		Changes: []types.FileEvent{{URI: base + "/service/" + ConfigFileName, Type: types.FileCreated}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	want = []string{`1:0-1:6 unknown-extension: Vendor extension "x-root" is not declared in .openapi-language-server.yaml`}
+	if got := diagnosticStrings(t, &h, base+"/service/api.yaml"); !reflect.DeepEqual(got, want) {
+		t.Errorf("diagnostics = %q, want %q", got, want)
+	}
+}
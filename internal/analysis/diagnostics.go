@@ -0,0 +1,235 @@
+package analysis
+
+import (
+	"cmp"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/armsnyder/openapi-language-server/internal/lsp"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+// diagnosticSource is the source reported on all diagnostics.
+const diagnosticSource = "openapi"
+
+// problem is a diagnostic together with the quick fixes that resolve it.
+type problem struct {
+	diagnostic types.Diagnostic
+	fixes      []fix
+}
+
+// fix is a quick fix that edits the file containing the problem.
+type fix struct {
+	title string
+	edits []types.TextEdit
+}
+
+// check inspects an open file and reports any problems. The file's document
+// has already been parsed.
+type check func(h *Handler, f *annotatedFile) []problem
+
+// checks run for every open file after every change. Besides the file itself,
+// they resolve its $refs, which stats and may parse the files they point to
+// and reads the cache of remote documents. The parsed files are kept until
+// they change on disk, so the cost is mostly one stat per referenced file.
+var checks = []check{
+	checkPathParameters,
+	checkExtensions,
+	checkRefs,
+}
+
+// saveChecks look at the whole workspace, which is too slow to do on every
+// change. They run when documents are opened, closed or saved.
+var saveChecks = []check{
+	checkDuplicateOperationIDs,
+}
+
+func (h *Handler) getProblems(uri string) ([]problem, error) {
+	if _, err := h.getDocument(uri); err != nil {
+		return nil, err
+	}
+
+	f := h.files[uri]
+
+	if f.outOfSync || !h.Settings.Enabled(FeatureDiagnostics) || isConfigFile(uri) || h.ignored(uri) || !h.related(uri) {
+		return nil, nil
+	}
+
+	if !f.checked {
+		f.problems = nil
+		for _, check := range checks {
+			f.problems = append(f.problems, check(h, f)...)
+		}
+		f.checked = true
+	}
+
+	if !f.saveChecked {
+		f.saveProblems = nil
+		for _, check := range saveChecks {
+			f.saveProblems = append(f.saveProblems, check(h, f)...)
+		}
+		f.saveChecked = true
+	}
+
+	var problems []problem
+	for _, p := range slices.Concat(f.problems, f.saveProblems) {
+		severity, ok := h.severity(uri, p.diagnostic.Code, p.diagnostic.Severity)
+		if !ok {
+			continue
+		}
+		p.diagnostic.Severity = severity
+		problems = append(problems, p)
+	}
+
+	slices.SortStableFunc(problems, func(a, b problem) int {
+		return cmp.Compare(a.diagnostic.Range.Start.Line, b.diagnostic.Range.Start.Line)
+	})
+
+	return problems, nil
+}
+
+func (h *Handler) Diagnostics() ([]types.PublishDiagnosticsParams, error) {
+	var result []types.PublishDiagnosticsParams
+
+	for _, uri := range h.closed {
+		result = append(result, types.PublishDiagnosticsParams{
+			URI:         uri,
+			Diagnostics: []types.Diagnostic{},
+		})
+	}
+	h.closed = nil
+
+	// The errors in config files are published whether or not the files
+	// are open.
+	h.loadConfigs()
+	for _, c := range h.sortedConfigs() {
+		if c.published || len(c.diagnostics) == 0 {
+			continue
+		}
+		c.published = true
+
+		result = append(result, types.PublishDiagnosticsParams{
+			URI:         c.uri,
+			Diagnostics: c.diagnostics,
+		})
+	}
+
+	for _, uri := range h.sortedURIs() {
+		if isConfigFile(uri) {
+			continue
+		}
+
+		problems, err := h.getProblems(uri)
+		if err != nil {
+			return nil, err
+		}
+
+		diagnostics := make([]types.Diagnostic, len(problems))
+		for i, p := range problems {
+			diagnostics[i] = p.diagnostic
+		}
+
+		f := h.files[uri]
+		if reflect.DeepEqual(diagnostics, f.published) || len(diagnostics)+len(f.published) == 0 {
+			continue
+		}
+		f.published = diagnostics
+
+		version := f.version
+
+		result = append(result, types.PublishDiagnosticsParams{
+			URI:         uri,
+			Version:     &version,
+			Diagnostics: diagnostics,
+		})
+	}
+
+	return result, nil
+}
+
+func (h *Handler) HandleCodeAction(params types.CodeActionParams) ([]types.CodeAction, error) {
+	uri := params.TextDocument.URI
+
+	if h.files[uri] == nil || !h.Settings.Enabled(FeatureCodeActions) || !h.related(uri) {
+		return nil, nil
+	}
+
+	problems, err := h.getProblems(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	var actions []types.CodeAction
+
+	for _, p := range problems {
+		if !overlaps(p.diagnostic.Range, params.Range) || !wantCodeAction(params.Context.Only, types.CodeActionQuickFix) {
+			continue
+		}
+
+		// Fixes insert YAML.
+		if h.files[uri].language != languageYAML {
+			continue
+		}
+
+		for _, fix := range p.fixes {
+			actions = append(actions, types.CodeAction{
+				Title:       fix.title,
+				Kind:        types.CodeActionQuickFix,
+				Diagnostics: []types.Diagnostic{p.diagnostic},
+				IsPreferred: len(p.fixes) == 1,
+				Edit:        h.workspaceEdit(uri, fix.edits),
+			})
+		}
+	}
+
+	if wantCodeAction(params.Context.Only, codeActionSortComponents) {
+		if action := h.sortAction(uri); action != nil {
+			actions = append(actions, *action)
+		}
+	}
+
+	return actions, nil
+}
+
+// wantCodeAction returns true if the client asked for code actions of the
+// given kind. Kinds are hierarchical, so asking for "source" includes
+// "source.sortComponents".
+func wantCodeAction(only []types.CodeActionKind, kind types.CodeActionKind) bool {
+	if len(only) == 0 {
+		return true
+	}
+
+	for _, want := range only {
+		if kind == want || strings.HasPrefix(string(kind), string(want)+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func overlaps(a, b types.Range) bool {
+	return !before(a.End, b.Start) && !before(b.End, a.Start)
+}
+
+func before(a, b types.Position) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Character < b.Character)
+}
+
+// insertLines returns an edit that inserts the given text, which must end with
+// a newline, before the given line number. If the line number is past the end
+// of the file, the text is appended instead.
+func insertLines(file *lsp.File, line int, text string) types.TextEdit {
+	end, err := file.GetPosition(file.Len())
+	if err != nil || line < end.Line {
+		pos := types.Position{Line: line}
+		return types.TextEdit{Range: types.Range{Start: pos, End: pos}, NewText: text}
+	}
+
+	if end.Character > 0 {
+		text = "\n" + text[:len(text)-1]
+	}
+
+	return types.TextEdit{Range: types.Range{Start: end, End: end}, NewText: text}
+}
@@ -0,0 +1,122 @@
+package analysis
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/armsnyder/openapi-language-server/internal/analysis/yaml"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+// HandleDocumentLink makes $refs to other files, and URL fields such as
+// externalDocs.url, clickable. Local $refs are left to go-to-definition.
+func (h *Handler) HandleDocumentLink(params types.DocumentLinkParams) ([]types.DocumentLink, error) {
+	if !h.Settings.Enabled(FeatureDocumentLinks) {
+		return nil, nil
+	}
+
+	uri := params.TextDocument.URI
+
+	document, err := h.getDocument(uri)
+	if err != nil {
+		h.logf("HandleDocumentLink: Error getting document %q: %v", uri, err)
+		return nil, nil
+	}
+
+	if !h.related(uri) {
+		return nil, nil
+	}
+
+	var links []types.DocumentLink
+
+	for _, line := range document.Nodes() {
+		if line.Value == "" {
+			continue
+		}
+
+		var target string
+
+		switch {
+		case line.Key == "$ref":
+			if strings.HasPrefix(line.Value, "#") {
+				continue
+			}
+			target = h.refLinkTarget(uri, line.Value)
+		case isLinkField(line):
+			target = linkTarget(uri, line.Value)
+		default:
+			continue
+		}
+
+		if target == "" {
+			continue
+		}
+
+		links = append(links, types.DocumentLink{
+			Range:  line.ValueRange,
+			Target: target,
+		})
+	}
+
+	return links, nil
+}
+
+// isLinkField returns true if the line holds a URL that is not a $ref.
+func isLinkField(line *yaml.Line) bool {
+	switch line.Key {
+	case "externalValue":
+		return true
+	case "termsOfService":
+		return line.Parent != nil && line.Parent.Key == "info"
+	case "url":
+		return line.Parent != nil && (line.Parent.Key == "externalDocs" || line.Parent.Key == "license")
+	default:
+		return false
+	}
+}
+
+// refLinkTarget returns the link target of a $ref to another document. If the
+// target document can be parsed, the fragment is replaced by the line that it
+// points to, in the "#L<line>" form that editors understand.
+func (h *Handler) refLinkTarget(baseURI, ref string) string {
+	uri, fragment, err := h.resolveRef(baseURI, ref)
+	if err != nil {
+		return ""
+	}
+
+	if !strings.HasPrefix(uri, "file:") {
+		return linkTarget(baseURI, ref)
+	}
+
+	if strings.Trim(fragment, "/") == "" {
+		return uri
+	}
+
+	target, err := h.resolve(baseURI, ref)
+	if err != nil || target.line == nil {
+		return uri
+	}
+
+	return fmt.Sprintf("%s#L%d", uri, target.line.Number+1)
+}
+
+// linkTarget resolves a URL relative to the document URI. Absolute URLs are
+// returned unchanged.
+func linkTarget(baseURI, value string) string {
+	u, err := url.Parse(value)
+	if err != nil {
+		return ""
+	}
+
+	if u.IsAbs() {
+		return value
+	}
+
+	base, err := url.Parse(baseURI)
+	if err != nil {
+		return ""
+	}
+
+	return base.ResolveReference(u).String()
+}
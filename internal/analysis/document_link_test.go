@@ -0,0 +1,77 @@
+package analysis_test
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	. "github.com/armsnyder/openapi-language-server/internal/analysis"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+func TestHandler_HandleDocumentLink(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "schemas", "pet.yaml"), `Pet:
+  type: object
+Owner:
+  type: object
+`)
+
+	base := "file://" + filepath.ToSlash(dir)
+
+	var h Handler
+
+	loadFile(base+"/api.yaml", `openapi: 3.0.0
+info:
+  termsOfService: https://example.com/terms
+  license:
+    name: MIT
+    url: https://opensource.org/licenses/MIT
+externalDocs:
+  url: ./docs/index.html
+paths:
+  /pets:
+    get:
+      responses:
+        "200":
+          content:
+            application/json:
+              schema:
+                $ref: ./schemas/pet.yaml#/Owner
+              examples:
+                pet:
+                  externalValue: https://example.com/pet.json
+        default:
+          $ref: "#/components/responses/Error"
+components:
+  schemas:
+    Pet:
+      $ref: "./schemas/pet.yaml"
+    Missing:
+      $ref: ./schemas/pet.yaml#/Missing
+    Remote:
+      $ref: https://example.com/schemas.yaml#/Remote
+`)(t, &h)
+
+	got, err := h.HandleDocumentLink(types.DocumentLinkParams{
+		TextDocument: types.TextDocumentIdentifier{URI: base + "/api.yaml"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []types.DocumentLink{
+		{Range: newRange("2:18-2:43"), Target: "https://example.com/terms"},
+		{Range: newRange("5:9-5:44"), Target: "https://opensource.org/licenses/MIT"},
+		{Range: newRange("7:7-7:24"), Target: base + "/docs/index.html"},
+		{Range: newRange("16:22-16:47"), Target: base + "/schemas/pet.yaml#L3"},
+		{Range: newRange("19:33-19:61"), Target: "https://example.com/pet.json"},
+		{Range: newRange("25:13-25:31"), Target: base + "/schemas/pet.yaml"},
+		{Range: newRange("27:12-27:39"), Target: base + "/schemas/pet.yaml"},
+		{Range: newRange("29:12-29:52"), Target: "https://example.com/schemas.yaml#/Remote"},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("links =\n%+v\nwant\n%+v", got, want)
+	}
+}
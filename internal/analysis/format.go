@@ -0,0 +1,354 @@
+package analysis
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/armsnyder/openapi-language-server/internal/analysis/yaml"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+// The formatter works line by line, so that comments and key order are
+// preserved. It normalizes the indentation of each line to the given tab size,
+// double-quotes $ref values, collapses runs of blank lines, and separates path
+// items and components with a blank line.
+
+// defaultTabSize is used when neither the client nor the settings specify a
+// tab size.
+const defaultTabSize = 2
+
+// Format returns the formatted contents of a YAML document.
+func Format(src []byte, tabSize int) ([]byte, error) {
+	f, err := newFormatter(src, tabSize)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	for i := range f.lines {
+		buf.WriteString(f.chunks[i])
+	}
+
+	return buf.Bytes(), nil
+}
+
+// CanFormat returns true if the formatter supports the document with the given
+// file name. Like the editor, it only formats YAML, which it tells from the
+// extension.
+func CanFormat(name string) bool {
+	return detectLanguage("", name) == languageYAML
+}
+
+func (h *Handler) HandleFormatting(params types.DocumentFormattingParams) ([]types.TextEdit, error) {
+	return h.formattingEdits(params.TextDocument.URI, params.Options, 0, -1)
+}
+
+func (h *Handler) HandleRangeFormatting(params types.DocumentRangeFormattingParams) ([]types.TextEdit, error) {
+	last := params.Range.End.Line
+	if params.Range.End.Character == 0 && last > params.Range.Start.Line {
+		last--
+	}
+
+	return h.formattingEdits(params.TextDocument.URI, params.Options, params.Range.Start.Line, last)
+}
+
+// formattingEdits returns edits that format the lines from first to last,
+// inclusive. A negative last line means the end of the document.
+func (h *Handler) formattingEdits(uri string, options types.FormattingOptions, first, last int) ([]types.TextEdit, error) {
+	file := h.files[uri]
+	if file == nil {
+		h.logf("Error formatting document: unknown file: %s", uri)
+		return nil, nil
+	}
+
+	if file.language != languageYAML || file.outOfSync || !h.related(uri) {
+		return nil, nil
+	}
+
+	tabSize := options.TabSize
+	if tabSize == 0 {
+		tabSize = h.Settings.TabSize
+	}
+
+	f, err := newFormatter(file.file.Bytes(), tabSize)
+	if err != nil {
+		h.logf("Error formatting document %q: %v", uri, err)
+		return nil, nil
+	}
+
+	if last < 0 || last >= len(f.lines) {
+		last = len(f.lines) - 1
+	}
+
+	edits := []types.TextEdit{}
+
+	for i := first; i <= last; i++ {
+		chunk := f.chunks[i]
+		if chunk == f.original(i) {
+			continue
+		}
+
+		end := types.Position{Line: i + 1}
+		if i == len(f.lines)-1 && !f.finalNewline {
+			end, err = file.file.GetPosition(file.file.Len())
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		edits = append(edits, types.TextEdit{
+			Range:   types.Range{Start: types.Position{Line: i}, End: end},
+			NewText: chunk,
+		})
+	}
+
+	return edits, nil
+}
+
+type formatter struct {
+	document yaml.Document
+
+	// lines is the text of each line, without the line ending.
+	lines        []string
+	newline      string
+	finalNewline bool
+	tabSize      int
+
+	// indents is the formatted indentation of each line.
+	indents []int
+
+	// raw is true for lines whose text must not change other than by shifting
+	// it, such as the contents of block scalars.
+	raw []bool
+
+	// chunks is the formatted text that replaces each line, including line
+	// endings. It is empty for lines that are removed.
+	chunks []string
+}
+
+func newFormatter(src []byte, tabSize int) (*formatter, error) {
+	document, err := yaml.Parse(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+
+	if tabSize <= 0 {
+		tabSize = defaultTabSize
+	}
+
+	f := &formatter{
+		document:     document,
+		newline:      "\n",
+		finalNewline: bytes.HasSuffix(src, []byte("\n")),
+		tabSize:      tabSize,
+	}
+
+	if bytes.Contains(src, []byte("\r\n")) {
+		f.newline = "\r\n"
+	}
+
+	if len(src) > 0 {
+		for _, line := range strings.Split(strings.TrimSuffix(string(src), "\n"), "\n") {
+			f.lines = append(f.lines, strings.TrimSuffix(line, "\r"))
+		}
+	}
+
+	// The parser and the split above must agree on the lines.
+	for len(f.lines) < len(document.Lines) {
+		f.lines = append(f.lines, "")
+	}
+
+	f.layout()
+	f.format()
+
+	return f, nil
+}
+
+// original returns the text of a line including its line ending.
+func (f *formatter) original(i int) string {
+	if i == len(f.lines)-1 && !f.finalNewline {
+		return f.lines[i]
+	}
+
+	return f.lines[i] + f.newline
+}
+
+// layout computes the formatted indentation of every line.
+func (f *formatter) layout() {
+	f.indents = make([]int, len(f.lines))
+	f.raw = make([]bool, len(f.lines))
+
+	for i := 0; i < len(f.document.Lines); i++ {
+		line := f.document.Lines[i]
+		if line.Empty {
+			continue
+		}
+
+		f.indents[i] = f.indent(line)
+
+		if line.IsBlockScalar() {
+			i = f.layoutBlockScalar(line)
+		}
+	}
+
+	// Comments are indented like the line that they describe, which is the
+	// next line that is not a comment.
+	next := -1
+	for i := len(f.document.Lines) - 1; i >= 0; i-- {
+		line := f.document.Lines[i]
+		switch {
+		case f.raw[i] || !line.Empty:
+			next = i
+		case line.Comment && next >= 0:
+			f.indents[i] = f.indents[next]
+		case line.Comment:
+			f.indents[i] = line.Indent
+		}
+	}
+}
+
+// layoutBlockScalar indents the contents of a block scalar one level below its
+// key, keeping their relative indentation. It returns the last line of the
+// contents.
+func (f *formatter) layoutBlockScalar(line *yaml.Line) int {
+	end := f.document.BlockScalarEnd(line)
+
+	base := -1
+	for j := line.Number + 1; j <= end; j++ {
+		if next := f.document.Lines[j]; (!next.Empty || next.Comment) && (base < 0 || next.Indent < base) {
+			base = next.Indent
+		}
+	}
+
+	column := f.indents[line.Number]
+	if line.Item {
+		column += len("- ")
+	}
+
+	for j := line.Number + 1; j <= end; j++ {
+		f.raw[j] = true
+		f.indents[j] = f.document.Lines[j].Indent - base + column + f.tabSize
+	}
+
+	return end
+}
+
+// indent returns the formatted indentation of a line that is part of the
+// document tree.
+func (f *formatter) indent(line *yaml.Line) int {
+	parent := line.Parent
+	if parent == nil {
+		return 0
+	}
+
+	parentIndent := f.indents[parent.Number]
+
+	switch {
+	case !line.Item && line.Key == "":
+		// A continuation of a multi-line scalar moves with its parent.
+		return line.Indent + parentIndent - parent.Indent
+	case !parent.Item:
+		return parentIndent + f.tabSize
+	case parent.Key == "" || line.Indent <= parent.KeyRange.Start.Character:
+		// The line is another entry of the mapping that starts on the item
+		// line, so it is aligned with the first key.
+		return parentIndent + len("- ")
+	default:
+		// The line is nested below the first key of the item.
+		return parentIndent + len("- ") + f.tabSize
+	}
+}
+
+// format computes the formatted text of every line.
+func (f *formatter) format() {
+	f.chunks = make([]string, len(f.lines))
+
+	separated := map[*yaml.Line]bool{}
+
+	for i, text := range f.lines {
+		var line *yaml.Line
+		if i < len(f.document.Lines) {
+			line = f.document.Lines[i]
+		}
+
+		switch {
+		case f.raw[i]:
+			if strings.TrimSpace(text) == "" {
+				f.chunks[i] = f.newline
+			} else {
+				f.chunks[i] = strings.Repeat(" ", f.indents[i]) + strings.TrimLeft(text, " ") + f.newline
+			}
+			continue
+		case line == nil || (line.Empty && !line.Comment):
+			if !f.lastLineBlank(i) {
+				f.chunks[i] = f.newline
+			}
+			continue
+		}
+
+		if line.Key == "$ref" {
+			text = quoteRef(text, line)
+		}
+
+		f.chunks[i] = strings.Repeat(" ", f.indents[i]) + strings.TrimRight(strings.TrimLeft(text, " "), " \t") + f.newline
+
+		if !line.Empty && (isPathItem(line) || isComponent(line)) {
+			if separated[line.Parent] {
+				f.separate(i)
+			}
+			separated[line.Parent] = true
+		}
+	}
+
+	// Remove blank lines at the end of the document.
+	for i := len(f.chunks) - 1; i >= 0 && (f.chunks[i] == "" || f.chunks[i] == f.newline); i-- {
+		if f.raw[i] {
+			break
+		}
+		f.chunks[i] = ""
+	}
+}
+
+// separate ensures that there is a blank line before the given line, or before
+// the comments directly above it.
+func (f *formatter) separate(i int) {
+	for i > 0 && f.document.Lines[i-1].Comment {
+		i--
+	}
+
+	if !f.lastLineBlank(i) {
+		f.chunks[i] = f.newline + f.chunks[i]
+	}
+}
+
+// lastLineBlank returns true if the formatted text before the given line ends
+// with a blank line, or if there is no text before the line.
+func (f *formatter) lastLineBlank(i int) bool {
+	for j := i - 1; j >= 0; j-- {
+		if f.chunks[j] != "" {
+			return f.chunks[j] == f.newline || strings.HasSuffix(f.chunks[j], f.newline+f.newline)
+		}
+	}
+
+	return true
+}
+
+// quoteRef returns the line with its $ref value in double quotes. Values that
+// would need escaping are left unchanged.
+func quoteRef(text string, line *yaml.Line) string {
+	start, end := line.ValueRange.Start.Character, line.ValueRange.End.Character
+	if line.Value == "" || start == 0 || end > len(text) || strings.ContainsAny(line.Value, `"\`) {
+		return text
+	}
+
+	value, rest := line.Value, text[end:]
+
+	if q := text[start-1]; (q == '"' || q == '\'') && end < len(text) && text[end] == q {
+		start--
+		rest = text[end+1:]
+	} else if comment := strings.Index(value, " #"); comment >= 0 {
+		value, rest = strings.TrimRight(value[:comment], " "), value[comment:]
+	}
+
+	return text[:start] + `"` + value + `"` + rest
+}
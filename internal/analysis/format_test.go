@@ -0,0 +1,227 @@
+package analysis_test
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/armsnyder/openapi-language-server/internal/analysis"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		tabSize int
+		want    string
+	}{
+		{
+			name: "indentation",
+			src: `paths:
+   /pets:
+      get:
+         parameters:
+         - name: limit
+           in: query
+           schema:
+              type: integer
+`,
+			tabSize: 2,
+			want: `paths:
+  /pets:
+    get:
+      parameters:
+        - name: limit
+          in: query
+          schema:
+            type: integer
+`,
+		},
+		{
+			name: "tab size",
+			src: `info:
+  title: Pets
+  license:
+    name: MIT
+`,
+			tabSize: 4,
+			want: `info:
+    title: Pets
+    license:
+        name: MIT
+`,
+		},
+		{
+			name: "ref quotes",
+			src: `a:
+  $ref: '#/components/schemas/A'
+b:
+  $ref: ./b.yaml # comment
+c:
+  $ref: "#/c"
+`,
+			tabSize: 2,
+			want: `a:
+  $ref: "#/components/schemas/A"
+b:
+  $ref: "./b.yaml" # comment
+c:
+  $ref: "#/c"
+`,
+		},
+		{
+			name: "blank lines",
+			src: `
+
+openapi: 3.0.0
+
+
+paths:
+  /a:
+    get: {}
+  # The b path.
+  /b:
+    get: {}
+components:
+  schemas:
+    A:
+      type: object
+    B:
+      type: object
+
+
+`,
+			tabSize: 2,
+			want: `openapi: 3.0.0
+
+paths:
+  /a:
+    get: {}
+
+  # The b path.
+  /b:
+    get: {}
+components:
+  schemas:
+    A:
+      type: object
+
+    B:
+      type: object
+`,
+		},
+		{
+			name: "comments and block scalars",
+			src: `info:
+    # The title.
+    title: Pets   
+    description: |
+         Some text.
+           Indented.
+         # Not a comment.
+# The end.
+`,
+			tabSize: 2,
+			want: `info:
+  # The title.
+  title: Pets
+  description: |
+    Some text.
+      Indented.
+    # Not a comment.
+# The end.
+`,
+		},
+		{
+			name:    "final newline",
+			src:     "openapi: 3.0.0",
+			tabSize: 2,
+			want:    "openapi: 3.0.0\n",
+		},
+		{
+			name:    "line endings",
+			src:     "info:\r\n    title: Pets\r\n",
+			tabSize: 2,
+			want:    "info:\r\n  title: Pets\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Format([]byte(tt.src), tt.tabSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("Format() =\n%s\nwant\n%s", got, tt.want)
+			}
+
+			again, err := Format(got, tt.tabSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(again) != string(got) {
+				t.Errorf("Format() is not idempotent, second pass =\n%s", again)
+			}
+		})
+	}
+}
+
+func TestHandler_HandleFormatting(t *testing.T) {
+	var h Handler
+
+	loadFile("file:///api.yaml", `openapi: 3.0.0
+info:
+   title: Pets
+paths:
+   /a:
+      get: {}
+   /b:
+      get: {}`)(t, &h)
+
+	options := types.FormattingOptions{TabSize: 2, InsertSpaces: true}
+
+	t.Run("document", func(t *testing.T) {
+		got, err := h.HandleFormatting(types.DocumentFormattingParams{
+			TextDocument: types.TextDocumentIdentifier{URI: "file:///api.yaml"},
+			Options:      options,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []types.TextEdit{
+			{Range: newRange("2:0-3:0"), NewText: "  title: Pets\n"},
+			{Range: newRange("4:0-5:0"), NewText: "  /a:\n"},
+			{Range: newRange("5:0-6:0"), NewText: "    get: {}\n"},
+			{Range: newRange("6:0-7:0"), NewText: "\n  /b:\n"},
+			{Range: newRange("7:0-7:13"), NewText: "    get: {}\n"},
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("edits = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("range", func(t *testing.T) {
+		got, err := h.HandleRangeFormatting(types.DocumentRangeFormattingParams{
+			TextDocument: types.TextDocumentIdentifier{URI: "file:///api.yaml"},
+			Range:        newRange("4:2-6:0"),
+			Options:      options,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []types.TextEdit{
+			{Range: newRange("4:0-5:0"), NewText: "  /a:\n"},
+			{Range: newRange("5:0-6:0"), NewText: "    get: {}\n"},
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("edits = %+v, want %+v", got, want)
+		}
+	})
+}
@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"fmt"
 	"log"
+	"path"
+	"slices"
+	"strings"
 
 	"github.com/armsnyder/openapi-language-server/internal/analysis/yaml"
 	"github.com/armsnyder/openapi-language-server/internal/lsp"
@@ -15,12 +18,114 @@ import (
 type Handler struct {
 	lsp.NopHandler
 
-	files map[string]*annotatedFile
+	// Settings configure optional behavior of the handler.
+	Settings Settings
+
+	// folders are the URIs of the workspace folders, with trailing slashes.
+	// Relative paths in the settings are resolved against each of them, and
+	// each folder is indexed separately.
+	folders []string
+
+	files   map[string]*annotatedFile
+	disk    map[string]diskDocument
+	configs map[string]*projectConfig
+	closed  []string
+
+	// remote are the documents that are fetched over HTTP(S), by URL.
+	remote map[string]*remoteDocument
+
+	// tasks receives work that finished in the background, which the server
+	// runs between messages.
+	tasks chan func()
+
+	// done is closed when the handler is closed, so that work in the
+	// background stops instead of waiting for the server to run its tasks.
+	done chan struct{}
+
+	// fragments are the URIs of the documents that are reachable from a root
+	// document, or nil if they have not been found since documents were last
+	// opened, closed or saved, or the settings, config files or workspace
+	// folders changed.
+	fragments map[string]bool
+
+	// messages are waiting to be sent to the client.
+	messages []lsp.Message
+}
+
+// logf logs a warning, both to standard error and to the client.
+func (h *Handler) logf(format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	_ = log.Output(2, message)
+	h.messages = append(h.messages, lsp.Message{Type: types.MessageWarning, Message: message})
+}
+
+// showf logs an error and asks the client to show it to the user. It is for
+// failures of actions that the user started, such as commands.
+func (h *Handler) showf(format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	_ = log.Output(2, message)
+	h.messages = append(h.messages, lsp.Message{Type: types.MessageError, Message: message, Show: true})
+}
+
+func (h *Handler) Messages() []lsp.Message {
+	messages := h.messages
+	h.messages = nil
+	return messages
 }
 
 type annotatedFile struct {
-	file     lsp.File
-	document yaml.Document
+	uri       string
+	version   int
+	language  language
+	file      lsp.File
+	document  yaml.Document
+	problems  []problem
+	checked   bool
+	published []types.Diagnostic
+
+	// saveProblems are the problems found by the checks that only run when
+	// documents are opened, closed or saved. Changes in between move them.
+	saveProblems []problem
+	saveChecked  bool
+	// outOfSync is true if a change to the file could not be applied, so its
+	// content no longer matches the editor. The file is not analyzed until the
+	// client sends its full content again.
+	outOfSync bool
+}
+
+// language is the syntax of a document.
+type language int
+
+const (
+	languageYAML language = iota
+	languageJSON
+)
+
+// detectLanguage returns the syntax of a document from the language ID that
+// the client reports, or from the extension of its URI if the server does not
+// know the language ID.
+func detectLanguage(languageID, uri string) language {
+	switch languageID {
+	case "json", "jsonc":
+		return languageJSON
+	case "yaml":
+		return languageYAML
+	}
+
+	if strings.EqualFold(path.Ext(uri), ".json") {
+		return languageJSON
+	}
+
+	return languageYAML
+}
+
+// parse parses a document that is written in the language.
+func (l language) parse(content []byte) (yaml.Document, error) {
+	if l == languageJSON {
+		return yaml.ParseJSON(bytes.NewReader(content))
+	}
+
+	return yaml.Parse(bytes.NewReader(content))
 }
 
 func (h *Handler) getDocument(uri string) (yaml.Document, error) {
@@ -30,7 +135,7 @@ func (h *Handler) getDocument(uri string) (yaml.Document, error) {
 	}
 
 	if f.document.Lines == nil {
-		document, err := yaml.Parse(bytes.NewReader(f.file.Bytes()))
+		document, err := f.language.parse(f.file.Bytes())
 		if err != nil {
 			return yaml.Document{}, err
 		}
@@ -40,14 +145,85 @@ func (h *Handler) getDocument(uri string) (yaml.Document, error) {
 	return f.document, nil
 }
 
-func (*Handler) Capabilities() types.ServerCapabilities {
+func (h *Handler) sortedURIs() []string {
+	uris := make([]string, 0, len(h.files))
+	for uri := range h.files {
+		uris = append(uris, uri)
+	}
+	slices.Sort(uris)
+	return uris
+}
+
+// workspaceEdit returns an edit of a single document. Edits of open documents
+// are also tagged with the version of the document that they were computed
+// for, so that the client can reject them if the document has changed since.
+func (h *Handler) workspaceEdit(uri string, edits []types.TextEdit) *types.WorkspaceEdit {
+	edit := &types.WorkspaceEdit{
+		Changes: map[string][]types.TextEdit{uri: edits},
+	}
+
+	if f := h.files[uri]; f != nil {
+		version := f.version
+		edit.DocumentChanges = []types.TextDocumentEdit{{
+			TextDocument: types.OptionalVersionedTextDocumentIdentifier{
+				TextDocumentIdentifier: types.TextDocumentIdentifier{URI: uri},
+				Version:                &version,
+			},
+			Edits: edits,
+		}}
+	}
+
+	return edit
+}
+
+// invalidateProblems marks the problems of all open files as stale. Checks may
+// look beyond a single file, so any change can affect any file.
+func (h *Handler) invalidateProblems() {
+	for _, f := range h.files {
+		f.checked = false
+	}
+}
+
+// invalidateSaveProblems marks the problems of the checks that run on save as
+// stale for all open files. Which documents are reachable from a root is found
+// again too, since it means walking the roots on disk, which is too slow to do
+// on every change.
+func (h *Handler) invalidateSaveProblems() {
+	h.fragments = nil
+
+	for _, f := range h.files {
+		f.saveChecked = false
+	}
+}
+
+func (h *Handler) Capabilities() types.ServerCapabilities {
 	return types.ServerCapabilities{
 		TextDocumentSync: types.TextDocumentSyncOptions{
 			OpenClose: true,
 			Change:    types.SyncIncremental,
+			// The settings can change after initialization, so the edits
+			// before saving are always requested, and there are none if
+			// the settings do not ask for them.
+			WillSaveWaitUntil: true,
+			Save:              &types.SaveOptions{},
+		},
+		DefinitionProvider:              true,
+		ReferencesProvider:              true,
+		HoverProvider:                   true,
+		CodeActionProvider:              true,
+		TypeHierarchyProvider:           true,
+		CallHierarchyProvider:           true,
+		CodeLensProvider:                &types.CodeLensOptions{ResolveProvider: true},
+		InlayHintProvider:               true,
+		DocumentLinkProvider:            &types.DocumentLinkOptions{},
+		FoldingRangeProvider:            true,
+		SelectionRangeProvider:          true,
+		DocumentFormattingProvider:      true,
+		DocumentRangeFormattingProvider: true,
+		ExecuteCommandProvider:          &types.ExecuteCommandOptions{Commands: []string{bundleCommand}},
+		Workspace: &types.WorkspaceServerCapabilities{
+			WorkspaceFolders: types.WorkspaceFoldersServerCapabilities{Supported: true, ChangeNotifications: true},
 		},
-		DefinitionProvider: true,
-		ReferencesProvider: true,
 	}
 }
 
@@ -56,49 +232,194 @@ func (h *Handler) HandleOpen(params types.DidOpenTextDocumentParams) error {
 		h.files = make(map[string]*annotatedFile)
 	}
 
-	var f annotatedFile
+	f := annotatedFile{
+		uri:      params.TextDocument.URI,
+		version:  params.TextDocument.Version,
+		language: detectLanguage(params.TextDocument.LanguageID, params.TextDocument.URI),
+	}
 
 	f.file.Reset([]byte(params.TextDocument.Text))
 	h.files[params.TextDocument.URI] = &f
+	h.invalidateProblems()
+	h.invalidateSaveProblems()
 
 	return nil
 }
 
 func (h *Handler) HandleClose(params types.DidCloseTextDocumentParams) error {
+	if f, ok := h.files[params.TextDocument.URI]; ok && len(f.published) > 0 {
+		h.closed = append(h.closed, params.TextDocument.URI)
+	}
+
 	delete(h.files, params.TextDocument.URI)
+	h.invalidateProblems()
+	h.invalidateSaveProblems()
 	return nil
 }
 
 func (h *Handler) HandleChange(params types.DidChangeTextDocumentParams) error {
-	f, ok := h.files[params.TextDocument.URI]
+	uri, version := params.TextDocument.URI, params.TextDocument.Version
+
+	f, ok := h.files[uri]
 	if !ok {
-		log.Printf("HandleChange: Unknown file %q", params.TextDocument.URI)
+		h.logf("HandleChange: Unknown file %q", uri)
 		return nil
 	}
 
+	// Versions increase with each change, but not necessarily by one. Some
+	// clients, such as Neovim, skip numbers. Changes that arrive out of order
+	// are stale.
+	switch {
+	case version <= f.version:
+		h.logf("HandleChange: Ignoring version %d of %q, which is not newer than version %d", version, uri, f.version)
+		return nil
+	case version > f.version+1:
+		log.Printf("HandleChange: Version of %q skipped from %d to %d", uri, f.version, version)
+	}
+
+	f.version = version
+
 	for _, change := range params.ContentChanges {
+		if change.Range == nil && f.outOfSync {
+			h.logf("HandleChange: Resynchronized %q with its full content", uri)
+			f.outOfSync = false
+		}
+
+		if f.outOfSync {
+			continue
+		}
+
 		if err := f.file.ApplyChange(change); err != nil {
-			return err
+			h.showf("Cannot apply a change to %s: %v. Reopen the document to resume analysis.", uri, err)
+			f.outOfSync = true
+			f.saveProblems = nil
+			continue
 		}
+
+		f.reparse(change)
+		f.shiftSaveProblems(change)
 	}
 
-	f.document = yaml.Document{}
+	h.invalidateProblems()
 
 	return nil
 }
 
+// reparse updates the parsed document of a file after a change. Changes to a
+// range only re-parse the part of the document that they affect. The document
+// is parsed lazily if it is not already parsed.
+func (f *annotatedFile) reparse(change types.TextDocumentContentChangeEvent) {
+	if f.document.Lines == nil {
+		return
+	}
+
+	if change.Range == nil || f.language != languageYAML {
+		f.document = yaml.Document{}
+		return
+	}
+
+	start, end := change.Range.Start.Line, change.Range.End.Line
+
+	document, err := f.document.Reparse(&f.file, start, end, start+strings.Count(change.Text, "\n"))
+	if err != nil {
+		f.document = yaml.Document{}
+		return
+	}
+
+	f.document = document
+}
+
+// shiftSaveProblems moves the problems found on save along with the lines that
+// a change adds or removes, so that they are shown until the next save.
+// Problems on the changed lines are dropped, since their positions are lost.
+func (f *annotatedFile) shiftSaveProblems(change types.TextDocumentContentChangeEvent) {
+	if change.Range == nil {
+		f.saveProblems = nil
+		return
+	}
+
+	start, end := change.Range.Start.Line, change.Range.End.Line
+	delta := strings.Count(change.Text, "\n") - (end - start)
+
+	// shift returns the range after the change, or false if the change
+	// overlaps it.
+	shift := func(r types.Range) (types.Range, bool) {
+		switch {
+		case r.End.Line < start:
+			return r, true
+		case r.Start.Line > end:
+			r.Start.Line += delta
+			r.End.Line += delta
+			return r, true
+		default:
+			return r, false
+		}
+	}
+
+	problems := f.saveProblems[:0]
+
+	for _, p := range f.saveProblems {
+		var ok bool
+		if p.diagnostic.Range, ok = shift(p.diagnostic.Range); !ok {
+			continue
+		}
+
+		related := slices.Clone(p.diagnostic.RelatedInformation)
+		for i, info := range related {
+			if rng, ok := shift(info.Location.Range); ok && info.Location.URI == f.uri {
+				related[i].Location.Range = rng
+			}
+		}
+		p.diagnostic.RelatedInformation = related
+
+		var fixes []fix
+	fixes:
+		for _, x := range p.fixes {
+			edits := slices.Clone(x.edits)
+			for i := range edits {
+				if edits[i].Range, ok = shift(edits[i].Range); !ok {
+					continue fixes
+				}
+			}
+			fixes = append(fixes, fix{title: x.title, edits: edits})
+		}
+		p.fixes = fixes
+
+		problems = append(problems, p)
+	}
+
+	f.saveProblems = problems
+}
+
 func (h *Handler) HandleDefinition(params types.DefinitionParams) ([]types.Location, error) {
 	document, err := h.getDocument(params.TextDocument.URI)
 	if err != nil {
-		log.Printf("HandleDefinition: Error getting document %q: %v", params.TextDocument.URI, err)
+		h.logf("HandleDefinition: Error getting document %q: %v", params.TextDocument.URI, err)
 		return nil, nil
 	}
 
-	if params.Position.Line >= len(document.Lines) {
+	line := document.At(params.Position)
+	if line == nil {
 		return nil, nil
 	}
 
-	ref := document.Lines[params.Position.Line].Value
+	ref := line.Value
+
+	// References to other documents are resolved too. Remote documents are
+	// opened from the cache.
+	if line.Key == "$ref" && ref != "" && !strings.HasPrefix(ref, "#") {
+		target, err := h.resolve(params.TextDocument.URI, ref)
+		if err != nil {
+			return nil, nil
+		}
+
+		var rng types.Range
+		if target.line != nil {
+			rng = target.line.KeyRange
+		}
+
+		return []types.Location{{URI: h.locationURI(target.uri), Range: rng}}, nil
+	}
 
 	referencedLine := document.Locate(ref)
 	if referencedLine == nil {
@@ -114,27 +435,16 @@ func (h *Handler) HandleDefinition(params types.DefinitionParams) ([]types.Locat
 func (h *Handler) HandleReferences(params types.ReferenceParams) ([]types.Location, error) {
 	document, err := h.getDocument(params.TextDocument.URI)
 	if err != nil {
-		log.Printf("HandleReferences: Error getting document %q: %v", params.TextDocument.URI, err)
+		h.logf("HandleReferences: Error getting document %q: %v", params.TextDocument.URI, err)
 		return nil, nil
 	}
 
-	if params.Position.Line >= len(document.Lines) {
+	line := document.At(params.Position)
+	if line == nil {
 		return nil, nil
 	}
 
-	ref := document.Lines[params.Position.Line].KeyRef()
-
-	var locations []types.Location
-
-	for _, line := range document.Lines {
-		if line.Value == ref {
-			locations = append(locations, types.Location{
-				URI:   params.TextDocument.URI,
-				Range: line.ValueRange,
-			})
-		}
-	}
-	return locations, nil
+	return h.findReferences(params.TextDocument.URI, line.KeyRef()), nil
 }
 
 var _ lsp.Handler = (*Handler)(nil)
@@ -1,6 +1,7 @@
 package analysis_test
 
 import (
+	"fmt"
 	"reflect"
 	"regexp"
 	"strconv"
@@ -174,7 +175,10 @@ func TestHandler_HandleChangeThenHandleDefinition(t *testing.T) {
 	// Add the reference to the file.
 
 	if err := h.HandleChange(types.DidChangeTextDocumentParams{
-		TextDocument: types.TextDocumentIdentifier{URI: "file:///foo.yaml"},
+		TextDocument: types.VersionedTextDocumentIdentifier{
+			TextDocumentIdentifier: types.TextDocumentIdentifier{URI: "file:///foo.yaml"},
+			Version:                1,
+		},
 		ContentChanges: []types.TextDocumentContentChangeEvent{{
 			Text: `foo:
   $ref: "#/bar/baz"
@@ -201,6 +205,265 @@ func TestHandler_HandleChangeThenHandleDefinition(t *testing.T) {
 	}
 }
 
+func TestHandler_HandleChange_Incremental(t *testing.T) {
+	var h Handler
+
+	loadFile("file:///foo.yaml", `foo:
+  $ref: "#/bar/qux"
+bar:
+  baz:
+    type: object
+  qux:
+    type: string`)(t, &h)
+
+	// Parse the file, so that the changes below update the parsed document.
+
+	if _, err := h.HandleDefinition(definitionParams("file:///foo.yaml", "0:0")); err != nil {
+		t.Fatalf("HandleDefinition: %v", err)
+	}
+
+	// Insert lines within the subtree of bar, which moves qux down.
+
+	if err := h.HandleChange(types.DidChangeTextDocumentParams{
+		TextDocument: types.VersionedTextDocumentIdentifier{
+			TextDocumentIdentifier: types.TextDocumentIdentifier{URI: "file:///foo.yaml"},
+			Version:                1,
+		},
+		ContentChanges: []types.TextDocumentContentChangeEvent{
+			{
+				Text:  "\n    description: A baz\n    format: uuid",
+				Range: toPtr(newRange("4:16-4:16")),
+			},
+			{
+				Text:  "string",
+				Range: toPtr(newRange("5:16-5:20")),
+			},
+		},
+	}); err != nil {
+		t.Fatalf("HandleChange: %v", err)
+	}
+
+	got, err := h.HandleDefinition(definitionParams("file:///foo.yaml", "1:10"))
+	if err != nil {
+		t.Fatalf("HandleDefinition: %v", err)
+	}
+
+	want := locations("file:///foo.yaml", "7:2-7:5")
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("HandleDefinition() = %v, want %v", got, want)
+	}
+}
+
+func TestHandler_HandleChange_Versions(t *testing.T) {
+	var h Handler
+
+	if err := h.HandleOpen(types.DidOpenTextDocumentParams{
+		TextDocument: types.TextDocumentItem{
+			URI:     "file:///api.yaml",
+			Version: 1,
+			Text: `openapi: 3.0.0
+paths:
+  /b:
+    get:
+      operationId: same
+  /a:
+    get:
+      operationId: same
+`,
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	change := func(version int, changes ...types.TextDocumentContentChangeEvent) {
+		t.Helper()
+
+		if err := h.HandleChange(types.DidChangeTextDocumentParams{
+			TextDocument: types.VersionedTextDocumentIdentifier{
+				TextDocumentIdentifier: types.TextDocumentIdentifier{URI: "file:///api.yaml"},
+				Version:                version,
+			},
+			ContentChanges: changes,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	diagnostics := func() []types.PublishDiagnosticsParams {
+		t.Helper()
+
+		params, err := h.Diagnostics()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return params
+	}
+
+	params := diagnostics()
+	if len(params) != 1 || params[0].Version == nil || *params[0].Version != 1 || len(params[0].Diagnostics) != 2 {
+		t.Fatalf("Diagnostics() = %+v, want 2 diagnostics for version 1", params)
+	}
+
+	// A change that is not newer than the document is ignored.
+
+	change(1, types.TextDocumentContentChangeEvent{Text: "x", Range: toPtr(newRange("0:0-0:5"))})
+
+	actions, err := h.HandleCodeAction(types.CodeActionParams{
+		TextDocument: types.TextDocumentIdentifier{URI: "file:///api.yaml"},
+		Context:      types.CodeActionContext{Only: []types.CodeActionKind{"source"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(actions) != 1 || actions[0].Edit == nil || len(actions[0].Edit.DocumentChanges) != 1 {
+		t.Fatalf("HandleCodeAction() = %+v, want a versioned sort action", actions)
+	}
+
+	if v := actions[0].Edit.DocumentChanges[0].TextDocument.Version; v == nil || *v != 1 {
+		t.Errorf("sort action has version %v, want 1", v)
+	}
+
+	// Versions may skip numbers, and the change still applies.
+
+	change(3, types.TextDocumentContentChangeEvent{Text: "", Range: toPtr(newRange("7:0-8:0"))})
+
+	for _, message := range h.Messages() {
+		if message.Show {
+			t.Errorf("Messages() has %+v, want no message that is shown to the user", message)
+		}
+	}
+
+	if err := h.HandleSave(types.DidSaveTextDocumentParams{
+		TextDocument: types.TextDocumentIdentifier{URI: "file:///api.yaml"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if params := diagnostics(); len(params) != 1 || *params[0].Version != 3 || len(params[0].Diagnostics) != 0 {
+		t.Errorf("Diagnostics() = %+v, want no diagnostics for version 3 without the duplicate", params)
+	}
+
+	change(4, types.TextDocumentContentChangeEvent{Text: `openapi: 3.0.0
+paths:
+  /b:
+    get:
+      operationId: same
+  /a:
+    get:
+      operationId: same
+`})
+
+	// The duplicate operation IDs are only checked on save.
+
+	if err := h.HandleSave(types.DidSaveTextDocumentParams{
+		TextDocument: types.TextDocumentIdentifier{URI: "file:///api.yaml"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	params = diagnostics()
+	if len(params) != 1 || *params[0].Version != 4 || len(params[0].Diagnostics) != 2 {
+		t.Errorf("Diagnostics() = %+v, want 2 diagnostics for version 4", params)
+	}
+}
+
+func TestHandler_JSON(t *testing.T) {
+	var h Handler
+
+	if err := h.HandleOpen(types.DidOpenTextDocumentParams{
+		TextDocument: types.TextDocumentItem{
+			URI:        "file:///api",
+			LanguageID: "json",
+			Text: `{
+  "paths": {
+    "/pets": {
+      "get": {
+        "operationId": "listPets",
+        "responses": {
+          "200": {
+            "$ref": "#/components/responses/Pets"
+          }
+        }
+      }
+    }
+  },
+  "components": {
+    "responses": {
+      "Pets": {
+        "description": "A list of pets"
+      }
+    }
+  }
+}
+`,
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := h.HandleDefinition(definitionParams("file:///api", "7:16"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := locations("file:///api", "15:7-15:11")
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("HandleDefinition() = %v, want %v", got, want)
+	}
+
+	// YAML formatting does not apply to JSON documents.
+
+	edits, err := h.HandleFormatting(types.DocumentFormattingParams{
+		TextDocument: types.TextDocumentIdentifier{URI: "file:///api"},
+		Options:      types.FormattingOptions{TabSize: 2},
+	})
+	if err != nil || edits != nil {
+		t.Errorf("HandleFormatting() = %v, %v, want no edits", edits, err)
+	}
+}
+
+func TestHandler_MinifiedJSON(t *testing.T) {
+	var h Handler
+
+	text := `{"paths":{"/pets":{"get":{"responses":{"200":{"$ref":"#/components/responses/Pets"}}}}},` +
+		`"components":{"responses":{"Pets":{"description":"A list of pets"}}}}`
+
+	if err := h.HandleOpen(types.DidOpenTextDocumentParams{
+		TextDocument: types.TextDocumentItem{
+			URI:        "file:///api",
+			LanguageID: "json",
+			Text:       text,
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	ref := strings.Index(text, "#/components")
+	pets := strings.Index(text, `"Pets"`) + 1
+
+	got, err := h.HandleDefinition(definitionParams("file:///api", fmt.Sprintf("0:%d", ref+2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := locations("file:///api", fmt.Sprintf("0:%d-0:%d", pets, pets+4))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("HandleDefinition() = %v, want %v", got, want)
+	}
+
+	got, err = h.HandleReferences(referenceParams("file:///api", fmt.Sprintf("0:%d", pets)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want = locations("file:///api", fmt.Sprintf("0:%d-0:%d", ref, ref+len("#/components/responses/Pets")))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("HandleReferences() = %v, want %v", got, want)
+	}
+}
+
 func loadFile(uri, text string) HandlerSetupFunc {
 	return func(t *testing.T, h *Handler) {
 		if err := h.HandleOpen(types.DidOpenTextDocumentParams{
@@ -0,0 +1,58 @@
+package analysis
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+// HandleHover describes the target of the $ref under the cursor, following
+// references into other files and remote documents.
+func (h *Handler) HandleHover(params types.HoverParams) (*types.Hover, error) {
+	if !h.Settings.Enabled(FeatureHover) {
+		return nil, nil
+	}
+
+	uri := params.TextDocument.URI
+
+	document, err := h.getDocument(uri)
+	if err != nil {
+		h.logf("HandleHover: Error getting document %q: %v", uri, err)
+		return nil, nil
+	}
+
+	if !h.related(uri) {
+		return nil, nil
+	}
+
+	line := document.At(params.Position)
+	if line == nil || line.Key != "$ref" || line.Value == "" {
+		return nil, nil
+	}
+
+	var value string
+
+	target, err := h.resolve(uri, line.Value)
+	switch {
+	case errors.Is(err, errRemotePending):
+		value = "Fetching " + line.Value + "…"
+	case err != nil:
+		value = fmt.Sprintf("Unresolved: %v", err)
+	default:
+		value = "`" + h.describeRef(uri, line.Value) + "`"
+
+		if description := target.fields()["description"]; description != nil && description.Value != "" && !description.IsBlockScalar() {
+			value += "\n\n" + unquoteScalar(description.Value)
+		}
+
+		if isRemote(target.uri) {
+			value += "\n\nFrom " + target.uri
+		}
+	}
+
+	return &types.Hover{
+		Contents: types.MarkupContent{Kind: types.MarkupMarkdown, Value: value},
+		Range:    &line.ValueRange,
+	}, nil
+}
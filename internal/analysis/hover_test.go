@@ -0,0 +1,80 @@
+package analysis_test
+
+import (
+	"testing"
+
+	. "github.com/armsnyder/openapi-language-server/internal/analysis"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+func TestHandler_HandleHover(t *testing.T) {
+	var h Handler
+
+	loadFile("file:///api.yaml", `openapi: 3.0.0
+components:
+  schemas:
+    Pet:
+      description: A pet in the store
+      type: object
+      required: [id]
+      properties:
+        id:
+          type: integer
+    Owner:
+      properties:
+        pet:
+          $ref: "#/components/schemas/Pet"
+        toy:
+          $ref: "#/components/schemas/Toy"
+`)(t, &h)
+
+	tests := []struct {
+		name     string
+		position string
+		want     string
+	}{
+		{name: "ref", position: "13:10", want: "`object · 1 prop · required: id`\n\nA pet in the store"},
+		{name: "unresolved ref", position: "15:10", want: "Unresolved: /components/schemas/Toy not found in file:///api.yaml"},
+		{name: "not a ref", position: "3:4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hoverValue(t, &h, "file:///api.yaml", tt.position); got != tt.want {
+				t.Errorf("hover = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandler_HandleHover_Disabled(t *testing.T) {
+	h := Handler{Settings: Settings{Features: map[Feature]bool{FeatureHover: false}}}
+
+	loadFile("file:///api.yaml", `openapi: 3.0.0
+components:
+  schemas:
+    Pet:
+      type: object
+    Owner:
+      $ref: "#/components/schemas/Pet"
+`)(t, &h)
+
+	if got := hoverValue(t, &h, "file:///api.yaml", "6:8"); got != "" {
+		t.Errorf("hover = %q, want none", got)
+	}
+}
+
+func hoverValue(t *testing.T, h *Handler, uri, position string) string {
+	t.Helper()
+
+	hover, err := h.HandleHover(types.HoverParams{TextDocumentPositionParams: positionParams(uri, position)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hover == nil {
+		return ""
+	}
+
+	return hover.Contents.Value
+}
@@ -0,0 +1,248 @@
+package analysis
+
+import (
+	"errors"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/armsnyder/openapi-language-server/internal/analysis/yaml"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+// unresolvedHint is the label of a $ref that does not point to anything.
+const unresolvedHint = "⚠ unresolved"
+
+// HandleInlayHint shows a summary of the referenced object after each $ref in
+// the range, such as "object · 7 props · required: id, name".
+func (h *Handler) HandleInlayHint(params types.InlayHintParams) ([]types.InlayHint, error) {
+	if !h.Settings.Enabled(FeatureInlayHints) {
+		return nil, nil
+	}
+
+	uri := params.TextDocument.URI
+
+	document, err := h.getDocument(uri)
+	if err != nil {
+		h.logf("HandleInlayHint: Error getting document %q: %v", uri, err)
+		return nil, nil
+	}
+
+	if !h.related(uri) {
+		return nil, nil
+	}
+
+	file := &h.files[uri].file
+
+	var hints []types.InlayHint
+
+	for _, line := range document.Nodes() {
+		if line.Key != "$ref" || line.Value == "" {
+			continue
+		}
+
+		if line.Number < params.Range.Start.Line || line.Number > params.Range.End.Line {
+			continue
+		}
+
+		label := h.describeRef(uri, line.Value)
+		if label == "" {
+			continue
+		}
+
+		// Place the hint after the closing quote, if there is one.
+		position := line.ValueRange.End
+		if s := file.Line(line.Number); position.Character < len(s) && (s[position.Character] == '"' || s[position.Character] == '\'') {
+			position.Character++
+		}
+
+		hints = append(hints, types.InlayHint{
+			Position:    position,
+			Label:       label,
+			Kind:        types.InlayHintKindType,
+			PaddingLeft: true,
+		})
+	}
+
+	return hints, nil
+}
+
+// describeRef returns a short description of the object that a $ref value
+// resolves to, following chained references. There is no description while a
+// remote document is fetched.
+func (h *Handler) describeRef(baseURI, ref string) string {
+	target, err := h.resolve(baseURI, ref)
+	if err == nil {
+		target, err = h.followRefs(target)
+	}
+
+	switch {
+	case errors.Is(err, errRemotePending):
+		return ""
+	case err != nil:
+		return unresolvedHint
+	}
+
+	fields := target.fields()
+
+	switch {
+	case fields["in"] != nil:
+		return describeParameter(fields)
+	case fields["content"] != nil:
+		return describeContent(fields["content"])
+	default:
+		return describeSchema(fields)
+	}
+}
+
+// describeSchema describes a schema by its type and shape, for example
+// "object · 7 props · required: id, name" or "string enum(3)".
+func describeSchema(fields map[string]*yaml.Line) string {
+	if ref := fields["$ref"]; ref != nil {
+		return refName(ref.Value)
+	}
+
+	for _, keyword := range []string{"allOf", "oneOf", "anyOf"} {
+		if composition := fields[keyword]; composition != nil {
+			return keyword + "(" + strconv.Itoa(len(sequence(composition))) + ")"
+		}
+	}
+
+	typ := ""
+	if t := fields["type"]; t != nil {
+		typ = t.Value
+	} else if fields["properties"] != nil {
+		typ = "object"
+	} else if fields["items"] != nil {
+		typ = "array"
+	}
+
+	parts := []string{}
+
+	switch typ {
+	case "":
+	case "object":
+		parts = append(parts, "object")
+		if properties := fields["properties"]; properties != nil {
+			parts = append(parts, pluralize(len(properties.Children), "prop", "props"))
+		}
+		if required := fields["required"]; required != nil {
+			if names := sequence(required); len(names) > 0 {
+				parts = append(parts, "required: "+strings.Join(names, ", "))
+			}
+		}
+	case "array":
+		if items := fields["items"]; items != nil {
+			if item := describeSchema(items.Children); item != "" {
+				parts = append(parts, "array of "+item)
+				break
+			}
+		}
+		parts = append(parts, "array")
+	default:
+		if format := fields["format"]; format != nil {
+			typ += "(" + format.Value + ")"
+		}
+		parts = append(parts, typ)
+	}
+
+	if enum := fields["enum"]; enum != nil {
+		label := "enum(" + strconv.Itoa(len(sequence(enum))) + ")"
+		if len(parts) == 0 {
+			return label
+		}
+		parts[len(parts)-1] += " " + label
+	}
+
+	return strings.Join(parts, " · ")
+}
+
+// describeParameter describes a parameter by its location and schema, for
+// example "query · integer".
+func describeParameter(fields map[string]*yaml.Line) string {
+	parts := []string{fields["in"].Value}
+
+	if fields["required"] != nil && fields["required"].Value == "true" {
+		parts = append(parts, "required")
+	}
+
+	if schema := fields["schema"]; schema != nil {
+		if description := describeSchema(schema.Children); description != "" {
+			parts = append(parts, description)
+		}
+	}
+
+	return strings.Join(parts, " · ")
+}
+
+// describeContent describes a response or request body by its media types.
+func describeContent(content *yaml.Line) string {
+	mediaTypes := make([]string, 0, len(content.Children))
+	for mediaType := range content.Children {
+		mediaTypes = append(mediaTypes, mediaType)
+	}
+	slices.Sort(mediaTypes)
+
+	if len(mediaTypes) == 1 {
+		if schema := content.Children[mediaTypes[0]].Children["schema"]; schema != nil {
+			if description := describeSchema(schema.Children); description != "" {
+				return mediaTypes[0] + " · " + description
+			}
+		}
+	}
+
+	return strings.Join(mediaTypes, ", ")
+}
+
+// sequence returns the values of a block or flow sequence of scalars.
+func sequence(line *yaml.Line) []string {
+	if len(line.Items) > 0 {
+		values := make([]string, len(line.Items))
+		for i, item := range line.Items {
+			values[i] = item.Value
+			if values[i] == "" {
+				values[i] = item.Key
+			}
+		}
+		return values
+	}
+
+	value := strings.TrimSpace(line.Value)
+	if !strings.HasPrefix(value, "[") || !strings.HasSuffix(value, "]") {
+		return nil
+	}
+
+	value = strings.TrimSpace(value[1 : len(value)-1])
+	if value == "" {
+		return nil
+	}
+
+	values := strings.Split(value, ",")
+	for i := range values {
+		values[i] = unquoteScalar(strings.TrimSpace(values[i]))
+	}
+
+	return values
+}
+
+func unquoteScalar(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+
+	return s
+}
+
+// refName returns the name of the object that a $ref value points to, which is
+// the last segment of the fragment or else the file name.
+func refName(ref string) string {
+	location, fragment, _ := strings.Cut(ref, "#")
+
+	if fragment = strings.Trim(fragment, "/"); fragment != "" {
+		segments := strings.Split(fragment, "/")
+		return strings.NewReplacer("~1", "/", "~0", "~").Replace(segments[len(segments)-1])
+	}
+
+	return path.Base(location)
+}
@@ -0,0 +1,121 @@
+package analysis_test
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/armsnyder/openapi-language-server/internal/analysis"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+const inlayHintSpec = `openapi: 3.0.0
+paths:
+  /pets:
+    get:
+      parameters:
+        - $ref: "#/components/parameters/Limit"
+      responses:
+        "200":
+          $ref: "#/components/responses/PetList"
+        default:
+          $ref: "#/components/responses/Missing"
+components:
+  parameters:
+    Limit:
+      name: limit
+      in: query
+      schema:
+        type: integer
+        format: int32
+  responses:
+    PetList:
+      content:
+        application/json:
+          schema:
+            type: array
+            items:
+              $ref: '#/components/schemas/Pet'
+  schemas:
+    Pet:
+      type: object
+      required: [id, name]
+      properties:
+        id:
+          type: integer
+        name:
+          type: string
+        status:
+          $ref: "#/components/schemas/Status"
+        tags:
+          type: array
+          items:
+            type: string
+        owner:
+          $ref: "#/components/schemas/Alias"
+    Status:
+      type: string
+      enum:
+        - available
+        - pending
+        - sold
+    Alias:
+      $ref: "#/components/schemas/Owner"
+    Owner:
+      type: object
+      required:
+        - id
+      properties:
+        id:
+          type: integer
+`
+
+func TestHandler_HandleInlayHint(t *testing.T) {
+	var h Handler
+
+	loadFile("file:///api.yaml", inlayHintSpec)(t, &h)
+
+	hints, err := h.HandleInlayHint(types.InlayHintParams{
+		TextDocument: types.TextDocumentIdentifier{URI: "file:///api.yaml"},
+		Range:        newRange("0:0-100:0"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, hint := range hints {
+		got = append(got, hint.Position.String()+" "+hint.Label)
+	}
+
+	want := []string{
+		"5:47 query · integer(int32)",
+		"8:48 application/json · array of Pet",
+		"10:48 ⚠ unresolved",
+		"26:46 object · 5 props · required: id, name",
+		"37:45 string enum(3)",
+		"43:44 object · 1 prop · required: id",
+		"51:40 object · 1 prop · required: id",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("hints = %q, want %q", got, want)
+	}
+}
+
+func TestHandler_HandleInlayHint_Range(t *testing.T) {
+	var h Handler
+
+	loadFile("file:///api.yaml", inlayHintSpec)(t, &h)
+
+	hints, err := h.HandleInlayHint(types.InlayHintParams{
+		TextDocument: types.TextDocumentIdentifier{URI: "file:///api.yaml"},
+		Range:        newRange("30:0-40:0"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(hints) != 1 || hints[0].Label != "string enum(3)" {
+		t.Errorf("hints = %+v, want only the Status hint", hints)
+	}
+}
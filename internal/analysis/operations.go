@@ -0,0 +1,127 @@
+package analysis
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/armsnyder/openapi-language-server/internal/analysis/yaml"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+// maxRefDepth limits how many chained references are followed, to guard
+// against cycles.
+const maxRefDepth = 8
+
+// operation is an operation in a spec, which may be declared in a different
+// file than the spec's root document.
+type operation struct {
+	uri  string
+	path string
+	line *yaml.Line
+}
+
+func (o operation) String() string {
+	return strings.ToUpper(o.line.Key) + " " + o.path
+}
+
+// isRoot returns true if the document is the root document of a spec.
+func isRoot(document yaml.Document) bool {
+	return document.Root["openapi"] != nil || document.Root["swagger"] != nil
+}
+
+// specOperations returns all operations in the spec with the given root
+// document, following path item references into other files.
+func (h *Handler) specOperations(uri string, document yaml.Document) []operation {
+	paths := document.Root["paths"]
+	if paths == nil {
+		return nil
+	}
+
+	var result []operation
+
+	for _, pathItem := range document.Nodes() {
+		if pathItem.Parent != paths || pathItem.Item {
+			continue
+		}
+
+		// A path item that cannot be resolved is reported by checkRefs.
+		target, err := h.followRefs(reference{uri: uri, document: document, line: pathItem})
+		if err != nil {
+			continue
+		}
+
+		for _, op := range operations(target.fields()) {
+			result = append(result, operation{uri: target.uri, path: pathItem.Key, line: op})
+		}
+	}
+
+	return result
+}
+
+// checkDuplicateOperationIDs reports operationIds in the file that are also
+// used by another operation in the same spec. Every root document in the
+// workspace folder of the file is considered, so that fragments referenced by a
+// root are checked too.
+func checkDuplicateOperationIDs(h *Handler, f *annotatedFile) []problem {
+	var problems []problem
+	reported := map[*yaml.Line]bool{}
+
+	for _, rootURI := range h.rootURIs(h.folder(f.uri)) {
+		root, err := h.loadDocument(rootURI)
+		if err != nil || !isRoot(root) {
+			continue
+		}
+
+		byID := map[string][]operation{}
+		for _, op := range h.specOperations(rootURI, root) {
+			if id := op.line.Children["operationId"]; id != nil && id.Value != "" {
+				byID[id.Value] = append(byID[id.Value], op)
+			}
+		}
+
+		for id, ops := range byID {
+			if len(ops) < 2 {
+				continue
+			}
+
+			for _, op := range ops {
+				idLine := op.line.Children["operationId"]
+				if op.uri != f.uri || reported[idLine] {
+					continue
+				}
+				reported[idLine] = true
+
+				var related []types.DiagnosticRelatedInformation
+				for _, other := range ops {
+					if other == op {
+						continue
+					}
+					related = append(related, types.DiagnosticRelatedInformation{
+						Location: types.Location{
+							URI:   other.uri,
+							Range: other.line.Children["operationId"].ValueRange,
+						},
+						Message: fmt.Sprintf("Also used by %s", other),
+					})
+				}
+
+				problems = append(problems, problem{diagnostic: types.Diagnostic{
+					Range:              idLine.ValueRange,
+					Severity:           types.SeverityError,
+					Code:               "duplicate-operation-id",
+					Source:             diagnosticSource,
+					Message:            fmt.Sprintf("Duplicate operationId %q", id),
+					RelatedInformation: related,
+				}})
+			}
+		}
+	}
+
+	slices.SortFunc(problems, func(a, b problem) int {
+		return cmp.Compare(a.diagnostic.Range.Start.Line, b.diagnostic.Range.Start.Line)
+	})
+
+	return problems
+}
@@ -0,0 +1,129 @@
+package analysis_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	. "github.com/armsnyder/openapi-language-server/internal/analysis"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+func TestHandler_Diagnostics_DuplicateOperationIDs(t *testing.T) {
+	t.Run("same file", func(t *testing.T) {
+		var h Handler
+
+		loadFile("file:///api.yaml", `openapi: 3.0.0
+paths:
+  /pets:
+    get:
+      operationId: listPets
+    post:
+      operationId: listPets
+  /toys:
+    get:
+      operationId: listToys
+`)(t, &h)
+
+		params, err := h.Diagnostics()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []types.PublishDiagnosticsParams{{
+			URI:     "file:///api.yaml",
+			Version: toPtr(0),
+			Diagnostics: []types.Diagnostic{
+				{
+					Range:    newRange("4:19-4:27"),
+					Severity: types.SeverityError,
+					Code:     "duplicate-operation-id",
+					Source:   "openapi",
+					Message:  `Duplicate operationId "listPets"`,
+					RelatedInformation: []types.DiagnosticRelatedInformation{{
+						Location: types.Location{URI: "file:///api.yaml", Range: newRange("6:19-6:27")},
+						Message:  "Also used by POST /pets",
+					}},
+				},
+				{
+					Range:    newRange("6:19-6:27"),
+					Severity: types.SeverityError,
+					Code:     "duplicate-operation-id",
+					Source:   "openapi",
+					Message:  `Duplicate operationId "listPets"`,
+					RelatedInformation: []types.DiagnosticRelatedInformation{{
+						Location: types.Location{URI: "file:///api.yaml", Range: newRange("4:19-4:27")},
+						Message:  "Also used by GET /pets",
+					}},
+				},
+			},
+		}}
+
+		if !reflect.DeepEqual(params, want) {
+			t.Errorf("Diagnostics() = %+v, want %+v", params, want)
+		}
+	})
+
+	t.Run("split path files", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "paths", "pets.yaml"), `get:
+  operationId: listPets
+`)
+
+		rootURI := "file://" + filepath.ToSlash(filepath.Join(dir, "api.yaml"))
+		fragmentURI := "file://" + filepath.ToSlash(filepath.Join(dir, "paths", "pets.yaml"))
+
+		var h Handler
+
+		loadFile(rootURI, `openapi: 3.0.0
+paths:
+  /pets:
+    $ref: "./paths/pets.yaml"
+  /animals:
+    get:
+      operationId: listPets
+`)(t, &h)
+
+		params, err := h.Diagnostics()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(params) != 1 || len(params[0].Diagnostics) != 1 {
+			t.Fatalf("Diagnostics() = %+v, want 1 diagnostic", params)
+		}
+
+		related := params[0].Diagnostics[0].RelatedInformation
+		wantRelated := []types.DiagnosticRelatedInformation{{
+			Location: types.Location{URI: fragmentURI, Range: newRange("1:15-1:23")},
+			Message:  "Also used by GET /pets",
+		}}
+
+		if !reflect.DeepEqual(related, wantRelated) {
+			t.Errorf("got related information %+v, want %+v", related, wantRelated)
+		}
+
+		// Opening the fragment reports the duplicate there too.
+
+		loadFile(fragmentURI, `get:
+  operationId: listPets
+`)(t, &h)
+
+		if got := diagnosticStrings(t, &h, fragmentURI); !reflect.DeepEqual(got, []string{`1:15-1:23 duplicate-operation-id: Duplicate operationId "listPets"`}) {
+			t.Errorf("fragment diagnostics = %q", got)
+		}
+	})
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
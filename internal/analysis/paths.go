@@ -0,0 +1,305 @@
+package analysis
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/armsnyder/openapi-language-server/internal/analysis/yaml"
+	"github.com/armsnyder/openapi-language-server/internal/lsp"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+// httpMethods are the operation keys of a path item, in canonical order.
+var httpMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+var pathTemplateRegexp = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// parameter is a parameter declaration, which may have been pulled in by a
+// $ref.
+type parameter struct {
+	name     string
+	in       string
+	required bool
+
+	// rng is where problems with the parameter are reported: the $ref value if
+	// the parameter is a reference, otherwise the name value.
+	rng types.Range
+
+	// unresolved is true if the parameter is a reference that cannot be
+	// resolved. It may declare any path parameter.
+	unresolved bool
+}
+
+// operations returns the operation lines of a path item, given its mapping
+// entries, in canonical order.
+func operations(pathItem map[string]*yaml.Line) []*yaml.Line {
+	var result []*yaml.Line
+
+	for _, method := range httpMethods {
+		if op := pathItem[method]; op != nil {
+			result = append(result, op)
+		}
+	}
+
+	return result
+}
+
+// parameters resolves the parameters declared in the fields of a path item or
+// operation in the document with the given URI. Parameters are followed into
+// other files. References that cannot be resolved are not checked, since they
+// are reported by checkRefs.
+func (h *Handler) parameters(uri string, fields map[string]*yaml.Line) []parameter {
+	list := fields["parameters"]
+	if list == nil {
+		return nil
+	}
+
+	var result []parameter
+
+	for _, item := range list.Items {
+		declaration := reference{uri: uri, line: item}
+
+		ref := item.Field("$ref")
+		if ref != nil {
+			var err error
+			if declaration, err = h.followRefs(declaration); err != nil {
+				result = append(result, parameter{rng: ref.ValueRange, unresolved: true})
+				continue
+			}
+		}
+
+		name := declaration.field("name")
+		if name == nil {
+			continue
+		}
+
+		p := parameter{name: name.Value, rng: name.ValueRange}
+
+		if in := declaration.field("in"); in != nil {
+			p.in = in.Value
+		}
+
+		if required := declaration.field("required"); required != nil {
+			p.required = required.Value == "true"
+		}
+
+		if ref != nil {
+			p.rng = ref.ValueRange
+		}
+
+		result = append(result, p)
+	}
+
+	return result
+}
+
+func hasPathParameter(params []parameter, name string) bool {
+	return slices.ContainsFunc(params, func(p parameter) bool {
+		return p.unresolved || p.in == "path" && p.name == name
+	})
+}
+
+// checkPathParameters checks that the parameters of each path item and
+// operation are consistent with the path template.
+func checkPathParameters(h *Handler, f *annotatedFile) []problem {
+	paths := f.document.Root["paths"]
+	if paths == nil {
+		return nil
+	}
+
+	var problems []problem
+
+	for _, pathItem := range f.document.Nodes() {
+		if pathItem.Parent != paths || pathItem.Item {
+			continue
+		}
+
+		problems = append(problems, h.checkPathItemParameters(f, pathItem)...)
+	}
+
+	return problems
+}
+
+// checkPathItemParameters checks the parameters of a path item in the given
+// file. A path item that is a $ref is followed, and its problems are reported
+// on the $ref, without fixes.
+func (h *Handler) checkPathItemParameters(f *annotatedFile, pathItem *yaml.Line) []problem {
+	target, err := h.followRefs(reference{uri: f.uri, document: f.document, line: pathItem})
+	if err != nil {
+		return nil
+	}
+
+	// rng is where a problem is reported: the given range in the path item
+	// itself, or the $ref otherwise.
+	rng := func(r types.Range) types.Range { return r }
+	if ref := pathItem.Children["$ref"]; ref != nil {
+		rng = func(types.Range) types.Range { return ref.ValueRange }
+	}
+
+	var problems []problem
+
+	var templateNames []string
+	for _, match := range pathTemplateRegexp.FindAllStringSubmatch(pathItem.Key, -1) {
+		if !slices.Contains(templateNames, match[1]) {
+			templateNames = append(templateNames, match[1])
+		}
+	}
+
+	pathParams := h.parameters(target.uri, target.fields())
+	ops := operations(target.fields())
+
+	opParams := make([][]parameter, len(ops))
+	for i, op := range ops {
+		opParams[i] = h.parameters(target.uri, op.Children)
+	}
+
+	// Check that each name in the template is declared.
+
+	for _, name := range templateNames {
+		if hasPathParameter(pathParams, name) {
+			continue
+		}
+
+		var missing []*yaml.Line
+		for i, op := range ops {
+			if !hasPathParameter(opParams[i], name) {
+				missing = append(missing, op)
+			}
+		}
+
+		// If no operation declares the parameter, report it once on the path
+		// item so that a single fix resolves it.
+		if len(missing) == len(ops) {
+			missing = []*yaml.Line{pathItem}
+		}
+
+		for _, op := range missing {
+			message := fmt.Sprintf("Path parameter %q is not defined", name)
+			if op != pathItem {
+				message = fmt.Sprintf("Path parameter %q is not defined for operation %s", name, strings.ToUpper(op.Key))
+			}
+
+			p := problem{
+				diagnostic: types.Diagnostic{
+					Range:    rng(op.KeyRange),
+					Severity: types.SeverityError,
+					Code:     "missing-path-parameter",
+					Source:   diagnosticSource,
+					Message:  message,
+				},
+			}
+
+			if target.line != pathItem {
+				problems = append(problems, p)
+				continue
+			}
+
+			if edit, ok := insertPathParameter(&f.file, f.document, op, name); ok {
+				p.fixes = []fix{{
+					title: fmt.Sprintf("Add path parameter %q", name),
+					edits: []types.TextEdit{edit},
+				}}
+			}
+
+			problems = append(problems, p)
+		}
+	}
+
+	// Check each declared path parameter.
+
+	for _, params := range append([][]parameter{pathParams}, opParams...) {
+		for _, param := range params {
+			if param.in != "path" {
+				continue
+			}
+
+			if !slices.Contains(templateNames, param.name) {
+				problems = append(problems, problem{diagnostic: types.Diagnostic{
+					Range:    rng(param.rng),
+					Severity: types.SeverityError,
+					Code:     "unused-path-parameter",
+					Source:   diagnosticSource,
+					Message:  fmt.Sprintf("Path parameter %q does not appear in path %q", param.name, pathItem.Key),
+				}})
+			}
+
+			if !param.required {
+				problems = append(problems, problem{diagnostic: types.Diagnostic{
+					Range:    rng(param.rng),
+					Severity: types.SeverityError,
+					Code:     "path-parameter-not-required",
+					Source:   diagnosticSource,
+					Message:  fmt.Sprintf("Path parameter %q must be required", param.name),
+				}})
+			}
+		}
+	}
+
+	return problems
+}
+
+// insertPathParameter returns an edit that inserts a skeleton path parameter
+// into the parameters of the given path item or operation, matching the
+// indentation of the surrounding lines.
+func insertPathParameter(file *lsp.File, document yaml.Document, target *yaml.Line, name string) (types.TextEdit, bool) {
+	step := indentStep(target)
+	list := target.Children["parameters"]
+
+	switch {
+	case list == nil:
+		text := strings.Repeat(" ", target.Indent+step) + "parameters:\n" + pathParameterSkeleton(target.Indent+2*step, 2, step, name)
+		return insertLines(file, target.Number+1, text), true
+
+	case len(list.Items) > 0:
+		first := list.Items[0]
+		keyOffset := 2
+		if first.Key != "" {
+			keyOffset = first.KeyRange.Start.Character - first.Indent
+		}
+		text := pathParameterSkeleton(first.Indent, keyOffset, step, name)
+		return insertLines(file, document.End(list)+1, text), true
+
+	case list.Value == "":
+		text := pathParameterSkeleton(list.Indent+step, 2, step, name)
+		return insertLines(file, document.End(list)+1, text), true
+
+	default:
+		// Flow style sequences are not supported.
+		return types.TextEdit{}, false
+	}
+}
+
+// pathParameterSkeleton returns a sequence item declaring a path parameter.
+// The dash is placed at the given indent, the item's keys at keyOffset past
+// the dash, and nested keys a further step in.
+func pathParameterSkeleton(indent, keyOffset, step int, name string) string {
+	dash := strings.Repeat(" ", indent)
+	key := dash + strings.Repeat(" ", keyOffset)
+	nested := key + strings.Repeat(" ", step)
+
+	return dash + "-" + strings.Repeat(" ", keyOffset-1) + "name: " + name + "\n" +
+		key + "in: path\n" +
+		key + "required: true\n" +
+		key + "schema:\n" +
+		nested + "type: string\n"
+}
+
+// indentStep returns the number of spaces by which the children of the given
+// line are indented, defaulting to 2.
+func indentStep(line *yaml.Line) int {
+	step := 0
+
+	for _, child := range line.Children {
+		if diff := child.Indent - line.Indent; diff > 0 && (step == 0 || diff < step) {
+			step = diff
+		}
+	}
+
+	if step == 0 {
+		return 2
+	}
+
+	return step
+}
@@ -0,0 +1,320 @@
+package analysis_test
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	. "github.com/armsnyder/openapi-language-server/internal/analysis"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+func TestHandler_Diagnostics_PathParameters(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "valid",
+			text: `openapi: 3.0.0
+paths:
+  /pets/{petId}:
+    get:
+      parameters:
+        - name: petId
+          in: path
+          required: true
+`,
+		},
+		{
+			name: "declared at path item level",
+			text: `openapi: 3.0.0
+paths:
+  /pets/{petId}:
+    parameters:
+      - name: petId
+        in: path
+        required: true
+    get: {}
+    put: {}
+`,
+		},
+		{
+			name: "declared by ref",
+			text: `openapi: 3.0.0
+paths:
+  /pets/{petId}:
+    get:
+      parameters:
+        - $ref: "#/components/parameters/PetId"
+components:
+  parameters:
+    PetId:
+      name: petId
+      in: path
+      required: true
+`,
+		},
+		{
+			name: "missing from all operations",
+			text: `openapi: 3.0.0
+paths:
+  /pets/{petId}:
+    get: {}
+    put: {}
+`,
+			want: []string{`2:2-2:15 missing-path-parameter: Path parameter "petId" is not defined`},
+		},
+		{
+			name: "missing from one operation",
+			text: `openapi: 3.0.0
+paths:
+  /pets/{petId}:
+    get:
+      parameters:
+        - name: petId
+          in: path
+          required: true
+    put: {}
+`,
+			want: []string{`8:4-8:7 missing-path-parameter: Path parameter "petId" is not defined for operation PUT`},
+		},
+		{
+			name: "not required",
+			text: `openapi: 3.0.0
+paths:
+  /pets/{petId}:
+    get:
+      parameters:
+        - name: petId
+          in: path
+`,
+			want: []string{`5:16-5:21 path-parameter-not-required: Path parameter "petId" must be required`},
+		},
+		{
+			name: "not in template",
+			text: `openapi: 3.0.0
+paths:
+  /pets/{petId}:
+    get:
+      parameters:
+        - $ref: "#/components/parameters/PetId"
+        - name: id
+          in: path
+          required: true
+components:
+  parameters:
+    PetId:
+      name: petId
+      in: path
+      required: true
+`,
+			want: []string{`6:16-6:18 unused-path-parameter: Path parameter "id" does not appear in path "/pets/{petId}"`},
+		},
+		{
+			name: "query parameter does not count",
+			text: `openapi: 3.0.0
+paths:
+  /pets/{petId}:
+    parameters:
+      - name: petId
+        in: query
+`,
+			want: []string{`2:2-2:15 missing-path-parameter: Path parameter "petId" is not defined`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var h Handler
+
+			loadFile("file:///foo.yaml", tt.text)(t, &h)
+
+			got := diagnosticStrings(t, &h, "file:///foo.yaml")
+
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diagnostics() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandler_Diagnostics_PathParameters_SplitFiles(t *testing.T) {
+	dir := t.TempDir()
+	base := "file://" + filepath.ToSlash(dir)
+
+	writeFile(t, filepath.Join(dir, "params.yaml"), `PetId:
+  name: petId
+  in: path
+  required: true
+`)
+	writeFile(t, filepath.Join(dir, "toys.yaml"), `get:
+  parameters:
+    - $ref: ./params.yaml#/PetId
+`)
+	writeFile(t, filepath.Join(dir, "owners.yaml"), `get: {}
+`)
+
+	var h Handler
+
+	loadFile(base+"/api.yaml", `openapi: 3.0.0
+paths:
+  /pets/{petId}:
+    get:
+      parameters:
+        - $ref: ./params.yaml#/PetId
+  /pets/{petId}/toys:
+    $ref: ./toys.yaml
+  /owners/{ownerId}:
+    $ref: ./owners.yaml
+  /stores/{storeId}:
+    get:
+      parameters:
+        - $ref: ./params.yaml#/StoreId
+`)(t, &h)
+
+	// Unresolved references are reported by checkRefs alone, and problems in
+	// path items of other files are reported on the $ref.
+
+	want := []string{
+		`9:10-9:23 missing-path-parameter: Path parameter "ownerId" is not defined`,
+		`13:16-13:38 unresolved-ref: Unresolved $ref: /StoreId not found in ` + base + `/params.yaml`,
+	}
+
+	if got := diagnosticStrings(t, &h, base+"/api.yaml"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diagnostics() = %q, want %q", got, want)
+	}
+
+	// The parameters of a path item in another file cannot be fixed here.
+
+	actions, err := h.HandleCodeAction(types.CodeActionParams{
+		TextDocument: types.TextDocumentIdentifier{URI: base + "/api.yaml"},
+		Range:        newRange("9:12-9:12"),
+		Context:      types.CodeActionContext{Only: []types.CodeActionKind{types.CodeActionQuickFix}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(actions) != 0 {
+		t.Errorf("actions = %+v, want none", actions)
+	}
+}
+
+func TestHandler_HandleCodeAction_AddPathParameter(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		rng    string
+		want   string
+		wantAt string
+	}{
+		{
+			name: "path item without parameters",
+			text: `openapi: 3.0.0
+paths:
+  /pets/{petId}:
+    get: {}
+`,
+			rng:    "2:5-2:5",
+			wantAt: "3:0-3:0",
+			want: `    parameters:
+      - name: petId
+        in: path
+        required: true
+        schema:
+          type: string
+`,
+		},
+		{
+			name: "operation with existing parameters",
+			text: `openapi: 3.0.0
+paths:
+  /pets/{petId}/toys/{toyId}:
+    parameters:
+    - name: petId
+      in: path
+      required: true
+    get:
+      parameters:
+      - name: limit
+        in: query
+
+    put:
+      parameters:
+      - name: toyId
+        in: path
+        required: true
+`,
+			rng:    "7:4-7:4",
+			wantAt: "11:0-11:0",
+			want: `      - name: toyId
+        in: path
+        required: true
+        schema:
+          type: string
+`,
+		},
+		{
+			name:   "end of file without newline",
+			text:   "openapi: 3.0.0\npaths:\n    /pets/{petId}:\n        get: {}",
+			rng:    "2:4-2:4",
+			wantAt: "3:15-3:15",
+			want:   "\n        parameters:\n            - name: petId\n              in: path\n              required: true\n              schema:\n                  type: string",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var h Handler
+
+			loadFile("file:///foo.yaml", tt.text)(t, &h)
+
+			got, err := h.HandleCodeAction(types.CodeActionParams{
+				TextDocument: types.TextDocumentIdentifier{URI: "file:///foo.yaml"},
+				Range:        newRange(tt.rng),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got) != 1 {
+				t.Fatalf("got %d code actions, want 1", len(got))
+			}
+
+			want := []types.TextEdit{{Range: newRange(tt.wantAt), NewText: tt.want}}
+
+			if edits := got[0].Edit.Changes["file:///foo.yaml"]; !reflect.DeepEqual(edits, want) {
+				t.Errorf("got edits %q, want %q", edits, want)
+			}
+		})
+	}
+}
+
+func diagnosticStrings(t *testing.T, h *Handler, uri string) []string {
+	t.Helper()
+
+	params, err := h.Diagnostics()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result []string
+
+	for _, p := range params {
+		if p.URI != uri {
+			continue
+		}
+
+		for _, d := range p.Diagnostics {
+			result = append(result, d.Range.String()+" "+d.Code+": "+d.Message)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,271 @@
+package analysis
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/armsnyder/openapi-language-server/internal/analysis/yaml"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+// diskDocument is a parsed file that is not open in the editor.
+type diskDocument struct {
+	modTime  time.Time
+	document yaml.Document
+}
+
+// reference is the resolved target of a $ref.
+type reference struct {
+	uri      string
+	document yaml.Document
+
+	// line is the referenced line, or nil if the reference targets the whole
+	// document.
+	line *yaml.Line
+}
+
+// fields returns the mapping entries of the referenced line or document.
+func (r reference) fields() map[string]*yaml.Line {
+	if r.line == nil {
+		return r.document.Root
+	}
+
+	return r.line.Children
+}
+
+// field returns the mapping entry of the referenced line or document with the
+// given key.
+func (r reference) field(key string) *yaml.Line {
+	if r.line == nil {
+		return r.document.Root[key]
+	}
+
+	return r.line.Field(key)
+}
+
+// followRefs follows the chain of $refs that starts at the given line, and
+// returns the line that is not a reference.
+func (h *Handler) followRefs(target reference) (reference, error) {
+	for depth := 0; depth < maxRefDepth; depth++ {
+		ref := target.field("$ref")
+		if ref == nil {
+			return target, nil
+		}
+
+		next, err := h.resolve(target.uri, ref.Value)
+		if err != nil {
+			return reference{}, err
+		}
+		target = next
+	}
+
+	return reference{}, fmt.Errorf("more than %d chained references", maxRefDepth)
+}
+
+// loadDocument returns the document with the given URI. Open files take
+// precedence over the contents on disk, and HTTP(S) URLs are fetched.
+func (h *Handler) loadDocument(uri string) (yaml.Document, error) {
+	if _, ok := h.files[uri]; ok {
+		return h.getDocument(uri)
+	}
+
+	if isRemote(uri) {
+		return h.loadRemote(uri)
+	}
+
+	path, err := uriToPath(uri)
+	if err != nil {
+		return yaml.Document{}, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return yaml.Document{}, err
+	}
+
+	if cached, ok := h.disk[uri]; ok && cached.modTime.Equal(info.ModTime()) {
+		return cached.document, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return yaml.Document{}, err
+	}
+
+	document, err := detectLanguage("", uri).parse(content)
+	if err != nil {
+		return yaml.Document{}, err
+	}
+
+	if h.disk == nil {
+		h.disk = make(map[string]diskDocument)
+	}
+	h.disk[uri] = diskDocument{modTime: info.ModTime(), document: document}
+
+	return document, nil
+}
+
+// resolve resolves a $ref value relative to the URI of the document that
+// contains it.
+func (h *Handler) resolve(baseURI, ref string) (reference, error) {
+	uri, fragment, err := h.resolveRef(baseURI, ref)
+	if err != nil {
+		return reference{}, err
+	}
+
+	document, err := h.loadDocument(uri)
+	if err != nil {
+		return reference{}, err
+	}
+
+	result := reference{uri: uri, document: document}
+
+	if strings.Trim(fragment, "/") != "" {
+		result.line = document.Locate(fragment)
+		if result.line == nil {
+			return reference{}, fmt.Errorf("%s not found in %s", fragment, uri)
+		}
+	}
+
+	return result, nil
+}
+
+// resolveRef splits a $ref value into the absolute URI of the target document
+// and the JSON pointer fragment. Relative references to documents that do not
+// exist are looked up in the search paths from the settings.
+func (h *Handler) resolveRef(baseURI, ref string) (uri, fragment string, err error) {
+	uri, fragment, err = resolveURI(baseURI, ref)
+	if err != nil || len(h.Settings.SearchPaths) == 0 || h.exists(uri) {
+		return uri, fragment, err
+	}
+
+	location, _, _ := strings.Cut(ref, "#")
+	if location == "" || strings.HasPrefix(location, "/") || strings.Contains(location, ":") {
+		return uri, fragment, nil
+	}
+
+	for _, dir := range h.Settings.SearchPaths {
+		dirURI := h.settingsURI(h.folder(baseURI), dir)
+		if dirURI == "" {
+			continue
+		}
+
+		candidate, _, err := resolveURI(strings.TrimSuffix(dirURI, "/")+"/", location)
+		if err == nil && h.exists(candidate) {
+			return candidate, fragment, nil
+		}
+	}
+
+	return uri, fragment, nil
+}
+
+// exists returns true if the document with the given URI is open or exists on
+// disk.
+func (h *Handler) exists(uri string) bool {
+	if _, ok := h.files[uri]; ok {
+		return true
+	}
+
+	path, err := uriToPath(uri)
+	if err != nil {
+		return false
+	}
+
+	_, err = os.Stat(path)
+
+	return err == nil
+}
+
+// resolveURI splits a $ref value into the absolute URI of the target document
+// and the JSON pointer fragment.
+func resolveURI(baseURI, ref string) (uri, fragment string, err error) {
+	location, fragment, _ := strings.Cut(ref, "#")
+
+	if location == "" {
+		return baseURI, fragment, nil
+	}
+
+	base, err := url.Parse(baseURI)
+	if err != nil {
+		return "", "", err
+	}
+
+	rel, err := url.Parse(location)
+	if err != nil {
+		return "", "", err
+	}
+
+	return base.ResolveReference(rel).String(), fragment, nil
+}
+
+// findReferences returns the locations of all values in the workspace folder
+// of the given document that refer to the given JSON reference URI within it.
+func (h *Handler) findReferences(uri, ref string) []types.Location {
+	var locations []types.Location
+
+	for _, d := range h.workspaceDocuments(h.folder(uri)) {
+		for _, line := range d.document.Nodes() {
+			if line.Value == "" || (line.Key != "$ref" && !strings.Contains(line.Value, "#/")) {
+				continue
+			}
+
+			target, fragment, err := h.resolveRef(d.uri, line.Value)
+			if err != nil || target != uri || "#"+fragment != ref {
+				continue
+			}
+
+			locations = append(locations, types.Location{
+				URI:   d.uri,
+				Range: line.ValueRange,
+			})
+		}
+	}
+
+	return locations
+}
+
+// checkRefs reports $refs whose targets cannot be found. Remote documents
+// that are still being fetched, or that are on hosts that are not allowed, are
+// not reported.
+func checkRefs(h *Handler, f *annotatedFile) []problem {
+	var problems []problem
+
+	for _, line := range f.document.Nodes() {
+		if line.Key != "$ref" || line.Value == "" {
+			continue
+		}
+
+		_, err := h.resolve(f.uri, line.Value)
+		if err == nil || errors.Is(err, errRemotePending) || errors.Is(err, errRemoteNotAllowed) {
+			continue
+		}
+
+		problems = append(problems, problem{diagnostic: types.Diagnostic{
+			Range:    line.ValueRange,
+			Severity: types.SeverityError,
+			Code:     "unresolved-ref",
+			Source:   diagnosticSource,
+			Message:  fmt.Sprintf("Unresolved $ref: %v", err),
+		}})
+	}
+
+	return problems
+}
+
+func uriToPath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("unsupported URI scheme: %s", uri)
+	}
+
+	return filepath.FromSlash(u.Path), nil
+}
@@ -0,0 +1,341 @@
+package analysis
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/armsnyder/openapi-language-server/internal/analysis/yaml"
+)
+
+// defaultRemoteTimeout limits how long a fetch may take, unless the settings
+// say otherwise.
+const defaultRemoteTimeout = 10 * time.Second
+
+// maxRemoteSize is the size of the largest remote document that is fetched.
+const maxRemoteSize = 10 << 20
+
+var (
+	// errRemoteNotAllowed is returned for remote documents on hosts that are
+	// not in the allowlist of the settings.
+	errRemoteNotAllowed = errors.New("the host is not in remote.allow")
+
+	// errRemotePending is returned for remote documents that are being
+	// fetched for the first time.
+	errRemotePending = errors.New("the document is being fetched")
+)
+
+// remoteDocument is a document that is fetched over HTTP(S).
+type remoteDocument struct {
+	// path is where the document is cached on disk.
+	path string
+
+	document yaml.Document
+	err      error
+
+	// fetching is true while the document is fetched in the background.
+	fetching bool
+}
+
+// isRemote returns true if the URI is an HTTP(S) URL.
+func isRemote(uri string) bool {
+	return strings.HasPrefix(uri, "http://") || strings.HasPrefix(uri, "https://")
+}
+
+// hostAllowed returns true if the host of the URL matches one of the patterns.
+func hostAllowed(patterns []string, u *url.URL) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, u.Hostname()); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// remoteCachePath returns where the document with the given URL is cached,
+// under the cache directory of the user. Files are named by a hash of the URL,
+// and keep its extension so that their language can be detected.
+func remoteCachePath(uri string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(uri))
+
+	return filepath.Join(dir, "openapi-language-server", "remote", hex.EncodeToString(sum[:])+path.Ext(u.Path)), nil
+}
+
+// loadRemote returns the document with the given HTTP(S) URL. A cached copy is
+// used right away, and is refreshed in the background once per session.
+// Documents that are not cached are fetched in the background, and
+// errRemotePending is returned until they arrive. In offline mode, only cached
+// copies are used.
+func (h *Handler) loadRemote(uri string) (yaml.Document, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return yaml.Document{}, err
+	}
+
+	if !hostAllowed(h.Settings.Remote.Allow, u) {
+		return yaml.Document{}, fmt.Errorf("%s: %w", uri, errRemoteNotAllowed)
+	}
+
+	r, ok := h.remote[uri]
+	if !ok {
+		r, err = h.openRemote(uri)
+		if err != nil {
+			return yaml.Document{}, err
+		}
+	}
+
+	if r.fetching && r.document.Lines == nil && r.err == nil {
+		return yaml.Document{}, fmt.Errorf("%s: %w", uri, errRemotePending)
+	}
+
+	return r.document, r.err
+}
+
+// openRemote reads a remote document from the cache, and starts to fetch it
+// unless the handler is offline.
+func (h *Handler) openRemote(uri string) (*remoteDocument, error) {
+	cachePath, err := remoteCachePath(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &remoteDocument{path: cachePath}
+
+	content, err := os.ReadFile(cachePath)
+	switch {
+	case err == nil:
+		r.document, r.err = detectLanguage("", uri).parse(content)
+	case h.Settings.Remote.Offline:
+		r.err = fmt.Errorf("%s is not cached, and remote documents are not fetched in offline mode", uri)
+	}
+
+	if !h.Settings.Remote.Offline {
+		r.fetching = true
+		h.fetch(uri, r)
+	}
+
+	if h.remote == nil {
+		h.remote = make(map[string]*remoteDocument)
+	}
+	h.remote[uri] = r
+
+	return r, nil
+}
+
+// fetch fetches a remote document in the background and writes it to the
+// cache. The result is applied by a task, on the goroutine of the server.
+func (h *Handler) fetch(uri string, r *remoteDocument) {
+	timeout := defaultRemoteTimeout
+	if d, err := time.ParseDuration(h.Settings.Remote.Timeout); err == nil {
+		timeout = d
+	}
+
+	allow := h.Settings.Remote.Allow
+	tasks, done := h.taskQueue(), h.doneChannel()
+
+	go func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go func() {
+			select {
+			case <-done:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		content, err := fetchRemote(ctx, uri, allow, timeout)
+
+		var cacheErr error
+		if err == nil {
+			cacheErr = writeCache(r.path, content)
+		}
+
+		// Nothing receives tasks once the handler is closed, so results that
+		// arrive later are dropped.
+		select {
+		case <-done:
+			return
+		default:
+		}
+
+		select {
+		case tasks <- func() { h.fetched(uri, r, content, err, cacheErr) }:
+		case <-done:
+		}
+	}()
+}
+
+// fetched applies the result of a fetch.
+func (h *Handler) fetched(uri string, r *remoteDocument, content []byte, err, cacheErr error) {
+	// The settings may have changed while the document was fetched.
+	if h.remote[uri] != r {
+		return
+	}
+
+	r.fetching = false
+
+	if cacheErr != nil {
+		h.logf("Error caching %s: %v", uri, cacheErr)
+	}
+
+	switch {
+	case err != nil && r.document.Lines != nil:
+		h.logf("Error fetching %s, using the cached copy: %v", uri, err)
+		return
+	case err != nil:
+		h.logf("Error fetching %s: %v", uri, err)
+		r.err = fmt.Errorf("error fetching %s: %w", uri, err)
+	default:
+		r.document, r.err = detectLanguage("", uri).parse(content)
+	}
+
+	h.invalidateProblems()
+	h.invalidateSaveProblems()
+}
+
+// fetchRemote downloads a remote document. Redirects are only followed to
+// allowed hosts.
+func fetchRemote(ctx context.Context, uri string, allow []string, timeout time.Duration) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client := http.Client{
+		CheckRedirect: func(request *http.Request, _ []*http.Request) error {
+			if !hostAllowed(allow, request.URL) {
+				return fmt.Errorf("redirect to %s: %w", request.URL.Host, errRemoteNotAllowed)
+			}
+			return nil
+		},
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", response.Status)
+	}
+
+	content, err := io.ReadAll(io.LimitReader(response.Body, maxRemoteSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(content) > maxRemoteSize {
+		return nil, fmt.Errorf("the document is larger than %d bytes", maxRemoteSize)
+	}
+
+	return content, nil
+}
+
+// writeCache writes a fetched document to the cache. The file is replaced
+// atomically, so that other servers never read a partial document.
+func writeCache(name string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0o700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".fetch-*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), name)
+}
+
+// locationURI returns the URI that the editor can open for a document. Remote
+// documents are opened from the cache.
+func (h *Handler) locationURI(uri string) string {
+	if r := h.remote[uri]; r != nil && isRemote(uri) {
+		return (&url.URL{Scheme: "file", Path: filepath.ToSlash(r.path)}).String()
+	}
+
+	return uri
+}
+
+// taskQueue returns the channel of tasks that the server runs for the handler.
+func (h *Handler) taskQueue() chan func() {
+	if h.tasks == nil {
+		h.tasks = make(chan func())
+	}
+
+	return h.tasks
+}
+
+func (h *Handler) Tasks() <-chan func() {
+	return h.taskQueue()
+}
+
+// Fetching returns true while remote documents are fetched in the background.
+// Each fetch ends with a task on the channel of Tasks.
+func (h *Handler) Fetching() bool {
+	for _, r := range h.remote {
+		if r.fetching {
+			return true
+		}
+	}
+
+	return false
+}
+
+// doneChannel returns the channel that is closed when the handler is closed.
+func (h *Handler) doneChannel() chan struct{} {
+	if h.done == nil {
+		h.done = make(chan struct{})
+	}
+
+	return h.done
+}
+
+// Close stops the work that the handler does in the background, such as
+// fetching remote documents. It is called when the server stops.
+func (h *Handler) Close() {
+	done := h.doneChannel()
+
+	select {
+	case <-done:
+	default:
+		close(done)
+	}
+}
@@ -0,0 +1,246 @@
+package analysis_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/armsnyder/openapi-language-server/internal/analysis"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+// remoteServer serves shared schemas, and counts the requests for them.
+func remoteServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	// Remote documents are cached under the cache directory of the user.
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	var requests atomic.Int32
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/common.yaml", func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		_, _ = w.Write([]byte(`Error:
+  description: A problem with the request
+  type: object
+  properties:
+    code:
+      type: integer
+`))
+	})
+	mux.HandleFunc("/slow.yaml", func(_ http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server, &requests
+}
+
+// runTask waits for a fetch to finish and applies its result, as the server
+// would.
+func runTask(t *testing.T, h *Handler) {
+	t.Helper()
+
+	task := <-h.Tasks()
+	task()
+}
+
+// inlayHintLabels returns the labels of the inlay hints of a document.
+func inlayHintLabels(t *testing.T, h *Handler, uri string) []string {
+	t.Helper()
+
+	hints, err := h.HandleInlayHint(types.InlayHintParams{
+		TextDocument: types.TextDocumentIdentifier{URI: uri},
+		Range:        newRange("0:0-100:0"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var labels []string
+	for _, hint := range hints {
+		labels = append(labels, hint.Label)
+	}
+
+	return labels
+}
+
+func TestHandler_RemoteRefs(t *testing.T) {
+	server, requests := remoteServer(t)
+
+	h := Handler{Settings: Settings{Remote: RemoteSettings{Allow: []string{"127.0.0.1"}}}}
+
+	loadFile("file:///api.yaml", `openapi: 3.0.0
+components:
+  responses:
+    BadRequest:
+      $ref: `+server.URL+`/common.yaml#/Error
+    NotFound:
+      $ref: `+server.URL+`/common.yaml#/Missing
+`)(t, &h)
+
+	// Nothing is reported while the document is fetched.
+
+	if got := diagnosticStrings(t, &h, "file:///api.yaml"); len(got) != 0 {
+		t.Errorf("diagnostics = %q, want none while fetching", got)
+	}
+
+	if got, want := hoverValue(t, &h, "file:///api.yaml", "4:6"), "Fetching "+server.URL+"/common.yaml#/Error…"; got != want {
+		t.Errorf("hover = %q, want %q", got, want)
+	}
+
+	if got := inlayHintLabels(t, &h, "file:///api.yaml"); len(got) != 0 {
+		t.Errorf("inlay hints = %q, want none while fetching", got)
+	}
+
+	runTask(t, &h)
+
+	if got, want := inlayHintLabels(t, &h, "file:///api.yaml"), []string{"object · 1 prop", "⚠ unresolved"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("inlay hints = %q, want %q", got, want)
+	}
+
+	want := []string{`6:12-6:` + strconv.Itoa(12+len(server.URL+"/common.yaml#/Missing")) + ` unresolved-ref: Unresolved $ref: /Missing not found in ` + server.URL + `/common.yaml`}
+	if got := diagnosticStrings(t, &h, "file:///api.yaml"); !reflect.DeepEqual(got, want) {
+		t.Errorf("diagnostics = %q, want %q", got, want)
+	}
+
+	wantHover := "`object · 1 prop`\n\nA problem with the request\n\nFrom " + server.URL + "/common.yaml"
+	if got := hoverValue(t, &h, "file:///api.yaml", "4:6"); got != wantHover {
+		t.Errorf("hover = %q, want %q", got, wantHover)
+	}
+
+	// Definitions open the cached copy.
+
+	locations, err := h.HandleDefinition(definitionParams("file:///api.yaml", "4:6"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(locations) != 1 || !strings.HasPrefix(locations[0].URI, "file://") || locations[0].Range != newRange("0:0-0:5") {
+		t.Fatalf("HandleDefinition() = %+v, want the Error schema in the cache", locations)
+	}
+
+	// In offline mode, the cached copy is used without fetching, and other
+	// documents are reported as missing.
+
+	offline := Handler{Settings: Settings{Remote: RemoteSettings{Allow: []string{"127.0.0.1"}, Offline: true}}}
+
+	loadFile("file:///api.yaml", `openapi: 3.0.0
+components:
+  responses:
+    BadRequest:
+      $ref: `+server.URL+`/common.yaml#/Error
+    NotFound:
+      $ref: `+server.URL+`/other.yaml#/Error
+`)(t, &offline)
+
+	want = []string{`6:12-6:` + strconv.Itoa(12+len(server.URL+"/other.yaml#/Error")) + ` unresolved-ref: Unresolved $ref: ` + server.URL + `/other.yaml is not cached, and remote documents are not fetched in offline mode`}
+	if got := diagnosticStrings(t, &offline, "file:///api.yaml"); !reflect.DeepEqual(got, want) {
+		t.Errorf("offline diagnostics = %q, want %q", got, want)
+	}
+
+	if got := requests.Load(); got != 1 {
+		t.Errorf("got %d requests, want 1", got)
+	}
+}
+
+func TestHandler_RemoteRefs_NotAllowed(t *testing.T) {
+	server, requests := remoteServer(t)
+
+	var h Handler
+
+	loadFile("file:///api.yaml", `openapi: 3.0.0
+components:
+  responses:
+    BadRequest:
+      $ref: `+server.URL+`/common.yaml#/Error
+`)(t, &h)
+
+	// Hosts that are not allowed are neither fetched nor reported.
+
+	if got := diagnosticStrings(t, &h, "file:///api.yaml"); len(got) != 0 {
+		t.Errorf("diagnostics = %q, want none", got)
+	}
+
+	if got := hoverValue(t, &h, "file:///api.yaml", "4:6"); !strings.Contains(got, "the host is not in remote.allow") {
+		t.Errorf("hover = %q, want an explanation", got)
+	}
+
+	if got := requests.Load(); got != 0 {
+		t.Errorf("got %d requests, want 0", got)
+	}
+}
+
+func TestHandler_RemoteRefs_Timeout(t *testing.T) {
+	server, _ := remoteServer(t)
+
+	h := Handler{Settings: Settings{Remote: RemoteSettings{Allow: []string{"127.0.0.1"}, Timeout: "50ms"}}}
+
+	loadFile("file:///api.yaml", `openapi: 3.0.0
+components:
+  responses:
+    BadRequest:
+      $ref: `+server.URL+`/slow.yaml#/Error
+`)(t, &h)
+
+	_ = diagnosticStrings(t, &h, "file:///api.yaml")
+
+	runTask(t, &h)
+
+	got := diagnosticStrings(t, &h, "file:///api.yaml")
+	if len(got) != 1 || !strings.Contains(got[0], "unresolved-ref: Unresolved $ref: error fetching "+server.URL+"/slow.yaml") {
+		t.Errorf("diagnostics = %q, want a fetch error", got)
+	}
+}
+
+func TestHandler_RemoteRefs_Close(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	started, canceled := make(chan struct{}), make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+		close(canceled)
+	}))
+	t.Cleanup(server.Close)
+
+	h := Handler{Settings: Settings{Remote: RemoteSettings{Allow: []string{"127.0.0.1"}, Timeout: "1m"}}}
+
+	loadFile("file:///api.yaml", `openapi: 3.0.0
+components:
+  responses:
+    BadRequest:
+      $ref: `+server.URL+`/slow.yaml#/Error
+`)(t, &h)
+
+	_ = diagnosticStrings(t, &h, "file:///api.yaml")
+	<-started
+
+	// Closing the handler cancels the fetch, and its result is dropped rather
+	// than waiting for a server that no longer runs tasks.
+
+	h.Close()
+
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the fetch was not canceled")
+	}
+
+	select {
+	case <-h.Tasks():
+		t.Error("got a task after the handler was closed")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,92 @@
+package analysis
+
+import (
+	"bytes"
+	"slices"
+
+	"github.com/armsnyder/openapi-language-server/internal/lsp"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+// HandleSave runs the checks that look at the whole workspace, which are too
+// slow to run on every change.
+func (h *Handler) HandleSave(params types.DidSaveTextDocumentParams) error {
+	if _, ok := h.files[params.TextDocument.URI]; !ok {
+		h.logf("HandleSave: Unknown file %q", params.TextDocument.URI)
+		return nil
+	}
+
+	// Clients that cannot watch files still tell the server about config
+	// files that are saved in the editor.
+	if isConfigFile(params.TextDocument.URI) {
+		h.reloadConfig(params.TextDocument.URI)
+	}
+
+	h.invalidateSaveProblems()
+
+	return nil
+}
+
+// HandleWillSaveWaitUntil returns the edits that the settings ask for before a
+// document is saved. Saves that happen automatically after a delay are left
+// alone, so that the document does not change while the user is typing.
+func (h *Handler) HandleWillSaveWaitUntil(params types.WillSaveTextDocumentParams) ([]types.TextEdit, error) {
+	uri := params.TextDocument.URI
+
+	f := h.files[uri]
+	if f == nil || f.language != languageYAML || f.outOfSync || params.Reason == types.SaveAfterDelay || !h.related(uri) {
+		return nil, nil
+	}
+
+	switch {
+	case h.Settings.SortOnSave && h.Settings.FormatOnSave:
+		return h.sortAndFormatEdits(uri)
+	case h.Settings.SortOnSave:
+		return h.sortEdits(uri), nil
+	case h.Settings.FormatOnSave:
+		return h.formattingEdits(uri, types.FormattingOptions{}, 0, -1)
+	default:
+		return nil, nil
+	}
+}
+
+// sortAndFormatEdits returns an edit that replaces the whole document with
+// its sorted and formatted contents. The edits of sorting and formatting would
+// overlap, so they cannot be combined.
+func (h *Handler) sortAndFormatEdits(uri string) ([]types.TextEdit, error) {
+	f := h.files[uri]
+	src := f.file.Bytes()
+
+	// Apply the sort edits from the end, so that the positions of the
+	// remaining edits stay valid.
+	var sorted lsp.File
+	sorted.Reset(slices.Clone(src))
+
+	edits := h.sortEdits(uri)
+	for i := len(edits) - 1; i >= 0; i-- {
+		if err := sorted.ApplyChange(types.TextDocumentContentChangeEvent{Range: &edits[i].Range, Text: edits[i].NewText}); err != nil {
+			h.logf("Error sorting document %q: %v", uri, err)
+			return nil, nil
+		}
+	}
+
+	formatted, err := Format(sorted.Bytes(), h.Settings.TabSize)
+	if err != nil {
+		h.logf("Error formatting document %q: %v", uri, err)
+		return nil, nil
+	}
+
+	if bytes.Equal(formatted, src) {
+		return nil, nil
+	}
+
+	end, err := f.file.GetPosition(len(src))
+	if err != nil {
+		return nil, err
+	}
+
+	return []types.TextEdit{{
+		Range:   types.Range{End: end},
+		NewText: string(formatted),
+	}}, nil
+}
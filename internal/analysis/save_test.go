@@ -0,0 +1,184 @@
+package analysis_test
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/armsnyder/openapi-language-server/internal/analysis"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+func TestHandler_HandleSave(t *testing.T) {
+	var h Handler
+
+	loadFile("file:///api.yaml", `openapi: 3.0.0
+paths:
+  /a:
+    get:
+      operationId: same
+  /b:
+    get:
+      operationId: same
+`)(t, &h)
+
+	params, err := h.Diagnostics()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(params) != 1 || len(params[0].Diagnostics) != 2 {
+		t.Fatalf("Diagnostics() = %+v, want 2 diagnostics after open", params)
+	}
+
+	// The duplicate operation IDs move along with the lines that are added,
+	// and are only checked again when the document is saved.
+
+	change := func(version int, rng, text string) {
+		t.Helper()
+
+		if err := h.HandleChange(types.DidChangeTextDocumentParams{
+			TextDocument: types.VersionedTextDocumentIdentifier{
+				TextDocumentIdentifier: types.TextDocumentIdentifier{URI: "file:///api.yaml"},
+				Version:                version,
+			},
+			ContentChanges: []types.TextDocumentContentChangeEvent{{Text: text, Range: toPtr(newRange(rng))}},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lines := func() []int {
+		t.Helper()
+
+		params, err := h.Diagnostics()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var result []int
+		for _, p := range params {
+			for _, d := range p.Diagnostics {
+				result = append(result, d.Range.Start.Line)
+			}
+		}
+		return result
+	}
+
+	change(1, "0:0-0:0", "# Pets\n")
+
+	if got, want := lines(), []int{5, 8}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("diagnostics on lines %v after adding a line, want %v", got, want)
+	}
+
+	// A change to the line of a problem drops it, since its position is lost.
+
+	change(2, "8:19-8:23", "other")
+
+	if got, want := lines(), []int{5}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("diagnostics on lines %v after editing a line, want %v", got, want)
+	}
+
+	if err := h.HandleSave(types.DidSaveTextDocumentParams{
+		TextDocument: types.TextDocumentIdentifier{URI: "file:///api.yaml"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := lines(); len(got) != 0 {
+		t.Fatalf("diagnostics on lines %v after save, want none", got)
+	}
+}
+
+func TestHandler_HandleWillSaveWaitUntil(t *testing.T) {
+	src := `openapi: 3.0.0
+paths:
+  /b:
+      get: {}
+  /a:
+      get: {}
+`
+
+	tests := []struct {
+		name     string
+		settings Settings
+		reason   types.TextDocumentSaveReason
+		want     []types.TextEdit
+	}{
+		{
+			name:   "disabled",
+			reason: types.SaveManual,
+		},
+		{
+			name:     "format",
+			settings: Settings{FormatOnSave: true},
+			reason:   types.SaveManual,
+			want: []types.TextEdit{
+				{Range: newRange("3:0-4:0"), NewText: "    get: {}\n"},
+				{Range: newRange("4:0-5:0"), NewText: "\n  /a:\n"},
+				{Range: newRange("5:0-6:0"), NewText: "    get: {}\n"},
+			},
+		},
+		{
+			name:     "sort",
+			settings: Settings{SortOnSave: true},
+			reason:   types.SaveManual,
+			want: []types.TextEdit{{
+				Range:   newRange("1:0-6:0"),
+				NewText: "paths:\n  /a:\n      get: {}\n  /b:\n      get: {}\n",
+			}},
+		},
+		{
+			name:     "sort and format",
+			settings: Settings{SortOnSave: true, FormatOnSave: true},
+			reason:   types.SaveFocusOut,
+			want: []types.TextEdit{{
+				Range:   newRange("0:0-6:0"),
+				NewText: "openapi: 3.0.0\npaths:\n  /a:\n    get: {}\n\n  /b:\n    get: {}\n",
+			}},
+		},
+		{
+			name:     "after delay",
+			settings: Settings{SortOnSave: true, FormatOnSave: true},
+			reason:   types.SaveAfterDelay,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := Handler{Settings: tt.settings}
+
+			loadFile("file:///api.yaml", src)(t, &h)
+
+			got, err := h.HandleWillSaveWaitUntil(types.WillSaveTextDocumentParams{
+				TextDocument: types.TextDocumentIdentifier{URI: "file:///api.yaml"},
+				Reason:       tt.reason,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("edits =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandler_Capabilities_WillSaveWaitUntil(t *testing.T) {
+	for _, settings := range []Settings{{}, {FormatOnSave: true}, {SortOnSave: true}} {
+		h := Handler{Settings: settings}
+		sync := h.Capabilities().TextDocumentSync
+
+		if !sync.WillSaveWaitUntil {
+			t.Errorf("with %+v: WillSaveWaitUntil = false, want true", settings)
+		}
+
+		if sync.Save == nil {
+			t.Errorf("with %+v: Save = nil, want save notifications", settings)
+		}
+	}
+}
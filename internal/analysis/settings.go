@@ -0,0 +1,235 @@
+package analysis
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+// Settings configure optional behavior of the handler. The zero value is the
+// default configuration.
+type Settings struct {
+	// Features turns features of the server on or off. Features that are not
+	// listed are on.
+	Features map[Feature]bool `json:"features"`
+
+	// Lint sets the level of lint rules, by the code of their diagnostics.
+	// Rules that are not listed keep their default level.
+	Lint map[string]Level `json:"lint"`
+
+	// Roots are the root documents of the specs in the workspace. They are
+	// analyzed even when they are not open, so that problems that involve
+	// the whole spec can be found from any of its files. Relative paths are
+	// resolved against each workspace folder.
+	Roots []string `json:"roots"`
+
+	// SearchPaths are directories where relative $refs are looked up when
+	// they are not found relative to the document that contains them.
+	// Relative paths are resolved against each workspace folder.
+	SearchPaths []string `json:"searchPaths"`
+
+	// TabSize is the indentation of formatted documents, when the client
+	// does not specify one.
+	TabSize int `json:"tabSize"`
+
+	// FormatOnSave formats documents before they are saved.
+	FormatOnSave bool `json:"formatOnSave"`
+
+	// SortOnSave sorts the components and paths of documents before they are
+	// saved.
+	SortOnSave bool `json:"sortOnSave"`
+
+	// Remote configures how $refs to HTTP(S) URLs are followed.
+	Remote RemoteSettings `json:"remote"`
+}
+
+// RemoteSettings configure how $refs to HTTP(S) URLs are followed. Remote
+// documents are fetched in the background and cached on disk.
+type RemoteSettings struct {
+	// Allow are glob patterns of the hosts that documents may be fetched
+	// from, such as "schemas.internal" or "*.example.com". $refs to other
+	// hosts are not followed.
+	Allow []string `json:"allow"`
+
+	// Timeout limits how long a fetch may take, such as "5s". The default is
+	// ten seconds.
+	Timeout string `json:"timeout"`
+
+	// Offline only uses documents that are already in the cache, and never
+	// fetches them.
+	Offline bool `json:"offline"`
+}
+
+// Feature is an optional feature of the server.
+type Feature string
+
+const (
+	FeatureDiagnostics   Feature = "diagnostics"
+	FeatureCodeActions   Feature = "codeActions"
+	FeatureCodeLens      Feature = "codeLens"
+	FeatureInlayHints    Feature = "inlayHints"
+	FeatureDocumentLinks Feature = "documentLinks"
+	FeatureHover         Feature = "hover"
+)
+
+var features = []Feature{FeatureDiagnostics, FeatureCodeActions, FeatureCodeLens, FeatureInlayHints, FeatureDocumentLinks, FeatureHover}
+
+// Enabled returns true if the feature is on.
+func (s Settings) Enabled(feature Feature) bool {
+	enabled, ok := s.Features[feature]
+	return !ok || enabled
+}
+
+// Level is the level of a lint rule.
+type Level string
+
+const (
+	LevelError       Level = "error"
+	LevelWarning     Level = "warning"
+	LevelInformation Level = "information"
+	LevelHint        Level = "hint"
+	LevelOff         Level = "off"
+)
+
+var levelSeverities = map[Level]types.DiagnosticSeverity{
+	LevelError:       types.SeverityError,
+	LevelWarning:     types.SeverityWarning,
+	LevelInformation: types.SeverityInformation,
+	LevelHint:        types.SeverityHint,
+}
+
+// lintRules are the codes of the diagnostics that the checks report.
+var lintRules = []string{
+	"duplicate-operation-id",
+	"missing-path-parameter",
+	"path-parameter-not-required",
+	"unknown-extension",
+	"unresolved-ref",
+	"unused-path-parameter",
+}
+
+// ParseSettings decodes settings from JSON. Fields that are missing keep their
+// default values. Unknown features, lint rules and levels are errors, so that
+// typos do not go unnoticed.
+func ParseSettings(data []byte) (Settings, error) {
+	var settings Settings
+
+	if len(bytes.TrimSpace(data)) == 0 || string(bytes.TrimSpace(data)) == "null" {
+		return settings, nil
+	}
+
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return Settings{}, err
+	}
+
+	var errs []error
+
+	for feature := range settings.Features {
+		if !slices.Contains(features, feature) {
+			errs = append(errs, fmt.Errorf("unknown feature %q", feature))
+		}
+	}
+
+	for rule, level := range settings.Lint {
+		if !slices.Contains(lintRules, rule) {
+			errs = append(errs, fmt.Errorf("unknown lint rule %q", rule))
+		}
+
+		if _, ok := levelSeverities[level]; !ok && level != LevelOff {
+			errs = append(errs, fmt.Errorf("unknown level %q for lint rule %q", level, rule))
+		}
+	}
+
+	if settings.TabSize < 0 {
+		errs = append(errs, fmt.Errorf("invalid tab size %d", settings.TabSize))
+	}
+
+	for _, pattern := range settings.Remote.Allow {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("invalid host pattern %q", pattern))
+		}
+	}
+
+	if timeout := settings.Remote.Timeout; timeout != "" {
+		if d, err := time.ParseDuration(timeout); err != nil || d <= 0 {
+			errs = append(errs, fmt.Errorf("invalid timeout %q", timeout))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return Settings{}, err
+	}
+
+	return settings, nil
+}
+
+func (h *Handler) HandleInitialize(params types.InitializeParams) error {
+	h.folders = nil
+	for _, folder := range params.WorkspaceFolders {
+		h.folders = append(h.folders, folderURI(folder.URI))
+	}
+
+	// Clients that do not support workspace folders only send the root.
+	if len(h.folders) == 0 && params.RootURI != "" {
+		h.folders = []string{folderURI(params.RootURI)}
+	}
+
+	h.applySettings(params.InitializationOptions)
+
+	return nil
+}
+
+func (h *Handler) HandleDidChangeConfiguration(params types.DidChangeConfigurationParams) error {
+	h.applySettings(params.Settings)
+
+	return nil
+}
+
+// applySettings replaces the settings of the handler. Invalid settings are
+// shown to the user and otherwise ignored.
+func (h *Handler) applySettings(data []byte) {
+	settings, err := ParseSettings(data)
+	if err != nil {
+		h.showf("Invalid settings: %v", strings.ReplaceAll(err.Error(), "\n", "; "))
+		return
+	}
+
+	h.Settings = settings
+
+	// Remote documents are loaded again, since the allowlist may have
+	// changed.
+	h.remote = nil
+
+	// Any check may depend on the roots, search paths or lint levels.
+	h.invalidateProblems()
+	h.invalidateSaveProblems()
+}
+
+// settingsURI returns the URI of a path from the settings, which is either
+// absolute or relative to the given workspace folder.
+func (h *Handler) settingsURI(folder, name string) string {
+	if filepath.IsAbs(name) || folder == "" {
+		abs, err := filepath.Abs(name)
+		if err != nil {
+			return ""
+		}
+
+		return (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String()
+	}
+
+	uri, _, err := resolveURI(folder, filepath.ToSlash(name))
+	if err != nil {
+		return ""
+	}
+
+	return uri
+}
@@ -0,0 +1,242 @@
+package analysis_test
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/armsnyder/openapi-language-server/internal/analysis"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+func TestParseSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    Settings
+		wantErr string
+	}{
+		{name: "empty"},
+		{name: "null", data: "null"},
+		{
+			name: "all",
+			data: `{
+  "features": {"codeLens": false},
+  "lint": {"duplicate-operation-id": "warning"},
+  "roots": ["openapi.yaml"],
+  "searchPaths": ["shared"],
+  "tabSize": 4,
+  "formatOnSave": true,
+  "sortOnSave": true,
+  "remote": {"allow": ["*.internal"], "timeout": "5s", "offline": true}
+}`,
+			want: Settings{
+				Features:     map[Feature]bool{FeatureCodeLens: false},
+				Lint:         map[string]Level{"duplicate-operation-id": LevelWarning},
+				Roots:        []string{"openapi.yaml"},
+				SearchPaths:  []string{"shared"},
+				TabSize:      4,
+				FormatOnSave: true,
+				SortOnSave:   true,
+				Remote:       RemoteSettings{Allow: []string{"*.internal"}, Timeout: "5s", Offline: true},
+			},
+		},
+		{name: "unknown feature", data: `{"features": {"completion": true}}`, wantErr: `unknown feature "completion"`},
+		{name: "unknown rule", data: `{"lint": {"no-such-rule": "off"}}`, wantErr: `unknown lint rule "no-such-rule"`},
+		{name: "unknown level", data: `{"lint": {"unused-path-parameter": "fatal"}}`, wantErr: `unknown level "fatal"`},
+		{name: "negative tab size", data: `{"tabSize": -1}`, wantErr: "invalid tab size -1"},
+		{name: "invalid host pattern", data: `{"remote": {"allow": ["[a-"]}}`, wantErr: `invalid host pattern "[a-"`},
+		{name: "invalid timeout", data: `{"remote": {"timeout": "soon"}}`, wantErr: `invalid timeout "soon"`},
+		{name: "wrong type", data: `{"roots": "openapi.yaml"}`, wantErr: "cannot unmarshal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSettings([]byte(tt.data))
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseSettings() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSettings() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandler_HandleDidChangeConfiguration(t *testing.T) {
+	var h Handler
+
+	loadFile("file:///api.yaml", `openapi: 3.0.0
+paths:
+  /pets/{id}:
+    get:
+      operationId: same
+      x-codegen: {}
+  /owners:
+    get:
+      operationId: same
+`)(t, &h)
+
+	configure := func(settings string) {
+		t.Helper()
+
+		if err := h.HandleDidChangeConfiguration(types.DidChangeConfigurationParams{Settings: json.RawMessage(settings)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	severities := func() map[string]types.DiagnosticSeverity {
+		t.Helper()
+
+		params, err := h.Diagnostics()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(params) != 1 {
+			t.Fatalf("Diagnostics() = %+v, want diagnostics for 1 file", params)
+		}
+
+		result := map[string]types.DiagnosticSeverity{}
+		for _, d := range params[0].Diagnostics {
+			result[d.Code] = d.Severity
+		}
+
+		return result
+	}
+
+	want := map[string]types.DiagnosticSeverity{
+		"duplicate-operation-id": types.SeverityError,
+		"missing-path-parameter": types.SeverityError,
+	}
+	if got := severities(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("severities = %v, want %v", got, want)
+	}
+
+	// Lint levels apply to the diagnostics that are already published.
+
+	configure(`{"lint": {"duplicate-operation-id": "hint", "missing-path-parameter": "off"}}`)
+
+	want = map[string]types.DiagnosticSeverity{
+		"duplicate-operation-id": types.SeverityHint,
+	}
+	if got := severities(); !reflect.DeepEqual(got, want) {
+		t.Errorf("severities = %v, want %v", got, want)
+	}
+
+	// Invalid settings are shown to the user, and the previous settings are
+	// kept.
+
+	configure(`{"lint": {"duplicate-operation-id": "loud"}}`)
+
+	if messages := h.Messages(); len(messages) != 1 || !messages[0].Show || !strings.Contains(messages[0].Message, `unknown level "loud"`) {
+		t.Errorf("Messages() = %+v, want the error to be shown", messages)
+	}
+
+	if h.Settings.Lint["duplicate-operation-id"] != LevelHint {
+		t.Errorf("Settings = %+v, want the previous settings", h.Settings)
+	}
+
+	// Disabled features return nothing.
+
+	configure(`{"features": {"diagnostics": false, "codeActions": false, "codeLens": false}}`)
+
+	if got := severities(); len(got) != 0 {
+		t.Errorf("severities = %v, want none", got)
+	}
+
+	actions, err := h.HandleCodeAction(types.CodeActionParams{TextDocument: types.TextDocumentIdentifier{URI: "file:///api.yaml"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(actions) != 0 {
+		t.Errorf("HandleCodeAction() = %+v, want none", actions)
+	}
+
+	lenses, err := h.HandleCodeLens(types.CodeLensParams{TextDocument: types.TextDocumentIdentifier{URI: "file:///api.yaml"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(lenses) != 0 {
+		t.Errorf("HandleCodeLens() = %+v, want none", lenses)
+	}
+}
+
+func TestHandler_HandleInitialize(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "openapi.yaml"), `openapi: 3.0.0
+paths:
+  /pets:
+    $ref: pets.yaml
+  /animals:
+    get:
+      operationId: listPets
+`)
+	writeFile(t, filepath.Join(dir, "shared", "pet.yaml"), `type: object
+`)
+
+	base := "file://" + filepath.ToSlash(dir)
+
+	var h Handler
+
+	if err := h.HandleInitialize(types.InitializeParams{
+		RootURI:               base,
+		InitializationOptions: json.RawMessage(`{"roots": ["openapi.yaml"], "searchPaths": ["shared"], "tabSize": 4}`),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The root is analyzed although it is not open, so the duplicate is found
+	// in the fragment.
+
+	loadFile(base+"/pets.yaml", `get:
+  operationId: listPets
+  responses:
+    "200":
+      content:
+        application/json:
+          schema:
+            $ref: pet.yaml
+`)(t, &h)
+
+	want := []string{`1:15-1:23 duplicate-operation-id: Duplicate operationId "listPets"`}
+	if got := diagnosticStrings(t, &h, base+"/pets.yaml"); !reflect.DeepEqual(got, want) {
+		t.Errorf("diagnostics = %q, want %q", got, want)
+	}
+
+	// References that are not found next to the document are looked up in
+	// the search paths.
+
+	links, err := h.HandleDocumentLink(types.DocumentLinkParams{TextDocument: types.TextDocumentIdentifier{URI: base + "/pets.yaml"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(links) != 1 || links[0].Target != base+"/shared/pet.yaml" {
+		t.Errorf("HandleDocumentLink() = %+v, want a link to the search path", links)
+	}
+
+	// The tab size applies when the client does not send one.
+
+	edits, err := h.HandleFormatting(types.DocumentFormattingParams{TextDocument: types.TextDocumentIdentifier{URI: base + "/pets.yaml"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(edits) == 0 || !strings.HasPrefix(edits[0].NewText, "    operationId") {
+		t.Errorf("HandleFormatting() = %+v, want indentation of 4", edits)
+	}
+}
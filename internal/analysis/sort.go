@@ -0,0 +1,221 @@
+package analysis
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/armsnyder/openapi-language-server/internal/analysis/yaml"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+// codeActionSortComponents is the kind of the source action that sorts the
+// document canonically.
+const codeActionSortComponents types.CodeActionKind = "source.sortComponents"
+
+// The sort action orders the entries of each components map alphabetically,
+// paths by their segments, and the operations of each path item by HTTP
+// method. Entries are moved as whole subtrees, together with any comments
+// directly above them, so their content is untouched. Blank lines stay where
+// they are.
+
+// sortAction returns the source action that sorts the document, or nil if the
+// document is already sorted.
+func (h *Handler) sortAction(uri string) *types.CodeAction {
+	edits := h.sortEdits(uri)
+	if len(edits) == 0 {
+		return nil
+	}
+
+	return &types.CodeAction{
+		Title: "Sort components and paths",
+		Kind:  codeActionSortComponents,
+		Edit:  h.workspaceEdit(uri, edits),
+	}
+}
+
+// sortEdits returns the edits that sort the document, in order.
+func (h *Handler) sortEdits(uri string) []types.TextEdit {
+	f := h.files[uri]
+	if f == nil || f.language != languageYAML || f.outOfSync {
+		return nil
+	}
+
+	document, err := h.getDocument(uri)
+	if err != nil {
+		return nil
+	}
+
+	// A file that ends with a line break has an empty last line, which is
+	// not part of the document.
+	lines := make([]string, f.file.LineCount())
+	for i := range lines {
+		lines[i] = string(f.file.Line(i))
+	}
+
+	finalNewline := len(lines) > 1 && lines[len(lines)-1] == ""
+	if finalNewline {
+		lines = lines[:len(lines)-1]
+	}
+
+	s := sorter{document: document, lines: lines}
+
+	var edits []types.TextEdit
+
+	for _, line := range document.Lines {
+		if line.Parent != nil || line.Empty {
+			continue
+		}
+
+		end := s.end(line)
+		original := lines[line.Number : end+1]
+
+		sorted := s.render(line)
+		if slices.Equal(sorted, original) {
+			continue
+		}
+
+		edit := types.TextEdit{
+			Range:   types.Range{Start: types.Position{Line: line.Number}, End: types.Position{Line: end + 1}},
+			NewText: strings.Join(sorted, "\n") + "\n",
+		}
+
+		if end == len(lines)-1 && !finalNewline {
+			edit.Range.End, err = f.file.GetPosition(f.file.Len())
+			if err != nil {
+				return nil
+			}
+			edit.NewText = strings.TrimSuffix(edit.NewText, "\n")
+		}
+
+		edits = append(edits, edit)
+	}
+
+	return edits
+}
+
+type sorter struct {
+	document yaml.Document
+	lines    []string
+}
+
+// block is a mapping entry together with the comments directly above it.
+type block struct {
+	line       *yaml.Line
+	start, end int
+}
+
+// render returns the lines of the subtree rooted at the given line, with the
+// entries of any sortable mappings within it reordered.
+func (s sorter) render(line *yaml.Line) []string {
+	end := s.end(line)
+
+	blocks := s.blocks(line)
+	if len(blocks) == 0 || len(line.Items) > 0 || line.IsBlockScalar() {
+		return s.lines[line.Number : end+1]
+	}
+
+	order := slices.Clone(blocks)
+	if less := sortOrder(line); less != nil {
+		slices.SortStableFunc(order, func(a, b block) int {
+			return less(a.line.Key, b.line.Key)
+		})
+	}
+
+	result := slices.Clone(s.lines[line.Number:blocks[0].start])
+
+	for i, b := range order {
+		result = append(result, s.lines[b.start:b.line.Number]...)
+		result = append(result, s.render(b.line)...)
+
+		// The gap after the i-th entry stays in place, whichever entry is
+		// moved there.
+		if i+1 < len(blocks) {
+			result = append(result, s.lines[blocks[i].end+1:blocks[i+1].start]...)
+		}
+	}
+
+	return result
+}
+
+// blocks returns the mapping entries nested directly under the given line, in
+// document order. Sequence items are not included, since their order is
+// meaningful.
+func (s sorter) blocks(line *yaml.Line) []block {
+	var blocks []block
+
+	for _, child := range line.Children {
+		blocks = append(blocks, block{line: child, start: child.Number, end: s.end(child)})
+	}
+
+	slices.SortFunc(blocks, func(a, b block) int {
+		return a.line.Number - b.line.Number
+	})
+
+	// Comments above an entry extend up to the end of the previous entry, or
+	// the parent for the first one. Lines that start with "#" at the end of a
+	// block scalar belong to the entry above.
+	above := line.Number
+	for i := range blocks {
+		for blocks[i].start-1 > above && s.document.Lines[blocks[i].start-1].Comment {
+			blocks[i].start--
+		}
+		above = blocks[i].end
+	}
+
+	return blocks
+}
+
+// end returns the line number of the last line in the subtree rooted at the
+// given line, including the contents of a block scalar at its end.
+func (s sorter) end(line *yaml.Line) int {
+	end := s.document.End(line)
+
+	for cur := s.document.Lines[end]; cur != nil; cur = cur.Parent {
+		if cur.IsBlockScalar() {
+			return s.document.BlockScalarEnd(cur)
+		}
+		if cur == line {
+			break
+		}
+	}
+
+	return end
+}
+
+// sortOrder returns the comparison of keys for the entries nested under the
+// given line, or nil if their order is left alone.
+func sortOrder(line *yaml.Line) func(a, b string) int {
+	switch {
+	case line.Key == "paths" && line.Parent == nil:
+		return comparePaths
+	case isPathItem(line):
+		return compareMethods
+	case line.Parent == nil && slices.Contains(swagger2Components, line.Key):
+		return compareNames
+	case line.Parent != nil && line.Parent.Key == "components" && line.Parent.Parent == nil:
+		return compareNames
+	default:
+		return nil
+	}
+}
+
+func compareNames(a, b string) int {
+	if c := strings.Compare(strings.ToLower(a), strings.ToLower(b)); c != 0 {
+		return c
+	}
+
+	return strings.Compare(a, b)
+}
+
+// comparePaths orders paths segment by segment, so that a path comes directly
+// before the paths nested below it.
+func comparePaths(a, b string) int {
+	return slices.Compare(strings.Split(a, "/"), strings.Split(b, "/"))
+}
+
+// compareMethods orders operations by the order of HTTP methods in the OpenAPI
+// specification. Other fields of the path item come first, in their original
+// order.
+func compareMethods(a, b string) int {
+	return slices.Index(httpMethods, a) - slices.Index(httpMethods, b)
+}
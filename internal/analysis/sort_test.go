@@ -0,0 +1,198 @@
+package analysis_test
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/armsnyder/openapi-language-server/internal/analysis"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+func TestHandler_HandleCodeAction_SortComponents(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []types.TextEdit
+	}{
+		{
+			name: "sorted",
+			src: `paths:
+  /pets:
+    get: {}
+components:
+  schemas:
+    A:
+      type: object
+    B:
+      type: object
+`,
+		},
+		{
+			name: "components",
+			src: `openapi: 3.0.0
+components:
+  schemas:
+    # Zebra.
+    Zebra:
+      type: object
+      properties:
+        z: {}
+        a: {}
+
+    apple:
+      type: object
+    Banana:
+      type: object
+  parameters:
+    Offset: {}
+    Limit: {}
+`,
+			want: []types.TextEdit{{
+				Range: newRange("1:0-17:0"),
+				NewText: `components:
+  schemas:
+    apple:
+      type: object
+
+    Banana:
+      type: object
+    # Zebra.
+    Zebra:
+      type: object
+      properties:
+        z: {}
+        a: {}
+  parameters:
+    Limit: {}
+    Offset: {}
+`,
+			}},
+		},
+		{
+			name: "paths and methods",
+			src: `openapi: 3.0.0
+paths:
+  /pets/{id}:
+    delete: {}
+    get: {}
+  /pets:
+    post: {}
+    parameters: []
+    get: {}
+  /pets-archive:
+    get: {}`,
+			want: []types.TextEdit{{
+				Range: newRange("1:0-10:11"),
+				NewText: `paths:
+  /pets:
+    parameters: []
+    get: {}
+    post: {}
+  /pets/{id}:
+    get: {}
+    delete: {}
+  /pets-archive:
+    get: {}`,
+			}},
+		},
+		{
+			name: "block scalar",
+			src: `openapi: 3.0.0
+components:
+  schemas:
+    B:
+      type: object
+      description: |
+        Text.
+
+        # Heading in markdown
+    A:
+      type: object
+`,
+			want: []types.TextEdit{{
+				Range: newRange("1:0-11:0"),
+				NewText: `components:
+  schemas:
+    A:
+      type: object
+    B:
+      type: object
+      description: |
+        Text.
+
+        # Heading in markdown
+`,
+			}},
+		},
+		{
+			name: "swagger 2",
+			src: `swagger: "2.0"
+definitions:
+  B: {}
+  A: {}
+`,
+			want: []types.TextEdit{{
+				Range:   newRange("1:0-4:0"),
+				NewText: "definitions:\n  A: {}\n  B: {}\n",
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var h Handler
+
+			loadFile("file:///api.yaml", tt.src)(t, &h)
+
+			actions, err := h.HandleCodeAction(types.CodeActionParams{
+				TextDocument: types.TextDocumentIdentifier{URI: "file:///api.yaml"},
+				Context:      types.CodeActionContext{Only: []types.CodeActionKind{types.CodeActionSource}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.want == nil {
+				if len(actions) != 0 {
+					t.Errorf("actions = %+v, want none", actions)
+				}
+				return
+			}
+
+			if len(actions) != 1 {
+				t.Fatalf("got %d actions, want 1", len(actions))
+			}
+
+			if actions[0].Kind != "source.sortComponents" {
+				t.Errorf("kind = %q, want source.sortComponents", actions[0].Kind)
+			}
+
+			got := actions[0].Edit.Changes["file:///api.yaml"]
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("edits =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandler_HandleCodeAction_OnlyQuickFix(t *testing.T) {
+	var h Handler
+
+	loadFile("file:///api.yaml", `components:
+  schemas:
+    B: {}
+    A: {}
+`)(t, &h)
+
+	actions, err := h.HandleCodeAction(types.CodeActionParams{
+		TextDocument: types.TextDocumentIdentifier{URI: "file:///api.yaml"},
+		Context:      types.CodeActionContext{Only: []types.CodeActionKind{types.CodeActionQuickFix}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(actions) != 0 {
+		t.Errorf("actions = %+v, want none", actions)
+	}
+}
@@ -0,0 +1,142 @@
+package analysis
+
+import (
+	"github.com/armsnyder/openapi-language-server/internal/analysis/yaml"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+// HandleFoldingRange folds every line that has nested lines, and every block
+// of consecutive comment lines.
+func (h *Handler) HandleFoldingRange(params types.FoldingRangeParams) ([]types.FoldingRange, error) {
+	uri := params.TextDocument.URI
+
+	document, err := h.getDocument(uri)
+	if err != nil {
+		h.logf("HandleFoldingRange: Error getting document %q: %v", uri, err)
+		return nil, nil
+	}
+
+	var ranges []types.FoldingRange
+
+	for i := 0; i < len(document.Lines); i++ {
+		line := document.Lines[i]
+
+		if line.Comment {
+			end := i
+			for end+1 < len(document.Lines) && document.Lines[end+1].Comment {
+				end++
+			}
+
+			if end > i {
+				ranges = append(ranges, types.FoldingRange{StartLine: i, EndLine: end, Kind: types.FoldingRangeComment})
+			}
+
+			continue
+		}
+
+		if line.Empty {
+			continue
+		}
+
+		if end := document.End(line); end > i {
+			ranges = append(ranges, types.FoldingRange{StartLine: i, EndLine: end})
+		}
+	}
+
+	return ranges, nil
+}
+
+// HandleSelectionRange expands a selection outward through the YAML structure:
+// the value or key under the cursor, then the key/value pair, then the mapping
+// that contains it, and so on up to the whole document. Since operations and
+// path items are entries of the mappings that contain them, expanding from a
+// field of an operation passes through the operation and then the path item.
+func (h *Handler) HandleSelectionRange(params types.SelectionRangeParams) ([]types.SelectionRange, error) {
+	uri := params.TextDocument.URI
+
+	document, err := h.getDocument(uri)
+	if err != nil {
+		h.logf("HandleSelectionRange: Error getting document %q: %v", uri, err)
+		return nil, nil
+	}
+
+	result := make([]types.SelectionRange, len(params.Positions))
+
+	for i, position := range params.Positions {
+		ranges := selectionRanges(document, position)
+
+		// Build the linked list from the outermost range inward.
+		var cur *types.SelectionRange
+		for j := len(ranges) - 1; j >= 0; j-- {
+			if cur != nil && cur.Range == ranges[j] {
+				continue
+			}
+			cur = &types.SelectionRange{Range: ranges[j], Parent: cur}
+		}
+
+		result[i] = *cur
+	}
+
+	return result, nil
+}
+
+// selectionRanges returns the ranges that contain the position, from the
+// innermost to the outermost. Consecutive ranges may be equal.
+func selectionRanges(document yaml.Document, position types.Position) []types.Range {
+	documentRange := types.Range{End: types.Position{Line: len(document.Lines)}}
+
+	if position.Line >= len(document.Lines) || document.Lines[position.Line].Empty {
+		return []types.Range{documentRange}
+	}
+
+	line := document.Lines[position.Line]
+
+	var ranges []types.Range
+
+	switch {
+	case contains(line.ValueRange, position):
+		ranges = append(ranges, line.ValueRange)
+	case contains(line.KeyRange, position):
+		ranges = append(ranges, line.KeyRange)
+	}
+
+	// The first entry of a mapping in a sequence shares its line with the
+	// item, so the pair is just that line.
+	if line.Item && line.Key != "" && (len(line.Children) > 0 || len(line.Items) > 0) {
+		ranges = append(ranges, types.Range{
+			Start: line.KeyRange.Start,
+			End:   types.Position{Line: line.Number + 1},
+		})
+	}
+
+	for cur := line; cur != nil; cur = cur.Parent {
+		ranges = append(ranges, subtreeRange(document, cur))
+
+		if cur.Parent != nil {
+			ranges = append(ranges, childrenRange(document, cur.Parent))
+		}
+	}
+
+	return append(ranges, documentRange)
+}
+
+// childrenRange returns a range that spans the nested lines of the given line,
+// without the line itself.
+func childrenRange(document yaml.Document, line *yaml.Line) types.Range {
+	for i := line.Number + 1; i < len(document.Lines); i++ {
+		if first := document.Lines[i]; !first.Empty {
+			return types.Range{
+				Start: types.Position{Line: first.Number, Character: first.Indent},
+				End:   types.Position{Line: document.End(line) + 1},
+			}
+		}
+	}
+
+	return subtreeRange(document, line)
+}
+
+// contains returns true if the position is within the range, including its
+// ends. The zero range contains nothing, since it marks a missing key or value.
+func contains(r types.Range, p types.Position) bool {
+	return r != (types.Range{}) && !before(p, r.Start) && !before(r.End, p)
+}
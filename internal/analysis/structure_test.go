@@ -0,0 +1,124 @@
+package analysis_test
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/armsnyder/openapi-language-server/internal/analysis"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+const structureSpec = `# Pet store.
+# Version 1.
+openapi: 3.0.0
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      parameters:
+        - name: limit
+          in: query
+
+    post:
+      operationId: createPet
+`
+
+func TestHandler_HandleFoldingRange(t *testing.T) {
+	var h Handler
+
+	loadFile("file:///api.yaml", structureSpec)(t, &h)
+
+	got, err := h.HandleFoldingRange(types.FoldingRangeParams{
+		TextDocument: types.TextDocumentIdentifier{URI: "file:///api.yaml"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []types.FoldingRange{
+		{StartLine: 0, EndLine: 1, Kind: types.FoldingRangeComment},
+		{StartLine: 3, EndLine: 12},
+		{StartLine: 4, EndLine: 12},
+		{StartLine: 5, EndLine: 9},
+		{StartLine: 7, EndLine: 9},
+		{StartLine: 8, EndLine: 9},
+		{StartLine: 11, EndLine: 12},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("folding ranges = %+v, want %+v", got, want)
+	}
+}
+
+func TestHandler_HandleSelectionRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		position string
+		want     []string
+	}{
+		{
+			name:     "operation field value",
+			position: "6:22",
+			want: []string{
+				"6:19-6:27", // value
+				"6:6-7:0",   // key/value pair
+				"6:6-10:0",  // operation mapping
+				"5:4-10:0",  // operation
+				"5:4-13:0",  // path item mapping
+				"4:2-13:0",  // path item
+				"3:0-13:0",  // paths
+				"0:0-13:0",  // document
+			},
+		},
+		{
+			name:     "sequence item",
+			position: "8:12",
+			want: []string{
+				"8:10-8:14", // key
+				"8:10-9:0",  // first entry of the item
+				"8:8-10:0",  // item
+				"7:6-10:0",  // parameters
+				"6:6-10:0",  // operation mapping
+				"5:4-10:0",  // operation
+				"5:4-13:0",  // path item mapping
+				"4:2-13:0",  // path item
+				"3:0-13:0",  // paths
+				"0:0-13:0",  // document
+			},
+		},
+		{
+			name:     "comment",
+			position: "0:3",
+			want:     []string{"0:0-13:0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var h Handler
+
+			loadFile("file:///api.yaml", structureSpec)(t, &h)
+
+			got, err := h.HandleSelectionRange(types.SelectionRangeParams{
+				TextDocument: types.TextDocumentIdentifier{URI: "file:///api.yaml"},
+				Positions:    []types.Position{positionParams("", tt.position).Position},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got) != 1 {
+				t.Fatalf("got %d selection ranges, want 1", len(got))
+			}
+
+			var ranges []string
+			for cur := &got[0]; cur != nil; cur = cur.Parent {
+				ranges = append(ranges, cur.Range.String())
+			}
+
+			if !reflect.DeepEqual(ranges, tt.want) {
+				t.Errorf("selection ranges = %q, want %q", ranges, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package analysis
+
+import (
+	"github.com/armsnyder/openapi-language-server/internal/analysis/yaml"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+// A schema's supertypes are the schemas referenced by its allOf list, and its
+// subtypes are the schemas whose allOf list references it.
+
+func (h *Handler) HandlePrepareTypeHierarchy(params types.TypeHierarchyPrepareParams) ([]types.TypeHierarchyItem, error) {
+	uri := params.TextDocument.URI
+
+	document, err := h.getDocument(uri)
+	if err != nil {
+		h.logf("HandlePrepareTypeHierarchy: Error getting document %q: %v", uri, err)
+		return nil, nil
+	}
+
+	if !h.related(uri) {
+		return nil, nil
+	}
+
+	line := document.At(params.Position)
+	if line == nil {
+		return nil, nil
+	}
+
+	// If the cursor is on a reference to a schema, prepare the referenced
+	// schema rather than the enclosing one.
+	if line.Key == "$ref" {
+		if target, err := h.resolve(uri, line.Value); err == nil && isSchema(target.line) {
+			return []types.TypeHierarchyItem{typeHierarchyItem(target.uri, target.document, target.line)}, nil
+		}
+	}
+
+	schema := enclosingSchema(line)
+	if schema == nil {
+		return nil, nil
+	}
+
+	return []types.TypeHierarchyItem{typeHierarchyItem(uri, document, schema)}, nil
+}
+
+func (h *Handler) HandleTypeHierarchySupertypes(params types.TypeHierarchySupertypesParams) ([]types.TypeHierarchyItem, error) {
+	schema := h.locateItem(params.Item.URI, params.Item.SelectionRange)
+	if schema == nil {
+		return nil, nil
+	}
+
+	var result []types.TypeHierarchyItem
+
+	for _, ref := range allOfRefs(schema) {
+		target, err := h.resolve(params.Item.URI, ref.Value)
+		if err != nil || !isSchema(target.line) {
+			continue
+		}
+
+		result = append(result, typeHierarchyItem(target.uri, target.document, target.line))
+	}
+
+	return result, nil
+}
+
+func (h *Handler) HandleTypeHierarchySubtypes(params types.TypeHierarchySubtypesParams) ([]types.TypeHierarchyItem, error) {
+	schema := h.locateItem(params.Item.URI, params.Item.SelectionRange)
+	if schema == nil {
+		return nil, nil
+	}
+
+	want := schema.KeyRef()
+
+	var result []types.TypeHierarchyItem
+
+	for _, d := range h.workspaceDocuments(h.folder(params.Item.URI)) {
+		for _, line := range d.document.Nodes() {
+			if !isSchema(line) {
+				continue
+			}
+
+			for _, ref := range allOfRefs(line) {
+				uri, fragment, err := h.resolveRef(d.uri, ref.Value)
+				if err == nil && uri == params.Item.URI && "#"+fragment == want {
+					result = append(result, typeHierarchyItem(d.uri, d.document, line))
+					break
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// locateItem returns the line identified by an item previously returned to the
+// client.
+func (h *Handler) locateItem(uri string, selectionRange types.Range) *yaml.Line {
+	document, err := h.loadDocument(uri)
+	if err != nil {
+		h.logf("Error loading document %q: %v", uri, err)
+		return nil
+	}
+
+	return document.At(selectionRange.Start)
+}
+
+// allOfRefs returns the $ref lines in the allOf list of the given schema.
+func allOfRefs(schema *yaml.Line) []*yaml.Line {
+	allOf := schema.Children["allOf"]
+	if allOf == nil {
+		return nil
+	}
+
+	var result []*yaml.Line
+
+	for _, item := range allOf.Items {
+		if ref := item.Field("$ref"); ref != nil {
+			result = append(result, ref)
+		}
+	}
+
+	return result
+}
+
+func typeHierarchyItem(uri string, document yaml.Document, schema *yaml.Line) types.TypeHierarchyItem {
+	return types.TypeHierarchyItem{
+		Name:           schema.Key,
+		Kind:           types.SymbolKindClass,
+		Detail:         schema.KeyRef(),
+		URI:            uri,
+		Range:          subtreeRange(document, schema),
+		SelectionRange: schema.KeyRange,
+	}
+}
+
+// subtreeRange returns a range that spans the given line and all of its
+// descendants.
+func subtreeRange(document yaml.Document, line *yaml.Line) types.Range {
+	return types.Range{
+		Start: types.Position{Line: line.Number, Character: line.Indent},
+		End:   types.Position{Line: document.End(line) + 1},
+	}
+}
@@ -0,0 +1,131 @@
+package analysis_test
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	. "github.com/armsnyder/openapi-language-server/internal/analysis"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+const typeHierarchySpec = `openapi: 3.0.0
+components:
+  schemas:
+    Animal:
+      type: object
+    Pet:
+      allOf:
+        - $ref: "#/components/schemas/Animal"
+        - type: object
+    Dog:
+      allOf:
+        - $ref: "#/components/schemas/Pet"
+        - $ref: "./common.yaml#/components/schemas/Named"
+    Cat:
+      allOf:
+        - $ref: "#/components/schemas/Pet"
+`
+
+func TestHandler_TypeHierarchy(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "common.yaml"), `components:
+  schemas:
+    Named:
+      type: object
+`)
+
+	uri := "file://" + filepath.ToSlash(filepath.Join(dir, "api.yaml"))
+	commonURI := "file://" + filepath.ToSlash(filepath.Join(dir, "common.yaml"))
+
+	var h Handler
+
+	loadFile(uri, typeHierarchySpec)(t, &h)
+
+	// Prepare from inside the Pet schema.
+
+	items, err := h.HandlePrepareTypeHierarchy(types.TypeHierarchyPrepareParams{
+		TextDocumentPositionParams: positionParams(uri, "6:8"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantPet := types.TypeHierarchyItem{
+		Name:           "Pet",
+		Kind:           types.SymbolKindClass,
+		Detail:         "#/components/schemas/Pet",
+		URI:            uri,
+		Range:          newRange("5:4-9:0"),
+		SelectionRange: newRange("5:4-5:7"),
+	}
+
+	if !reflect.DeepEqual(items, []types.TypeHierarchyItem{wantPet}) {
+		t.Fatalf("HandlePrepareTypeHierarchy() = %+v, want %+v", items, wantPet)
+	}
+
+	// Supertypes of Pet.
+
+	supertypes, err := h.HandleTypeHierarchySupertypes(types.TypeHierarchySupertypesParams{Item: wantPet})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := itemNames(supertypes); !reflect.DeepEqual(got, []string{"Animal"}) {
+		t.Errorf("supertypes of Pet = %v, want [Animal]", got)
+	}
+
+	// Subtypes of Pet.
+
+	subtypes, err := h.HandleTypeHierarchySubtypes(types.TypeHierarchySubtypesParams{Item: wantPet})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := itemNames(subtypes); !reflect.DeepEqual(got, []string{"Dog", "Cat"}) {
+		t.Errorf("subtypes of Pet = %v, want [Dog Cat]", got)
+	}
+
+	// Prepare from a reference into another file, then find its subtypes.
+
+	items, err = h.HandlePrepareTypeHierarchy(types.TypeHierarchyPrepareParams{
+		TextDocumentPositionParams: positionParams(uri, "12:16"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(items) != 1 || items[0].Name != "Named" || items[0].URI != commonURI {
+		t.Fatalf("HandlePrepareTypeHierarchy() = %+v, want Named in common.yaml", items)
+	}
+
+	subtypes, err = h.HandleTypeHierarchySubtypes(types.TypeHierarchySubtypesParams{Item: items[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := itemNames(subtypes); !reflect.DeepEqual(got, []string{"Dog"}) {
+		t.Errorf("subtypes of Named = %v, want [Dog]", got)
+	}
+
+	// Outside of any schema.
+
+	items, err = h.HandlePrepareTypeHierarchy(types.TypeHierarchyPrepareParams{
+		TextDocumentPositionParams: positionParams(uri, "0:0"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(items) != 0 {
+		t.Errorf("HandlePrepareTypeHierarchy() = %+v, want none", items)
+	}
+}
+
+func itemNames(items []types.TypeHierarchyItem) []string {
+	var names []string
+	for _, item := range items {
+		names = append(names, item.Name)
+	}
+	return names
+}
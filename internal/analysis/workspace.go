@@ -0,0 +1,164 @@
+package analysis
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/armsnyder/openapi-language-server/internal/analysis/yaml"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+// loadedDocument is a parsed document together with its URI.
+type loadedDocument struct {
+	uri      string
+	document yaml.Document
+}
+
+// folderURI returns the URI of a workspace folder with a trailing slash.
+func folderURI(uri string) string {
+	return strings.TrimSuffix(uri, "/") + "/"
+}
+
+// folder returns the URI of the innermost workspace folder that contains the
+// document with the given URI, or "" if there is none.
+func (h *Handler) folder(uri string) string {
+	var result string
+
+	for _, folder := range h.folders {
+		if strings.HasPrefix(uri, folder) && len(folder) > len(result) {
+			result = folder
+		}
+	}
+
+	return result
+}
+
+func (h *Handler) HandleDidChangeWorkspaceFolders(params types.DidChangeWorkspaceFoldersParams) error {
+	for _, removed := range params.Event.Removed {
+		h.folders = slices.DeleteFunc(h.folders, func(folder string) bool {
+			return folder == folderURI(removed.URI)
+		})
+	}
+
+	for _, added := range params.Event.Added {
+		if folder := folderURI(added.URI); !slices.Contains(h.folders, folder) {
+			h.folders = append(h.folders, folder)
+		}
+	}
+
+	// Config files are looked up no further than the workspace folder, so a
+	// different one may apply to a document now.
+	h.reloadConfigs()
+
+	return nil
+}
+
+// rootURIs returns the URIs of the documents in the given workspace folder that
+// may be the roots of specs, which are the open documents and the roots from
+// the settings and config files, ordered by URI. The folder "" holds the
+// documents that are outside of every folder, such as shared schemas, and
+// its roots are those of all folders.
+func (h *Handler) rootURIs(folder string) []string {
+	uris := h.sortedURIs()
+
+	folders := []string{folder}
+	if folder == "" {
+		folders = append(folders, h.folders...)
+	}
+
+	for _, root := range h.Settings.Roots {
+		for _, f := range folders {
+			if uri := h.settingsURI(f, root); uri != "" {
+				uris = append(uris, uri)
+			}
+		}
+	}
+
+	h.loadConfigs()
+	for _, c := range h.sortedConfigs() {
+		uris = append(uris, c.roots...)
+	}
+
+	if folder != "" {
+		uris = slices.DeleteFunc(uris, func(uri string) bool {
+			return h.folder(uri) != folder
+		})
+	}
+
+	slices.Sort(uris)
+
+	return slices.Compact(uris)
+}
+
+// workspaceDocuments returns every root document of the given workspace folder,
+// plus every document that is reachable from them through $refs, ordered by
+// URI. Each folder is indexed separately, so that services in a monorepo do
+// not see each other's documents.
+func (h *Handler) workspaceDocuments(folder string) []loadedDocument {
+	var result []loadedDocument
+
+	seen := map[string]bool{}
+	queue := h.rootURIs(folder)
+
+	for len(queue) > 0 {
+		uri := queue[0]
+		queue = queue[1:]
+
+		if seen[uri] {
+			continue
+		}
+		seen[uri] = true
+
+		document, err := h.loadDocument(uri)
+		if err != nil {
+			continue
+		}
+
+		result = append(result, loadedDocument{uri: uri, document: document})
+
+		for _, line := range document.Nodes() {
+			if line.Key != "$ref" {
+				continue
+			}
+
+			target, _, err := h.resolveRef(uri, line.Value)
+			if err == nil && !seen[target] {
+				queue = append(queue, target)
+			}
+		}
+	}
+
+	slices.SortFunc(result, func(a, b loadedDocument) int {
+		return strings.Compare(a.uri, b.uri)
+	})
+
+	return result
+}
+
+// isSchema returns true if the line declares a named schema, either under
+// components/schemas (OpenAPI 3) or definitions (Swagger 2).
+func isSchema(line *yaml.Line) bool {
+	if line == nil || line.Item || line.Parent == nil {
+		return false
+	}
+
+	parent := line.Parent
+
+	if parent.Key == "definitions" && parent.Parent == nil {
+		return true
+	}
+
+	return parent.Key == "schemas" && parent.Parent != nil && parent.Parent.Key == "components" && parent.Parent.Parent == nil
+}
+
+// enclosingSchema returns the named schema that contains the given line, or
+// nil if there is none.
+func enclosingSchema(line *yaml.Line) *yaml.Line {
+	for cur := line; cur != nil; cur = cur.Parent {
+		if isSchema(cur) {
+			return cur
+		}
+	}
+
+	return nil
+}
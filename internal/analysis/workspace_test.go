@@ -0,0 +1,85 @@
+package analysis_test
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	. "github.com/armsnyder/openapi-language-server/internal/analysis"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+func TestHandler_WorkspaceFolders(t *testing.T) {
+	dir := t.TempDir()
+	base := "file://" + filepath.ToSlash(dir)
+
+	var h Handler
+
+	if err := h.HandleInitialize(types.InitializeParams{
+		RootURI: base,
+		WorkspaceFolders: []types.WorkspaceFolder{
+			{URI: base + "/pets", Name: "pets"},
+			{URI: base + "/users", Name: "users"},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both services have a Pet schema, and the users service borrows the one
+	// of the pets service.
+
+	loadFile(base+"/pets/schemas.yaml", `Pet:
+  type: object
+`)(t, &h)
+	loadFile(base+"/pets/api.yaml", `openapi: 3.0.0
+components:
+  schemas:
+    Pet:
+      $ref: ./schemas.yaml#/Pet
+`)(t, &h)
+	loadFile(base+"/users/api.yaml", `openapi: 3.0.0
+components:
+  schemas:
+    Pet:
+      $ref: ../pets/schemas.yaml#/Pet
+`)(t, &h)
+
+	references := func() []string {
+		t.Helper()
+
+		locations, err := h.HandleReferences(referenceParams(base+"/pets/schemas.yaml", "0:0"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var uris []string
+		for _, location := range locations {
+			uris = append(uris, location.URI)
+		}
+
+		return uris
+	}
+
+	if got, want := references(), []string{base + "/pets/api.yaml"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("references = %q, want %q", got, want)
+	}
+
+	// Once the monorepo is opened as a single folder, the services see each
+	// other.
+
+	if err := h.HandleDidChangeWorkspaceFolders(types.DidChangeWorkspaceFoldersParams{
+		Event: types.WorkspaceFoldersChangeEvent{
+			Added: []types.WorkspaceFolder{{URI: base, Name: "monorepo"}},
+			Removed: []types.WorkspaceFolder{
+				{URI: base + "/pets", Name: "pets"},
+				{URI: base + "/users", Name: "users"},
+			},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := references(), []string{base + "/pets/api.yaml", base + "/users/api.yaml"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("references = %q, want %q", got, want)
+	}
+}
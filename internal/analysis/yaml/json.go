@@ -0,0 +1,473 @@
+package yaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ToJSON converts a YAML document to indented JSON, preserving the order of
+// mapping keys. It supports the subset of YAML that Parse understands: block
+// mappings and sequences, flow collections on a single line, quoted and plain
+// scalars, and block scalars.
+func ToJSON(src []byte) ([]byte, error) {
+	document, err := Parse(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+
+	c := converter{document: document}
+
+	for _, line := range strings.Split(strings.TrimSuffix(string(src), "\n"), "\n") {
+		c.lines = append(c.lines, strings.TrimSuffix(line, "\r"))
+	}
+
+	var roots []*Line
+	for _, line := range document.Lines {
+		if line.Empty || line.Parent != nil {
+			continue
+		}
+		if line.Item {
+			return nil, errors.New("a sequence at the root of the document is not supported")
+		}
+		roots = append(roots, line)
+	}
+
+	if err := c.writeMapping(roots); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, c.buf.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+	out.WriteByte('\n')
+
+	return out.Bytes(), nil
+}
+
+type converter struct {
+	document Document
+	lines    []string
+	buf      bytes.Buffer
+}
+
+// writeMapping writes a mapping whose entries are the given key lines.
+func (c *converter) writeMapping(entries []*Line) error {
+	entries = slices.Clone(entries)
+	slices.SortFunc(entries, func(a, b *Line) int {
+		return a.Number - b.Number
+	})
+
+	c.buf.WriteByte('{')
+
+	first := true
+
+	for _, entry := range entries {
+		// Continuation lines of multi-line scalars are part of the value of
+		// their parent.
+		if entry.Key == "" && !entry.Item {
+			continue
+		}
+
+		if !first {
+			c.buf.WriteByte(',')
+		}
+		first = false
+
+		writeString(&c.buf, entry.Key)
+		c.buf.WriteByte(':')
+
+		if err := c.writeValue(entry); err != nil {
+			return err
+		}
+	}
+
+	c.buf.WriteByte('}')
+
+	return nil
+}
+
+// writeValue writes the value of a mapping entry.
+func (c *converter) writeValue(line *Line) error {
+	switch {
+	case line.IsBlockScalar():
+		writeString(&c.buf, c.blockScalar(line))
+		return nil
+	case line.Value != "":
+		return c.writeScalar(line)
+	case len(line.Items) > 0:
+		return c.writeSequence(line.Items)
+	case line.Item:
+		// Entries nested under the first key of a sequence item.
+		var nested []*Line
+		for _, child := range line.Children {
+			if child.Indent > line.KeyRange.Start.Character {
+				nested = append(nested, child)
+			}
+		}
+		if len(nested) == 0 {
+			c.buf.WriteString("null")
+			return nil
+		}
+		return c.writeMapping(nested)
+	case len(line.Children) > 0:
+		return c.writeMapping(mapValues(line.Children))
+	default:
+		c.buf.WriteString("null")
+		return nil
+	}
+}
+
+func (c *converter) writeSequence(items []*Line) error {
+	c.buf.WriteByte('[')
+
+	for i, item := range items {
+		if i > 0 {
+			c.buf.WriteByte(',')
+		}
+
+		if err := c.writeItem(item); err != nil {
+			return err
+		}
+	}
+
+	c.buf.WriteByte(']')
+
+	return nil
+}
+
+func (c *converter) writeItem(item *Line) error {
+	if item.Key == "" {
+		switch {
+		case item.Value != "":
+			return c.writeScalar(item)
+		case len(item.Items) > 0:
+			return c.writeSequence(item.Items)
+		case len(item.Children) > 0:
+			return c.writeMapping(mapValues(item.Children))
+		default:
+			c.buf.WriteString("null")
+			return nil
+		}
+	}
+
+	// The item is a mapping whose first entry is on the item line. The other
+	// entries are aligned with the first key.
+	entries := []*Line{item}
+	for _, child := range item.Children {
+		if child.Indent <= item.KeyRange.Start.Character {
+			entries = append(entries, child)
+		}
+	}
+
+	return c.writeMapping(entries)
+}
+
+// writeScalar writes the value on a line, which may be quoted, plain, or a flow
+// collection.
+func (c *converter) writeScalar(line *Line) error {
+	text := c.lines[line.Number]
+	start := line.ValueRange.Start.Character
+
+	if start > 0 && start <= len(text) {
+		switch text[start-1] {
+		case '"':
+			writeString(&c.buf, unquoteDouble(line.Value))
+			return nil
+		case '\'':
+			writeString(&c.buf, strings.ReplaceAll(line.Value, "''", "'"))
+			return nil
+		}
+	}
+
+	value, _, _ := strings.Cut(line.Value, " #")
+	value = strings.TrimSpace(value)
+
+	if strings.HasPrefix(value, "{") || strings.HasPrefix(value, "[") {
+		p := flowParser{s: value, buf: &c.buf}
+		if err := p.value(); err != nil {
+			return fmt.Errorf("line %d: %w", line.Number+1, err)
+		}
+		return nil
+	}
+
+	// A plain scalar may continue on the following lines, which are folded
+	// into a single line.
+	for i := line.Number + 1; i <= c.document.End(line); i++ {
+		if next := c.document.Lines[i]; !next.Empty && next.Key == "" && !next.Item {
+			value += " " + strings.TrimSpace(c.lines[i])
+		}
+	}
+
+	writePlain(&c.buf, value)
+
+	return nil
+}
+
+// blockScalar returns the contents of a literal or folded block scalar.
+func (c *converter) blockScalar(line *Line) string {
+	header, _, _ := strings.Cut(strings.TrimSpace(line.Value), " #")
+	end := c.document.BlockScalarEnd(line)
+
+	base := -1
+	for i := line.Number + 1; i <= end; i++ {
+		if strings.TrimSpace(c.lines[i]) != "" && (base < 0 || c.document.Lines[i].Indent < base) {
+			base = c.document.Lines[i].Indent
+		}
+	}
+
+	var contents []string
+	for i := line.Number + 1; i <= end; i++ {
+		if len(c.lines[i]) > base {
+			contents = append(contents, c.lines[i][base:])
+		} else {
+			contents = append(contents, "")
+		}
+	}
+
+	var result string
+	if header[0] == '>' {
+		result = fold(contents)
+	} else {
+		result = strings.Join(contents, "\n")
+	}
+
+	switch {
+	case strings.Contains(header, "-"):
+		return strings.TrimRight(result, "\n")
+	case strings.Contains(header, "+"):
+		return result + "\n"
+	default:
+		return strings.TrimRight(result, "\n") + "\n"
+	}
+}
+
+// fold joins the lines of a folded block scalar. Lines are joined with spaces,
+// and each blank line becomes a newline. More indented lines keep their line
+// breaks.
+func fold(lines []string) string {
+	var b strings.Builder
+
+	for i, line := range lines {
+		if line == "" {
+			b.WriteByte('\n')
+			continue
+		}
+
+		if i > 0 && lines[i-1] != "" {
+			if strings.HasPrefix(line, " ") || strings.HasPrefix(lines[i-1], " ") {
+				b.WriteByte('\n')
+			} else {
+				b.WriteByte(' ')
+			}
+		}
+
+		b.WriteString(line)
+	}
+
+	return b.String()
+}
+
+func mapValues(m map[string]*Line) []*Line {
+	result := make([]*Line, 0, len(m))
+	for _, line := range m {
+		result = append(result, line)
+	}
+	return result
+}
+
+func unquoteDouble(s string) string {
+	if unquoted, err := strconv.Unquote(`"` + s + `"`); err == nil {
+		return unquoted
+	}
+
+	return s
+}
+
+var (
+	intRegexp   = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	floatRegexp = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+)
+
+// writePlain writes a plain scalar, resolving it to null, a boolean, a number,
+// or a string like the YAML core schema.
+func writePlain(buf *bytes.Buffer, value string) {
+	switch value {
+	case "", "~", "null", "Null", "NULL":
+		buf.WriteString("null")
+		return
+	case "true", "True", "TRUE":
+		buf.WriteString("true")
+		return
+	case "false", "False", "FALSE":
+		buf.WriteString("false")
+		return
+	}
+
+	if intRegexp.MatchString(value) {
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			buf.WriteString(strconv.FormatInt(n, 10))
+			return
+		}
+	}
+
+	if floatRegexp.MatchString(value) {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			buf.WriteString(strconv.FormatFloat(f, 'g', -1, 64))
+			return
+		}
+	}
+
+	writeString(buf, value)
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(s)
+
+	// Remove the newline that Encode adds.
+	buf.Truncate(buf.Len() - 1)
+}
+
+// flowParser converts a flow collection, such as {a: 1, b: [x, y]}, to JSON.
+type flowParser struct {
+	s   string
+	pos int
+	buf *bytes.Buffer
+}
+
+func (p *flowParser) skipSpaces() {
+	for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *flowParser) value() error {
+	p.skipSpaces()
+
+	if p.pos >= len(p.s) {
+		return errors.New("unexpected end of flow collection")
+	}
+
+	switch p.s[p.pos] {
+	case '{':
+		return p.collection('}')
+	case '[':
+		return p.collection(']')
+	case '"', '\'':
+		s, err := p.quoted()
+		if err != nil {
+			return err
+		}
+		writeString(p.buf, s)
+		return nil
+	default:
+		writePlain(p.buf, p.plain(",]}"))
+		return nil
+	}
+}
+
+// collection parses a flow mapping or sequence, which ends with the given
+// closing bracket.
+func (p *flowParser) collection(closing byte) error {
+	mapping := closing == '}'
+
+	p.buf.WriteByte(p.s[p.pos])
+	p.pos++
+
+	for i := 0; ; i++ {
+		p.skipSpaces()
+
+		if p.pos < len(p.s) && p.s[p.pos] == closing {
+			p.pos++
+			p.buf.WriteByte(closing)
+			return nil
+		}
+
+		if i > 0 {
+			if p.pos >= len(p.s) || p.s[p.pos] != ',' {
+				return fmt.Errorf("expected ',' or %q in flow collection", closing)
+			}
+			p.pos++
+			p.buf.WriteByte(',')
+			p.skipSpaces()
+		}
+
+		if mapping {
+			if err := p.key(); err != nil {
+				return err
+			}
+		}
+
+		if err := p.value(); err != nil {
+			return err
+		}
+	}
+}
+
+// key parses the key of a flow mapping entry and the colon after it.
+func (p *flowParser) key() error {
+	var key string
+
+	if p.pos < len(p.s) && (p.s[p.pos] == '"' || p.s[p.pos] == '\'') {
+		s, err := p.quoted()
+		if err != nil {
+			return err
+		}
+		key = s
+	} else {
+		key = p.plain(":,}")
+	}
+
+	p.skipSpaces()
+
+	if p.pos >= len(p.s) || p.s[p.pos] != ':' {
+		return errors.New("expected ':' in flow mapping")
+	}
+	p.pos++
+
+	writeString(p.buf, key)
+	p.buf.WriteByte(':')
+
+	return nil
+}
+
+func (p *flowParser) quoted() (string, error) {
+	q := p.s[p.pos]
+	start := p.pos + 1
+
+	for i := start; i < len(p.s); i++ {
+		switch {
+		case q == '"' && p.s[i] == '\\':
+			i++
+		case p.s[i] == q && q == '\'' && i+1 < len(p.s) && p.s[i+1] == '\'':
+			i++
+		case p.s[i] == q:
+			p.pos = i + 1
+			if q == '"' {
+				return unquoteDouble(p.s[start:i]), nil
+			}
+			return strings.ReplaceAll(p.s[start:i], "''", "'"), nil
+		}
+	}
+
+	return "", errors.New("unterminated quoted string in flow collection")
+}
+
+// plain parses a plain scalar up to any of the given terminators.
+func (p *flowParser) plain(terminators string) string {
+	start := p.pos
+
+	for p.pos < len(p.s) && !strings.ContainsRune(terminators, rune(p.s[p.pos])) {
+		p.pos++
+	}
+
+	return strings.TrimSpace(p.s[start:p.pos])
+}
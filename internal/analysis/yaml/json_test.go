@@ -0,0 +1,140 @@
+package yaml_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	. "github.com/armsnyder/openapi-language-server/internal/analysis/yaml"
+)
+
+func TestToJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "mapping order",
+			src: `b: 1
+a: 2
+c:
+  z: x
+  y: "y"
+`,
+			want: `{"b":1,"a":2,"c":{"z":"x","y":"y"}}`,
+		},
+		{
+			name: "scalars",
+			src: `null: ~
+empty:
+bool: true
+int: 42
+negative: -7
+float: 1.5
+string: hello world # comment
+version: 3.0.0
+double: "a \"quoted\" é"
+single: 'it''s'
+code: "200"
+`,
+			want: `{"null":null,"empty":null,"bool":true,"int":42,"negative":-7,"float":1.5,"string":"hello world","version":"3.0.0","double":"a \"quoted\" é","single":"it's","code":"200"}`,
+		},
+		{
+			name: "sequences",
+			src: `tags:
+  - a
+  - b
+parameters:
+  - name: limit
+    in: query
+    schema:
+      type: integer
+  - $ref: "#/components/parameters/Offset"
+compact:
+- x
+`,
+			want: `{"tags":["a","b"],"parameters":[{"name":"limit","in":"query","schema":{"type":"integer"}},{"$ref":"#/components/parameters/Offset"}],"compact":["x"]}`,
+		},
+		{
+			name: "flow collections",
+			src: `empty: {}
+list: [a, "b, c", 1]
+nested: {a: [1, 2], 'b': {c: null}}
+`,
+			want: `{"empty":{},"list":["a","b, c",1],"nested":{"a":[1,2],"b":{"c":null}}}`,
+		},
+		{
+			name: "block scalars",
+			src: `literal: |
+  line one
+    indented
+
+  # not a comment
+folded: >-
+  one
+  two
+
+  three
+plain: one
+  two
+`,
+			want: `{"literal":"line one\n  indented\n\n# not a comment\n","folded":"one two\nthree","plain":"one two"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToJSON([]byte(tt.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var compact map[string]any
+			if err := json.Unmarshal(got, &compact); err != nil {
+				t.Fatalf("invalid JSON: %v\n%s", err, got)
+			}
+
+			if want := indentJSON(t, tt.want); string(got) != want {
+				t.Errorf("ToJSON() =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestToJSON_PetStore(t *testing.T) {
+	src, err := os.ReadFile("testdata/petstore.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ToJSON(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var spec struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(got, &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.OpenAPI != "3.0.2" || len(spec.Paths["/pet"]) != 2 {
+		t.Errorf("unexpected spec: openapi %q, /pet %v", spec.OpenAPI, spec.Paths["/pet"])
+	}
+}
+
+func indentJSON(t *testing.T, s string) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(s), "", "  "); err != nil {
+		t.Fatal(err)
+	}
+	buf.WriteByte('\n')
+
+	return buf.String()
+}
@@ -0,0 +1,454 @@
+package yaml
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+// jsonCollection is an object or array that is open while parsing JSON.
+type jsonCollection struct {
+	// owner is the line whose children are the entries of the collection, or
+	// nil for a collection at the root of the document.
+	owner *Line
+	array bool
+}
+
+// ParseJSON parses a JSON document from a reader into the same line-based
+// tree as Parse. Each line holds the first key or array element that starts on
+// it, and lines that only close collections are empty. Compact JSON can have
+// several keys on a line, of which the ones after the first are only in the
+// tree and in Nodes. JSON that is not valid, as while it is being edited, is
+// parsed line by line using best-effort instead.
+func ParseJSON(r io.Reader) (Document, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return Document{}, err
+	}
+
+	if document, err := parseJSONTokens(src); err == nil {
+		return document, nil
+	}
+
+	return parseJSONLines(bytes.NewReader(src))
+}
+
+// jsonParser builds a document from the tokens of a JSON decoder, using the
+// offsets of the tokens to find their positions.
+type jsonParser struct {
+	src        []byte
+	decoder    *json.Decoder
+	lineStarts []int
+	document   Document
+
+	// extra are the keys and array elements that do not start their line.
+	extra []*Line
+}
+
+func parseJSONTokens(src []byte) (Document, error) {
+	p := jsonParser{
+		src:        src,
+		decoder:    json.NewDecoder(bytes.NewReader(src)),
+		lineStarts: []int{0},
+		document: Document{
+			Root: map[string]*Line{},
+		},
+	}
+
+	for i, ch := range src {
+		if ch == '\n' {
+			p.lineStarts = append(p.lineStarts, i+1)
+		}
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(src))
+	scanner.Buffer(nil, 10*1024*1024)
+
+	for lineNum := 0; scanner.Scan(); lineNum++ {
+		s := scanner.Bytes()
+		indent := bytes.IndexFunc(s, func(ch rune) bool {
+			return ch != ' ' && ch != '\t'
+		})
+		if indent == -1 {
+			indent = len(s)
+		}
+		p.document.Lines = append(p.document.Lines, &Line{Number: lineNum, Indent: indent, Empty: true})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return Document{}, err
+	}
+
+	tok, start, end, err := p.token()
+	if err != nil {
+		return Document{}, err
+	}
+
+	if err := p.value(nil, tok, start, end); err != nil {
+		return Document{}, err
+	}
+
+	if _, err := p.decoder.Token(); err != io.EOF {
+		return Document{}, errors.New("unexpected data after the JSON value")
+	}
+
+	if len(p.extra) > 0 {
+		p.document.nodes = make([]*Line, 0, len(p.document.Lines)+len(p.extra))
+
+		for _, line := range p.document.Lines {
+			p.document.nodes = append(p.document.nodes, line)
+
+			for len(p.extra) > 0 && p.extra[0].Number == line.Number {
+				p.document.nodes = append(p.document.nodes, p.extra[0])
+				p.extra = p.extra[1:]
+			}
+		}
+	}
+
+	return p.document, nil
+}
+
+// token returns the next token, together with the offsets of its start and
+// end.
+func (p *jsonParser) token() (tok json.Token, start, end int, err error) {
+	start = int(p.decoder.InputOffset())
+	for start < len(p.src) && strings.IndexByte(" \t\r\n,:", p.src[start]) != -1 {
+		start++
+	}
+
+	tok, err = p.decoder.Token()
+
+	return tok, start, int(p.decoder.InputOffset()), err
+}
+
+// value sets the value of a line from the value that starts with the given
+// token, or adds the entries of an object or array as its children. The line
+// is nil for the value at the root of the document.
+func (p *jsonParser) value(line *Line, tok json.Token, start, end int) error {
+	switch tok {
+	case json.Delim('{'):
+		return p.object(line, start)
+	case json.Delim('['):
+		return p.array(line, start)
+	}
+
+	if line == nil {
+		return nil
+	}
+
+	if s, ok := tok.(string); ok {
+		line.Value = s
+		line.ValueRange = p.rangeOf(start+1, end-1)
+	} else {
+		line.Value = string(p.src[start:end])
+		line.ValueRange = p.rangeOf(start, end)
+	}
+
+	return nil
+}
+
+func (p *jsonParser) object(parent *Line, open int) error {
+	for empty := true; ; empty = false {
+		tok, start, end, err := p.token()
+		if err != nil {
+			return err
+		}
+
+		if tok == json.Delim('}') {
+			if empty {
+				p.setRaw(parent, open, end)
+			}
+			return nil
+		}
+
+		key, _ := tok.(string)
+		line := p.add(parent, start, key, false)
+		line.KeyRange = p.rangeOf(start+1, end-1)
+
+		if tok, start, end, err = p.token(); err != nil {
+			return err
+		}
+
+		if err := p.value(line, tok, start, end); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *jsonParser) array(parent *Line, open int) error {
+	for empty := true; ; empty = false {
+		tok, start, end, err := p.token()
+		if err != nil {
+			return err
+		}
+
+		if tok == json.Delim(']') {
+			if empty {
+				p.setRaw(parent, open, end)
+			}
+			return nil
+		}
+
+		if err := p.value(p.add(parent, start, "", true), tok, start, end); err != nil {
+			return err
+		}
+	}
+}
+
+// setRaw keeps an empty object or array as the raw value of a line, like a
+// flow collection in YAML.
+func (p *jsonParser) setRaw(line *Line, start, end int) {
+	if line != nil {
+		line.Value = string(p.src[start:end])
+		line.ValueRange = p.rangeOf(start, end)
+	}
+}
+
+// add adds a key or array element that starts at the given offset to the
+// document. The first one on a line takes the place of the line, and the
+// others are indented by their column.
+func (p *jsonParser) add(parent *Line, offset int, key string, item bool) *Line {
+	pos := p.position(offset)
+	line := &Line{Key: key, Number: pos.Line, Indent: pos.Character, Item: item}
+
+	if first := p.document.Lines[pos.Line]; first.Empty {
+		line.Indent = first.Indent
+		p.document.Lines[pos.Line] = line
+	} else {
+		p.extra = append(p.extra, line)
+	}
+
+	switch {
+	case parent != nil:
+		addChild(parent, line)
+	case !item:
+		p.document.Root[key] = line
+	}
+
+	return line
+}
+
+func (p *jsonParser) position(offset int) types.Position {
+	line := sort.SearchInts(p.lineStarts, offset+1) - 1
+
+	return types.Position{Line: line, Character: offset - p.lineStarts[line]}
+}
+
+func (p *jsonParser) rangeOf(start, end int) types.Range {
+	return types.Range{Start: p.position(start), End: p.position(end)}
+}
+
+// parseJSONLines parses a JSON document line by line into the same line-based
+// tree as Parse, using best-effort. Each line holds the first key or array
+// element that starts on it, and lines that only close collections are empty.
+// An object or array that is closed on the same line that opens it is kept as
+// the raw value of the line, like a flow collection in YAML.
+func parseJSONLines(r io.Reader) (Document, error) {
+	var stack []jsonCollection
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 10*1024*1024)
+	document := Document{
+		Root: map[string]*Line{},
+	}
+
+	for lineNum := 0; scanner.Scan(); lineNum++ {
+		s := scanner.Bytes()
+		line := &Line{Number: lineNum}
+		document.Lines = append(document.Lines, line)
+
+		pos := bytes.IndexFunc(s, func(ch rune) bool {
+			return ch != ' ' && ch != '\t'
+		})
+		if pos == -1 {
+			line.Empty = true
+			line.Indent = len(s)
+			continue
+		}
+		line.Indent = pos
+
+		var parent *jsonCollection
+		if len(stack) > 0 {
+			parent = &stack[len(stack)-1]
+		}
+
+		switch {
+		case parent != nil && !parent.array && s[pos] == '"':
+			end := jsonStringEnd(s, pos)
+			if end == -1 {
+				line.Empty = true
+				continue
+			}
+
+			line.Key = jsonString(s[pos : end+1])
+			line.KeyRange = types.Range{
+				Start: types.Position{Line: lineNum, Character: pos + 1},
+				End:   types.Position{Line: lineNum, Character: end},
+			}
+
+			pos = skipJSONSpaces(s, end+1)
+			if pos < len(s) && s[pos] == ':' {
+				pos = skipJSONSpaces(s, pos+1)
+			}
+		case parent != nil && parent.array && s[pos] != ']' && s[pos] != '}':
+			line.Item = true
+		default:
+			line.Empty = true
+		}
+
+		if !line.Empty {
+			switch {
+			case parent.owner != nil:
+				addChild(parent.owner, line)
+			case !line.Item:
+				document.Root[line.Key] = line
+			}
+
+			pos = parseJSONValue(line, s, pos, lineNum)
+		}
+
+		// Track the collections that are opened or closed on the rest of the
+		// line.
+		for ; pos < len(s); pos++ {
+			switch s[pos] {
+			case '"':
+				if end := jsonStringEnd(s, pos); end != -1 {
+					pos = end
+				}
+			case '{', '[':
+				owner := line
+				if line.Empty {
+					owner = nil
+					if parent != nil {
+						owner = parent.owner
+					}
+				}
+				stack = append(stack, jsonCollection{owner: owner, array: s[pos] == '['})
+			case '}', ']':
+				if len(stack) > 0 {
+					stack = stack[:len(stack)-1]
+				}
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return Document{}, err
+	}
+
+	return document, nil
+}
+
+// parseJSONValue sets the value of a line from the value that starts at pos,
+// and returns the position after it. The value of a collection that does not
+// close on the same line is left empty, and its position is returned.
+func parseJSONValue(line *Line, s []byte, pos, lineNum int) int {
+	if pos >= len(s) {
+		return pos
+	}
+
+	start, end := pos, pos
+
+	switch s[pos] {
+	case '"':
+		closing := jsonStringEnd(s, pos)
+		if closing == -1 {
+			return len(s)
+		}
+
+		line.Value = jsonString(s[pos : closing+1])
+		line.ValueRange = types.Range{
+			Start: types.Position{Line: lineNum, Character: pos + 1},
+			End:   types.Position{Line: lineNum, Character: closing},
+		}
+
+		return closing + 1
+	case '{', '[':
+		closing := jsonCollectionEnd(s, pos)
+		if closing == -1 {
+			return pos
+		}
+		end = closing + 1
+	default:
+		for end < len(s) && strings.IndexByte(",}] \t", s[end]) == -1 {
+			end++
+		}
+	}
+
+	line.Value = string(s[start:end])
+	line.ValueRange = types.Range{
+		Start: types.Position{Line: lineNum, Character: start},
+		End:   types.Position{Line: lineNum, Character: end},
+	}
+
+	return end
+}
+
+// jsonStringEnd returns the index of the quote that closes the string that
+// starts at pos, or -1 if it does not close on the line.
+func jsonStringEnd(s []byte, pos int) int {
+	for i := pos + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+
+	return -1
+}
+
+// jsonCollectionEnd returns the index of the bracket that closes the object
+// or array that starts at pos, or -1 if it does not close on the line.
+func jsonCollectionEnd(s []byte, pos int) int {
+	depth := 0
+
+	for i := pos; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			end := jsonStringEnd(s, i)
+			if end == -1 {
+				return -1
+			}
+			i = end
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// jsonString decodes a quoted JSON string. Invalid escapes are kept as they
+// are.
+func jsonString(quoted []byte) string {
+	if bytes.IndexByte(quoted, '\\') == -1 {
+		return string(quoted[1 : len(quoted)-1])
+	}
+
+	var s string
+	if err := json.Unmarshal(quoted, &s); err != nil {
+		return string(quoted[1 : len(quoted)-1])
+	}
+
+	return s
+}
+
+func skipJSONSpaces(s []byte, pos int) int {
+	for pos < len(s) && (s[pos] == ' ' || s[pos] == '\t') {
+		pos++
+	}
+
+	return pos
+}
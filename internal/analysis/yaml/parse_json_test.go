@@ -0,0 +1,251 @@
+package yaml_test
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/armsnyder/openapi-language-server/internal/analysis/yaml"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+func TestParseJSON(t *testing.T) {
+	document, err := ParseJSON(strings.NewReader(`{
+  "openapi": "3.0.0",
+  "paths": {
+    "/pets/{id}": {
+      "parameters": [
+        {
+          "name": "id",
+          "in": "path"
+        },
+        { "$ref": "#/components/parameters/Limit" }
+      ],
+      "get": { "tags": ["pets"], "summary": "a \"quoted\" {value}" }
+    }
+  },
+  "components": {}
+}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(document.Lines) != 16 {
+		t.Fatalf("got %d lines, want 16", len(document.Lines))
+	}
+
+	for _, n := range []int{0, 8, 10, 13, 15} {
+		if !document.Lines[n].Empty {
+			t.Errorf("line %d is not empty", n)
+		}
+	}
+
+	if got := len(document.Root); got != 3 {
+		t.Errorf("got %d root keys, want 3", got)
+	}
+
+	if line := document.Root["openapi"]; line == nil || line.Value != "3.0.0" || line.Parent != nil {
+		t.Errorf("openapi = %+v", line)
+	}
+
+	name := document.Locate("#/paths/~1pets~1{id}/parameters/0/name")
+	if name == nil {
+		t.Fatal("could not locate the name of the first parameter")
+	}
+
+	wantKeyRange := types.Range{Start: types.Position{Line: 6, Character: 11}, End: types.Position{Line: 6, Character: 15}}
+	wantValueRange := types.Range{Start: types.Position{Line: 6, Character: 19}, End: types.Position{Line: 6, Character: 21}}
+
+	if name.Value != "id" || name.KeyRange != wantKeyRange || name.ValueRange != wantValueRange {
+		t.Errorf("name = %q %v %v, want %q %v %v", name.Value, name.KeyRange, name.ValueRange, "id", wantKeyRange, wantValueRange)
+	}
+
+	parameters := document.Locate("#/paths/~1pets~1{id}/parameters")
+	if parameters == nil || len(parameters.Items) != 2 {
+		t.Fatalf("parameters = %+v, want 2 items", parameters)
+	}
+
+	ref := document.Locate("#/paths/~1pets~1{id}/parameters/1/$ref")
+	wantRefRange := types.Range{Start: types.Position{Line: 9, Character: 19}, End: types.Position{Line: 9, Character: 48}}
+
+	if ref == nil || ref.Value != "#/components/parameters/Limit" || ref.ValueRange != wantRefRange {
+		t.Errorf("inline $ref = %+v, want %q at %v", ref, "#/components/parameters/Limit", wantRefRange)
+	}
+
+	if summary := document.Locate("#/paths/~1pets~1{id}/get/summary"); summary == nil || summary.Value != `a "quoted" {value}` {
+		t.Errorf("summary = %+v, want the decoded string", summary)
+	}
+
+	if tag := document.Locate("#/paths/~1pets~1{id}/get/tags/0"); tag == nil || tag.Value != "pets" {
+		t.Errorf("tag = %+v", tag)
+	}
+
+	if components := document.Root["components"]; components == nil || components.Value != "{}" {
+		t.Errorf("components = %+v", components)
+	}
+}
+
+func TestParseJSON_Minified(t *testing.T) {
+	src := `{"paths":{"/pets":{"get":{"responses":{"200":{"$ref":"#/components/responses/Pets"}}}}},` +
+		`"components":{"schemas":{"Pet":{"type":"object"},"Pets":{"type":"array","items":{"$ref":"#/components/schemas/Pet"}}}}}`
+
+	document, err := ParseJSON(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(document.Lines) != 1 || document.Lines[0].Key != "paths" {
+		t.Fatalf("lines = %+v, want a single line that holds the first key", document.Lines)
+	}
+
+	pet := document.Locate("#/components/schemas/Pet")
+	if pet == nil || pet.Key != "Pet" || pet.Number != 0 {
+		t.Fatalf("Pet = %+v", pet)
+	}
+
+	if start := strings.Index(src, `"Pet"`) + 1; pet.KeyRange.Start.Character != start || pet.KeyRange.End.Character != start+3 {
+		t.Errorf("Pet key range = %v, want it to start at %d", pet.KeyRange, start)
+	}
+
+	ref := document.Locate("#/components/schemas/Pets/items/$ref")
+	if ref == nil || ref.Value != "#/components/schemas/Pet" {
+		t.Fatalf("items $ref = %+v", ref)
+	}
+
+	var refs []string
+	for _, line := range document.Nodes() {
+		if line.Key == "$ref" {
+			refs = append(refs, line.Value)
+		}
+	}
+
+	if want := []string{"#/components/responses/Pets", "#/components/schemas/Pet"}; !reflect.DeepEqual(refs, want) {
+		t.Errorf("refs in Nodes = %q, want %q", refs, want)
+	}
+
+	if got := document.At(ref.ValueRange.Start); got != ref {
+		t.Errorf("At(%v) = %+v, want the items $ref", ref.ValueRange.Start, got)
+	}
+
+	if got := document.At(types.Position{Line: 0, Character: 1}); got != document.Lines[0] {
+		t.Errorf("At(0:1) = %+v, want the first key", got)
+	}
+}
+
+func TestParseJSON_Compact(t *testing.T) {
+	document, err := ParseJSON(strings.NewReader(`{
+  "components": {"schemas": {"Pet": {"type": "object"}}},
+  "paths": {"/pets": {"get": {"responses": {"200": {"$ref": "#/components/responses/Pet"}}}}}
+}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(document.Lines) != 4 || document.Lines[1].Key != "components" || document.Lines[2].Key != "paths" {
+		t.Fatalf("lines = %+v, want one line for each root key", document.Lines)
+	}
+
+	if pet := document.Locate("#/components/schemas/Pet"); pet == nil || pet.Number != 1 || pet.KeyRange.Start.Character != 30 {
+		t.Errorf("Pet = %+v", pet)
+	}
+
+	ref := document.Locate("#/paths/~1pets/get/responses/200/$ref")
+	if ref == nil || ref.Number != 2 || ref.Value != "#/components/responses/Pet" {
+		t.Fatalf("$ref = %+v", ref)
+	}
+
+	if got := document.At(ref.KeyRange.Start); got != ref {
+		t.Errorf("At(%v) = %+v, want the $ref", ref.KeyRange.Start, got)
+	}
+
+	if got := document.Nodes(); len(got) != 12 {
+		t.Errorf("got %d nodes, want 12", len(got))
+	}
+}
+
+func TestParseJSON_Invalid(t *testing.T) {
+	// While a document is edited it is often not valid, so it falls back to
+	// parsing line by line.
+	document, err := ParseJSON(strings.NewReader(`{
+  "openapi": "3.0.0",
+  "paths": {
+    "/pets": {,
+      "get": {}
+    }
+  }
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if get := document.Locate("#/paths/~1pets/get"); get == nil || get.Value != "{}" {
+		t.Errorf("get = %+v", get)
+	}
+}
+
+// TestParseJSON_PetStore checks that every key of a YAML document can be
+// found in its JSON conversion.
+func TestParseJSON_PetStore(t *testing.T) {
+	src, err := os.ReadFile("testdata/petstore.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	converted, err := ToJSON(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := Parse(bytes.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ParseJSON(bytes.NewReader(converted))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range want.Lines {
+		if line.Empty || line.Key == "" || inBlockScalar(line) {
+			continue
+		}
+
+		found := got.Locate(line.KeyRef())
+		if found == nil {
+			t.Errorf("could not locate %s", line.KeyRef())
+			continue
+		}
+
+		// In YAML the first entry of a sequence item is on the item line, but
+		// in JSON the item starts with a line of its own.
+		if line.Item {
+			if !found.Item || found.Field(line.Key) == nil {
+				t.Errorf("%s: got %+v, want an item with %q", line.KeyRef(), found, line.Key)
+			}
+			continue
+		}
+
+		if found.Key != line.Key {
+			t.Errorf("%s: got key %q, want %q", line.KeyRef(), found.Key, line.Key)
+		}
+
+		if line.Key == "$ref" && found.Value != line.Value {
+			t.Errorf("%s: got value %q, want %q", line.KeyRef(), found.Value, line.Value)
+		}
+	}
+}
+
+func inBlockScalar(line *Line) bool {
+	for cur := line.Parent; cur != nil; cur = cur.Parent {
+		if cur.IsBlockScalar() {
+			return true
+		}
+	}
+
+	return false
+}
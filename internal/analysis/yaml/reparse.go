@@ -0,0 +1,147 @@
+package yaml
+
+import (
+	"bytes"
+
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+)
+
+// Text is the content of a document, by line.
+type Text interface {
+	// Bytes returns the whole content.
+	Bytes() []byte
+
+	// LineCount returns the number of lines, including an empty last line
+	// after a final line break.
+	LineCount() int
+
+	// Line returns the content of a line, without the line break.
+	Line(line int) []byte
+}
+
+// Reparse updates the document after an edit, re-parsing only the subtree
+// that contains the edited lines. text is the content after the edit, the edit
+// replaced the lines from start to end (inclusive) of the previous content,
+// and the replacement ends on line newEnd. The result is the same as parsing
+// the text from scratch.
+//
+// The lines of the previous document are reused, so it must not be used
+// after calling Reparse.
+func (s Document) Reparse(text Text, start, end, newEnd int) (Document, error) {
+	delta := newEnd - end
+
+	if start < 0 || end < start || newEnd < start || countLines(text) != len(s.Lines)+delta {
+		return Parse(bytes.NewReader(text.Bytes()))
+	}
+
+	// The anchor is a line before the edit whose subtree contains every line
+	// that was edited. Start with the deepest one, and move up whenever the
+	// new lines do not fit below it.
+	for anchor := s.anchor(start, end); anchor != nil; anchor = anchor.Parent {
+		if document, ok := s.reparseBelow(anchor, text, end, delta); ok {
+			return document, nil
+		}
+	}
+
+	return Parse(bytes.NewReader(text.Bytes()))
+}
+
+// anchor returns the deepest line before the start of an edit whose subtree
+// contains all non-empty lines within the edit, or nil if there is none.
+func (s Document) anchor(start, end int) *Line {
+	var anchor *Line
+
+	for i := min(start, len(s.Lines)) - 1; i >= 0; i-- {
+		if !s.Lines[i].Empty {
+			anchor = s.Lines[i]
+			break
+		}
+	}
+
+	for i := start; i <= end && i < len(s.Lines) && anchor != nil; i++ {
+		for anchor != nil && !s.Lines[i].Empty && !s.Lines[i].IsDescendantOf(anchor) {
+			anchor = anchor.Parent
+		}
+	}
+
+	return anchor
+}
+
+// reparseBelow replaces the subtree of the anchor with the lines parsed from
+// the new text. It returns false, leaving the document in an unusable state,
+// if one of the new lines would not be nested below the anchor, since that
+// would change the structure of the lines that follow.
+func (s Document) reparseBelow(anchor *Line, text Text, end, delta int) (Document, bool) {
+	first := anchor.Number + 1
+	last := min(max(s.End(anchor), end), len(s.Lines)-1)
+
+	// Start with the same parents as a full parse would have at the anchor.
+	var stack []lineWithIndent
+	for cur := anchor; cur != nil; cur = cur.Parent {
+		stack = append([]lineWithIndent{{line: cur, indent: cur.Indent}}, stack...)
+	}
+	base := len(stack)
+
+	if last+delta < first-1 {
+		return Document{}, false
+	}
+
+	anchor.Children = nil
+	anchor.Items = nil
+
+	region := make([]*Line, 0, last+delta-first+1)
+
+	for lineNum := first; lineNum <= last+delta; lineNum++ {
+		line := parseLine(bytes.TrimSuffix(text.Line(lineNum), []byte{'\r'}), lineNum)
+		region = append(region, line.line)
+
+		if line.line.Empty {
+			continue
+		}
+
+		for !isParentOf(stack[len(stack)-1], line) {
+			if len(stack) == base {
+				return Document{}, false
+			}
+			stack = stack[:len(stack)-1]
+		}
+
+		addChild(stack[len(stack)-1].line, line.line)
+		stack = append(stack, line)
+	}
+
+	lines := make([]*Line, 0, len(s.Lines)+delta)
+	lines = append(lines, s.Lines[:first]...)
+	lines = append(lines, region...)
+
+	for _, line := range s.Lines[last+1:] {
+		if delta != 0 {
+			line.Number += delta
+			shiftRange(&line.KeyRange, delta)
+			shiftRange(&line.ValueRange, delta)
+		}
+		lines = append(lines, line)
+	}
+
+	return Document{Lines: lines, Root: s.Root}, true
+}
+
+// shiftRange moves a range by a number of lines, unless the line has no such
+// range.
+func shiftRange(r *types.Range, delta int) {
+	if *r != (types.Range{}) {
+		r.Start.Line += delta
+		r.End.Line += delta
+	}
+}
+
+// countLines returns the number of lines that Parse reads from the text,
+// which does not include an empty last line.
+func countLines(text Text) int {
+	n := text.LineCount()
+	if n > 0 && len(text.Line(n-1)) == 0 {
+		n--
+	}
+
+	return n
+}
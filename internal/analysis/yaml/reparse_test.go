@@ -0,0 +1,245 @@
+package yaml_test
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/armsnyder/openapi-language-server/internal/analysis/yaml"
+)
+
+// edit replaces the text between two positions, given as line and column.
+type edit struct {
+	startLine, startChar int
+	endLine, endChar     int
+	text                 string
+}
+
+// apply returns the text after the edit. Positions past the end of a line are
+// clamped to its end.
+func (e edit) apply(src string) string {
+	return src[:offset(src, e.startLine, e.startChar)] + e.text + src[offset(src, e.endLine, e.endChar):]
+}
+
+func offset(src string, line, char int) int {
+	start := 0
+	for ; line > 0; line-- {
+		i := strings.IndexByte(src[start:], '\n')
+		if i < 0 {
+			return len(src)
+		}
+		start += i + 1
+	}
+
+	end := strings.IndexByte(src[start:], '\n')
+	if end < 0 {
+		end = len(src) - start
+	}
+
+	return start + min(char, end)
+}
+
+// text implements Text with the lines of a string.
+type text []string
+
+func newText(s string) text {
+	return strings.Split(s, "\n")
+}
+
+func (t text) Bytes() []byte {
+	return []byte(strings.Join(t, "\n"))
+}
+
+func (t text) LineCount() int {
+	return len(t)
+}
+
+func (t text) Line(line int) []byte {
+	return []byte(t[line])
+}
+
+// sameLine returns true if two lines have the same contents and the same
+// position in the tree of their documents.
+func sameLine(a, b *Line) bool {
+	if a.Number != b.Number || number(a.Parent) != number(b.Parent) || a.Key != b.Key || a.Value != b.Value ||
+		a.KeyRange != b.KeyRange || a.ValueRange != b.ValueRange || a.Indent != b.Indent ||
+		a.Item != b.Item || a.Empty != b.Empty || a.Comment != b.Comment ||
+		len(a.Children) != len(b.Children) || len(a.Items) != len(b.Items) {
+		return false
+	}
+
+	for key, child := range a.Children {
+		if b.Children[key] == nil || b.Children[key].Number != child.Number {
+			return false
+		}
+	}
+
+	for i, item := range a.Items {
+		if b.Items[i].Number != item.Number {
+			return false
+		}
+	}
+
+	return true
+}
+
+func describeLine(line *Line) string {
+	return fmt.Sprintf("number=%d parent=%d key=%q value=%q keyRange=%v valueRange=%v indent=%d item=%t empty=%t comment=%t children=%d items=%d",
+		line.Number, number(line.Parent), line.Key, line.Value, line.KeyRange, line.ValueRange, line.Indent, line.Item, line.Empty, line.Comment, len(line.Children), len(line.Items))
+}
+
+func number(line *Line) int {
+	if line == nil {
+		return -1
+	}
+	return line.Number
+}
+
+// checkReparse applies an edit to a document and checks that the result of
+// Reparse matches a full parse of the new text.
+func checkReparse(t *testing.T, document Document, src string, e edit) (Document, string) {
+	t.Helper()
+
+	newSrc := e.apply(src)
+	newEnd := e.startLine + strings.Count(e.text, "\n")
+
+	got, err := document.Reparse(newText(newSrc), e.startLine, e.endLine, newEnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := Parse(strings.NewReader(newSrc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got.Lines) != len(want.Lines) {
+		t.Fatalf("after %+v: got %d lines, want %d", e, len(got.Lines), len(want.Lines))
+	}
+
+	for i := range want.Lines {
+		if !sameLine(got.Lines[i], want.Lines[i]) {
+			t.Fatalf("after %+v: line %d:\ngot  %s\nwant %s", e, i, describeLine(got.Lines[i]), describeLine(want.Lines[i]))
+		}
+	}
+
+	if len(got.Root) != len(want.Root) {
+		t.Fatalf("after %+v: got %d root keys, want %d", e, len(got.Root), len(want.Root))
+	}
+
+	for key, line := range want.Root {
+		if got.Root[key] == nil || got.Root[key].Number != line.Number {
+			t.Fatalf("after %+v: root key %q is not on line %d", e, key, line.Number)
+		}
+	}
+
+	return got, newSrc
+}
+
+func TestDocument_Reparse(t *testing.T) {
+	src := `openapi: 3.0.0
+paths:
+  /pets:
+    get:
+      parameters:
+        - name: limit
+          in: query
+
+      responses:
+        "200":
+          description: OK
+components:
+  schemas:
+    Pet:
+      type: object
+`
+
+	tests := []struct {
+		name string
+		edit edit
+	}{
+		{"change a value", edit{6, 14, 6, 19, "path"}},
+		{"insert a sibling", edit{6, 19, 6, 19, "\n          required: true"}},
+		{"insert a child", edit{10, 22, 10, 22, "\n          content: {}"}},
+		{"insert an item", edit{6, 19, 6, 19, "\n        - name: offset"}},
+		{"dedent a line", edit{9, 0, 9, 6, ""}},
+		{"indent a line", edit{11, 0, 11, 0, "  "}},
+		{"join lines", edit{5, 23, 7, 0, ""}},
+		{"delete a subtree", edit{4, 0, 8, 0, ""}},
+		{"add a root key", edit{11, 0, 11, 0, "info: {}\n"}},
+		{"insert a comment", edit{7, 0, 7, 0, "# comment"}},
+		{"append at the end", edit{15, 0, 15, 0, "      required: [name]\n"}},
+		{"append without newline", edit{14, 18, 14, 18, "\n  responses: {}"}},
+		{"edit the first line", edit{0, 0, 0, 7, "swagger"}},
+		{"replace everything", edit{0, 0, 15, 0, "foo: bar\n"}},
+		{"windows line endings", edit{6, 19, 6, 19, "\r\n          required: true\r"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			document, err := Parse(strings.NewReader(src))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			checkReparse(t, document, src, tt.edit)
+		})
+	}
+}
+
+func TestDocument_Reparse_Random(t *testing.T) {
+	b, err := os.ReadFile("testdata/petstore.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := string(b)
+
+	document, err := Parse(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snippets := []string{
+		"",
+		"x",
+		" ",
+		"  ",
+		"\n",
+		"- ",
+		"# comment",
+		"key: value",
+		"\n  nested:\n    deeper: true\n",
+		"\n- item\n",
+		"\nroot: {}\n",
+		"\n      $ref: '#/components/schemas/Pet'",
+		"description: |\n  text\n",
+	}
+
+	rng := rand.New(rand.NewSource(1)) //nolint:gosec // deterministic test input
+
+	// Edits are applied one after another to the same document, so that any
+	// state that is left behind by one reparse shows up in the next.
+	for range 1000 {
+		lines := strings.Count(src, "\n") + 1
+		startLine := rng.Intn(lines)
+		endLine := min(startLine+rng.Intn(4), lines-1)
+
+		e := edit{
+			startLine: startLine,
+			startChar: rng.Intn(12),
+			endLine:   endLine,
+			endChar:   rng.Intn(12),
+			text:      snippets[rng.Intn(len(snippets))],
+		}
+
+		if offset(src, e.endLine, e.endChar) < offset(src, e.startLine, e.startChar) {
+			e.endChar = e.startChar
+		}
+
+		document, src = checkReparse(t, document, src, e)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"bufio"
 	"bytes"
 	"io"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
@@ -13,6 +15,44 @@ import (
 type Document struct {
 	Lines []*Line
 	Root  map[string]*Line
+
+	// nodes are the lines together with the keys and array elements that do
+	// not start their line, or nil if there are none.
+	nodes []*Line
+}
+
+// Nodes returns the lines of the document in order. Compact JSON can have
+// several keys or array elements on a line, which follow the line that holds
+// the first of them.
+func (s Document) Nodes() []*Line {
+	if s.nodes == nil {
+		return s.Lines
+	}
+
+	return s.nodes
+}
+
+// At returns the line at a position, or nil if the position is past the end of
+// the document. If several keys or array elements start on the line, it is the
+// last one that starts at or before the position.
+func (s Document) At(position types.Position) *Line {
+	if position.Line < 0 || position.Line >= len(s.Lines) {
+		return nil
+	}
+
+	result := s.Lines[position.Line]
+	if s.nodes == nil {
+		return result
+	}
+
+	i := sort.Search(len(s.nodes), func(i int) bool { return s.nodes[i].Number > position.Line })
+	for _, node := range s.nodes[:i] {
+		if node.Number == position.Line && !node.Empty && node.Indent <= position.Character && node.Indent > result.Indent {
+			result = node
+		}
+	}
+
+	return result
 }
 
 // Locate finds a line in the document by its JSON reference URI.
@@ -22,13 +62,13 @@ func (s Document) Locate(ref string) *Line {
 		return nil
 	}
 
-	cur := s.Root[split[1]]
+	cur := s.Root[unescape(split[1])]
 	if cur == nil {
 		return nil
 	}
 
 	for _, key := range split[2:] {
-		cur = cur.Children[key]
+		cur = cur.child(unescape(key))
 		if cur == nil {
 			return nil
 		}
@@ -37,14 +77,107 @@ func (s Document) Locate(ref string) *Line {
 	return cur
 }
 
+// End returns the line number of the last line in the subtree rooted at the
+// given line. Trailing empty lines are not included.
+func (s Document) End(line *Line) int {
+	end := line.Number
+
+	for i := line.Number + 1; i < len(s.Lines); i++ {
+		cur := s.Lines[i]
+		if cur.Empty {
+			continue
+		}
+		if !cur.IsDescendantOf(line) {
+			break
+		}
+		end = i
+	}
+
+	return end
+}
+
+// BlockScalarEnd returns the line number of the last line of the contents of a
+// block scalar. Lines that start with "#" are part of the contents if they are
+// indented below the key, even though they are parsed as comments.
+func (s Document) BlockScalarEnd(line *Line) int {
+	end := s.End(line)
+
+	for i := end + 1; i < len(s.Lines) && s.Lines[i].Empty; i++ {
+		if s.Lines[i].Comment && s.Lines[i].Indent > line.Indent {
+			end = i
+		}
+	}
+
+	return end
+}
+
 // Line represents a line in a YAML document.
 type Line struct {
 	Parent     *Line
 	Children   map[string]*Line
+	Items      []*Line
 	Key        string
 	Value      string
 	KeyRange   types.Range
 	ValueRange types.Range
+
+	// Number is the zero-based line number.
+	Number int
+
+	// Indent is the number of leading spaces on the line.
+	Indent int
+
+	// Item is true if the line starts a sequence item ("- "). Item lines are
+	// stored in the parent's Items rather than Children. If the item is a
+	// mapping, the Key and Value of the line are those of the first entry, and
+	// the remaining entries are stored in Children.
+	Item bool
+
+	// Empty is true if the line is blank or contains only a comment. Empty
+	// lines are not part of the document tree.
+	Empty bool
+
+	// Comment is true if the line is an empty line that contains a comment.
+	Comment bool
+}
+
+// Field returns the mapping entry with the given key, taking into account that
+// the first entry of a sequence item is stored on the item line itself.
+func (e *Line) Field(key string) *Line {
+	if e.Item && e.Key == key {
+		return e
+	}
+
+	return e.Children[key]
+}
+
+// IsDescendantOf returns true if the line is nested anywhere below the given
+// ancestor.
+func (e *Line) IsDescendantOf(ancestor *Line) bool {
+	for cur := e.Parent; cur != nil; cur = cur.Parent {
+		if cur == ancestor {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsBlockScalar returns true if the value of the line is a literal or folded
+// block scalar, whose contents are on the following lines.
+func (e *Line) IsBlockScalar() bool {
+	value, _, _ := strings.Cut(e.Value, " #")
+	value = strings.TrimSpace(value)
+
+	return value != "" && (value[0] == '|' || value[0] == '>') && strings.Trim(value[1:], "+-0123456789") == ""
+}
+
+func (e *Line) child(key string) *Line {
+	if index, err := strconv.Atoi(key); err == nil && index >= 0 && index < len(e.Items) {
+		return e.Items[index]
+	}
+
+	return e.Field(key)
 }
 
 // KeyRef returns the JSON reference URI that describes the key on this line.
@@ -52,7 +185,17 @@ func (e *Line) KeyRef() string {
 	keys := []string{}
 
 	for cur := e; cur != nil; cur = cur.Parent {
-		keys = append(keys, cur.Key)
+		if cur.Item && cur.Parent != nil {
+			for i, item := range cur.Parent.Items {
+				if item == cur {
+					keys = append(keys, strconv.Itoa(i))
+					break
+				}
+			}
+			continue
+		}
+
+		keys = append(keys, escape(cur.Key))
 	}
 
 	var b strings.Builder
@@ -70,6 +213,16 @@ func (e *Line) KeyRef() string {
 	return b.String()
 }
 
+// escape encodes a key as a JSON pointer reference token.
+func escape(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
+
+// unescape decodes a JSON pointer reference token.
+func unescape(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+}
+
 type lineWithIndent struct {
 	line   *Line
 	indent int
@@ -80,6 +233,7 @@ type lineWithIndent struct {
 func Parse(r io.Reader) (Document, error) {
 	parentStack := []lineWithIndent{}
 	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 10*1024*1024)
 	document := Document{
 		Root: map[string]*Line{},
 	}
@@ -88,7 +242,11 @@ func Parse(r io.Reader) (Document, error) {
 		line := parseLine(scanner.Bytes(), lineNum)
 		document.Lines = append(document.Lines, line.line)
 
-		for len(parentStack) > 0 && parentStack[len(parentStack)-1].indent >= line.indent {
+		if line.line.Empty {
+			continue
+		}
+
+		for len(parentStack) > 0 && !isParentOf(parentStack[len(parentStack)-1], line) {
 			parentStack = parentStack[:len(parentStack)-1]
 		}
 
@@ -98,12 +256,7 @@ func Parse(r io.Reader) (Document, error) {
 			continue
 		}
 
-		parent := parentStack[len(parentStack)-1]
-		line.line.Parent = parent.line
-		if parent.line.Children == nil {
-			parent.line.Children = map[string]*Line{}
-		}
-		parent.line.Children[line.line.Key] = line.line
+		addChild(parentStack[len(parentStack)-1].line, line.line)
 		parentStack = append(parentStack, line)
 	}
 
@@ -114,9 +267,34 @@ func Parse(r io.Reader) (Document, error) {
 	return document, nil
 }
 
+// addChild adds a line to the children or items of its parent.
+func addChild(parent, child *Line) {
+	child.Parent = parent
+
+	if child.Item {
+		parent.Items = append(parent.Items, child)
+		return
+	}
+
+	if parent.Children == nil {
+		parent.Children = map[string]*Line{}
+	}
+	parent.Children[child.Key] = child
+}
+
+// isParentOf returns true if the candidate line can contain the given line.
+// Sequence items may be written at the same indentation as their parent key.
+func isParentOf(candidate, line lineWithIndent) bool {
+	if candidate.indent < line.indent {
+		return true
+	}
+
+	return candidate.indent == line.indent && line.line.Item && !candidate.line.Item && candidate.line.Value == ""
+}
+
 func parseLine(s []byte, lineNum int) lineWithIndent {
 	result := lineWithIndent{
-		line: &Line{},
+		line: &Line{Number: lineNum},
 	}
 
 	result.indent = bytes.IndexFunc(s, func(ch rune) bool {
@@ -125,18 +303,49 @@ func parseLine(s []byte, lineNum int) lineWithIndent {
 	if result.indent == -1 {
 		result.indent = len(s)
 	}
+	result.line.Indent = result.indent
+
+	if result.indent == len(s) || s[result.indent] == '#' {
+		result.line.Empty = true
+		result.line.Comment = result.indent < len(s)
+		return result
+	}
+
+	keyStart := result.indent
+
+	if s[keyStart] == '-' && (keyStart+1 == len(s) || s[keyStart+1] == ' ') {
+		result.line.Item = true
+
+		keyStart = bytes.IndexFunc(s[keyStart+1:], func(ch rune) bool {
+			return ch != ' '
+		})
+		if keyStart == -1 {
+			return result
+		}
+		keyStart += result.indent + 1
+	}
 
-	keyEnd := bytes.Index(s, []byte(":"))
+	keyEnd := keySeparator(s[keyStart:])
 	if keyEnd == -1 {
+		if result.line.Item {
+			parseValue(result.line, s, keyStart, lineNum)
+		}
 		return result
 	}
+	keyEnd += keyStart
 
-	result.line.Key = string(s[result.indent:keyEnd])
+	result.line.Key = string(s[keyStart:keyEnd])
 	result.line.KeyRange = types.Range{
-		Start: types.Position{Line: lineNum, Character: result.indent},
+		Start: types.Position{Line: lineNum, Character: keyStart},
 		End:   types.Position{Line: lineNum, Character: keyEnd},
 	}
 
+	if quoted := unquote(result.line.Key); quoted != result.line.Key {
+		result.line.Key = quoted
+		result.line.KeyRange.Start.Character++
+		result.line.KeyRange.End.Character--
+	}
+
 	valueStart := bytes.IndexFunc(s[keyEnd+1:], func(ch rune) bool {
 		return ch != ' '
 	})
@@ -148,26 +357,52 @@ func parseLine(s []byte, lineNum int) lineWithIndent {
 		return result
 	}
 
+	parseValue(result.line, s, valueStart, lineNum)
+
+	return result
+}
+
+func parseValue(line *Line, s []byte, valueStart, lineNum int) {
 	if s[valueStart] == '"' || s[valueStart] == '\'' {
 		valueEnd := bytes.LastIndex(s, s[valueStart:valueStart+1])
 		if valueEnd <= valueStart {
-			return result
+			return
 		}
 
-		result.line.Value = string(s[valueStart+1 : valueEnd])
-		result.line.ValueRange = types.Range{
+		line.Value = string(s[valueStart+1 : valueEnd])
+		line.ValueRange = types.Range{
 			Start: types.Position{Line: lineNum, Character: valueStart + 1},
 			End:   types.Position{Line: lineNum, Character: valueEnd},
 		}
 
-		return result
+		return
 	}
 
-	result.line.Value = string(s[valueStart:])
-	result.line.ValueRange = types.Range{
+	line.Value = string(s[valueStart:])
+	line.ValueRange = types.Range{
 		Start: types.Position{Line: lineNum, Character: valueStart},
 		End:   types.Position{Line: lineNum, Character: len(s)},
 	}
+}
 
-	return result
+// keySeparator returns the index of the colon that separates a key from its
+// value, or -1 if there is none. A colon only separates a key when it is
+// followed by a space or the end of the line, so that values such as URLs are
+// not split.
+func keySeparator(s []byte) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] == ':' && (i+1 == len(s) || s[i+1] == ' ') {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+
+	return s
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"os"
 	"strconv"
+	"strings"
 	"testing"
 
 	. "github.com/armsnyder/openapi-language-server/internal/analysis/yaml"
@@ -353,3 +354,100 @@ func TestParse_PetStore(t *testing.T) {
 		t.Errorf("got key %q, want %q", line.Key, wantKey)
 	}
 }
+
+func TestParse_Sequence(t *testing.T) {
+	document, err := Parse(bytes.NewReader([]byte(`parameters:
+  - name: petId
+
+    in: path
+  # comment
+  - $ref: "#/components/parameters/Limit"
+required:
+- id
+- name
+`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parameters := document.Root["parameters"]
+	if parameters == nil {
+		t.Fatal("missing root key parameters")
+	}
+
+	if len(parameters.Items) != 2 {
+		t.Fatalf("parameters: got %d items, want 2", len(parameters.Items))
+	}
+
+	first := parameters.Items[0]
+	if !first.Item || first.Key != "name" || first.Value != "petId" {
+		t.Errorf("first item: got %+v", first)
+	}
+
+	if first.Field("name") != first {
+		t.Errorf("first item: Field(name) did not return the item line")
+	}
+
+	if in := first.Field("in"); in == nil || in.Value != "path" {
+		t.Errorf("first item: Field(in) = %v, want path", in)
+	}
+
+	wantKeyRange := types.Range{
+		Start: types.Position{Line: 1, Character: 4},
+		End:   types.Position{Line: 1, Character: 8},
+	}
+	if first.KeyRange != wantKeyRange {
+		t.Errorf("first item: got key range %v, want %v", first.KeyRange, wantKeyRange)
+	}
+
+	if got := document.Locate("#/parameters/1"); got != parameters.Items[1] {
+		t.Errorf("Locate(#/parameters/1) = %v, want second item", got)
+	}
+
+	if got := parameters.Items[1].KeyRef(); got != "#/parameters/1" {
+		t.Errorf("second item: got KeyRef %q, want %q", got, "#/parameters/1")
+	}
+
+	if got := document.End(parameters); got != 5 {
+		t.Errorf("End(parameters) = %d, want 5", got)
+	}
+
+	required := document.Root["required"]
+	if required == nil {
+		t.Fatal("missing root key required")
+	}
+
+	var values []string
+	for _, item := range required.Items {
+		values = append(values, item.Value)
+	}
+
+	if strings.Join(values, ",") != "id,name" {
+		t.Errorf("required: got items %v, want [id name]", values)
+	}
+}
+
+func TestParse_EscapedRef(t *testing.T) {
+	document, err := Parse(bytes.NewReader([]byte(`paths:
+  "/pets/{petId}":
+    get:
+      summary: http://example.com/a~b
+`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := document.Locate("#/paths/~1pets~1{petId}/get")
+	if get == nil {
+		t.Fatal("could not locate operation")
+	}
+
+	if got := get.KeyRef(); got != "#/paths/~1pets~1{petId}/get" {
+		t.Errorf("got KeyRef %q", got)
+	}
+
+	summary := get.Children["summary"]
+	if summary == nil || summary.Value != "http://example.com/a~b" {
+		t.Errorf("got summary %v", summary)
+	}
+}
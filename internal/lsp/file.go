@@ -1,39 +1,37 @@
 package lsp
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
-	"slices"
 	"unicode/utf8"
 
 	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
 )
 
 // File is a representation of a text file that can be modified by LSP text
-// document change events. It keeps track of line breaks to allow for efficient
-// conversion between byte offsets and LSP positions.
+// document change events. The content is stored in a rope that keeps track of
+// line breaks, so that edits and conversions between byte offsets and LSP
+// positions take O(log n) time in the size of the file.
 type File struct {
-	bytes       []byte
-	lineOffsets []int
+	text *rope
+
+	// bytes caches the content of the file as a single slice.
+	bytes []byte
 }
 
-// Bytes returns the raw bytes of the file.
-func (f File) Bytes() []byte {
+// Bytes returns the raw bytes of the file. The result must not be modified.
+func (f *File) Bytes() []byte {
+	if f.bytes == nil {
+		f.bytes = f.text.AppendRange(make([]byte, 0, f.text.Len()), 0, f.text.Len())
+	}
+
 	return f.bytes
 }
 
 // Reset initializes the file with the given content.
 func (f *File) Reset(s []byte) {
+	f.text = buildRope(s)
 	f.bytes = s
-	newlineCount := bytes.Count(f.bytes, []byte{'\n'})
-	f.lineOffsets = make([]int, 1, newlineCount+1)
-
-	for i, b := range f.bytes {
-		if b == '\n' {
-			f.lineOffsets = append(f.lineOffsets, i+1)
-		}
-	}
 }
 
 // ApplyChange applies the given change to the file content.
@@ -53,47 +51,75 @@ func (f *File) ApplyChange(change types.TextDocumentContentChangeEvent) error {
 		return err
 	}
 
-	f.Reset(append(f.bytes[:start], append([]byte(change.Text), f.bytes[end:]...)...))
+	if end < start {
+		return fmt.Errorf("range %s ends before it starts", change.Range)
+	}
+
+	f.text = f.text.Replace(start, end, []byte(change.Text))
+	f.bytes = nil
 
 	return nil
 }
 
-// GetPosition returns the LSP protocol position for the given byte offset.
-func (f *File) GetPosition(offset int) (types.Position, error) {
-	if offset < 0 || offset > len(f.bytes) {
-		return types.Position{}, fmt.Errorf("offset %d is out of range [0, %d]", offset, len(f.bytes))
+// Len returns the length of the file in bytes.
+func (f *File) Len() int {
+	return f.text.Len()
+}
+
+// LineCount returns the number of lines in the file. A file that ends with a
+// line break has an empty last line.
+func (f *File) LineCount() int {
+	return f.text.Lines() + 1
+}
+
+// Line returns the content of a line, without the line break.
+func (f *File) Line(line int) []byte {
+	if line < 0 || line >= f.LineCount() {
+		return nil
 	}
 
-	line, found := slices.BinarySearch(f.lineOffsets, offset)
-	if !found {
-		line--
+	start, end := f.lineRange(line)
+
+	return f.text.AppendRange(nil, start, end)
+}
+
+// lineRange returns the offsets of the start and the end of a line, without
+// the line break.
+func (f *File) lineRange(line int) (start, end int) {
+	start = f.text.LineStart(line)
+
+	if line == f.text.Lines() {
+		return start, f.text.Len()
 	}
 
-	character := UTF16Len(f.bytes[f.lineOffsets[line]:offset])
+	return start, f.text.LineStart(line+1) - 1
+}
+
+// GetPosition returns the LSP protocol position for the given byte offset.
+func (f *File) GetPosition(offset int) (types.Position, error) {
+	if offset < 0 || offset > f.text.Len() {
+		return types.Position{}, fmt.Errorf("offset %d is out of range [0, %d]", offset, f.text.Len())
+	}
+
+	line := f.text.LinesBefore(offset)
+	character := UTF16Len(f.text.AppendRange(nil, f.text.LineStart(line), offset))
 
 	return types.Position{Line: line, Character: character}, nil
 }
 
 // GetOffset returns the byte offset for the given LSP protocol position.
 func (f *File) GetOffset(p types.Position) (int, error) {
-	if p.Line < 0 || p.Line >= len(f.lineOffsets) {
-		return 0, fmt.Errorf("position %s is out of range", p)
-	}
-
-	if p.Line == len(f.lineOffsets) {
-		if p.Character == 0 {
-			return len(f.bytes), nil
-		}
-
+	if p.Line < 0 || p.Line >= f.LineCount() {
 		return 0, fmt.Errorf("position %s is out of range", p)
 	}
 
-	rest := f.bytes[f.lineOffsets[p.Line]:]
+	start, end := f.lineRange(p.Line)
+	rest := f.text.AppendRange(nil, start, end)
 
 	for i := 0; i < p.Character; i++ {
 		r, size := utf8.DecodeRune(rest)
 
-		if size == 0 || r == '\n' {
+		if size == 0 {
 			return 0, fmt.Errorf("position %s is out of range", p)
 		}
 
@@ -113,5 +139,5 @@ func (f *File) GetOffset(p types.Position) (int, error) {
 		rest = rest[size:]
 	}
 
-	return len(f.bytes) - len(rest), nil
+	return end - len(rest), nil
 }
@@ -1,10 +1,14 @@
 package lsp_test
 
 import (
+	"bytes"
+	"math/rand"
 	"regexp"
 	"runtime"
 	"strconv"
+	"strings"
 	"testing"
+	"unicode/utf8"
 
 	. "github.com/armsnyder/openapi-language-server/internal/lsp"
 	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
@@ -129,6 +133,156 @@ func TestFile(t *testing.T) {
 	}
 }
 
+func TestFile_RandomEdits(t *testing.T) {
+	rng := rand.New(rand.NewSource(1)) //nolint:gosec // deterministic test input
+
+	// Compare against a plain string, starting from a file that spans several
+	// chunks of the rope.
+	want := strings.Repeat("key: value\n  nested: 🙂 ü\n\n", 2000)
+
+	var f File
+	f.Reset([]byte(want))
+
+	snippets := []string{"", "x", "\n", "ab\ncd", "🙂", "\n\n\n", strings.Repeat("long line ", 1000) + "\n"}
+
+	for i := range 1000 {
+		start := rng.Intn(len(want) + 1)
+		end := min(start+rng.Intn(100), len(want))
+
+		// Align the offsets to whole characters.
+		for start < len(want) && !utf8.RuneStart(want[start]) {
+			start--
+		}
+		for end < len(want) && !utf8.RuneStart(want[end]) {
+			end++
+		}
+
+		text := snippets[rng.Intn(len(snippets))]
+
+		startPos, err := f.GetPosition(start)
+		if err != nil {
+			t.Fatalf("step %d: GetPosition: %v", i, err)
+		}
+
+		endPos, err := f.GetPosition(end)
+		if err != nil {
+			t.Fatalf("step %d: GetPosition: %v", i, err)
+		}
+
+		if err := f.ApplyChange(types.TextDocumentContentChangeEvent{Text: text, Range: &types.Range{Start: startPos, End: endPos}}); err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+
+		want = want[:start] + text + want[end:]
+
+		if i%100 == 0 {
+			checkFile(t, &f, want)
+		}
+	}
+
+	checkFile(t, &f, want)
+}
+
+// checkFile checks that every query of a file matches its expected content.
+func checkFile(t *testing.T, f *File, want string) {
+	t.Helper()
+
+	if f.Len() != len(want) {
+		t.Fatalf("Len() = %d, want %d", f.Len(), len(want))
+	}
+
+	if got := string(f.Bytes()); got != want {
+		t.Fatalf("got %d bytes, want %d", len(got), len(want))
+	}
+
+	lines := strings.Split(want, "\n")
+
+	if f.LineCount() != len(lines) {
+		t.Fatalf("LineCount() = %d, want %d", f.LineCount(), len(lines))
+	}
+
+	offset := 0
+
+	for i, line := range lines {
+		if got := string(f.Line(i)); got != line {
+			t.Fatalf("Line(%d) = %q, want %q", i, got, line)
+		}
+
+		pos := types.Position{Line: i, Character: UTF16Len([]byte(line))}
+
+		got, err := f.GetOffset(pos)
+		if err != nil || got != offset+len(line) {
+			t.Fatalf("GetOffset(%s) = %d, %v, want %d", pos, got, err, offset+len(line))
+		}
+
+		gotPos, err := f.GetPosition(offset + len(line))
+		if err != nil || gotPos != pos {
+			t.Fatalf("GetPosition(%d) = %s, %v, want %s", offset+len(line), gotPos, err, pos)
+		}
+
+		offset += len(line) + 1
+	}
+}
+
+// largeFile returns the content of a file with about 4 MB.
+func largeFile() []byte {
+	return bytes.Repeat([]byte("      description: A line of a large OpenAPI document.\n"), 80000)
+}
+
+func BenchmarkFile_Reset(b *testing.B) {
+	content := largeFile()
+
+	b.SetBytes(int64(len(content)))
+
+	for range b.N {
+		var f File
+		f.Reset(content)
+	}
+}
+
+func BenchmarkFile_ApplyChange(b *testing.B) {
+	var f File
+	f.Reset(largeFile())
+
+	b.ResetTimer()
+
+	// Type one character at a time, spread over the whole file.
+	for i := range b.N {
+		pos := types.Position{Line: i * 7919 % 80000, Character: 20}
+		if err := f.ApplyChange(types.TextDocumentContentChangeEvent{Text: "x", Range: &types.Range{Start: pos, End: pos}}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFile_GetOffset(b *testing.B) {
+	var f File
+	f.Reset(largeFile())
+
+	b.ResetTimer()
+
+	for i := range b.N {
+		if _, err := f.GetOffset(types.Position{Line: i % 80000, Character: 20}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFile_GetPosition(b *testing.B) {
+	var f File
+	f.Reset(largeFile())
+
+	size := len(f.Bytes())
+
+	b.ResetTimer()
+
+	for i := range b.N {
+		if _, err := f.GetPosition(i * 7919 % size); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 type Step struct {
 	Text  string
 	Range string
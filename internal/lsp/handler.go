@@ -5,11 +5,62 @@ import "github.com/armsnyder/openapi-language-server/internal/lsp/types"
 // Handler is an interface for handling LSP requests.
 type Handler interface {
 	Capabilities() types.ServerCapabilities
+	HandleInitialize(params types.InitializeParams) error
+	HandleDidChangeConfiguration(params types.DidChangeConfigurationParams) error
+	HandleDidChangeWatchedFiles(params types.DidChangeWatchedFilesParams) error
+	HandleDidChangeWorkspaceFolders(params types.DidChangeWorkspaceFoldersParams) error
 	HandleOpen(params types.DidOpenTextDocumentParams) error
 	HandleClose(params types.DidCloseTextDocumentParams) error
 	HandleChange(params types.DidChangeTextDocumentParams) error
+	HandleSave(params types.DidSaveTextDocumentParams) error
+	HandleWillSaveWaitUntil(params types.WillSaveTextDocumentParams) ([]types.TextEdit, error)
 	HandleDefinition(params types.DefinitionParams) ([]types.Location, error)
 	HandleReferences(params types.ReferenceParams) ([]types.Location, error)
+	HandleHover(params types.HoverParams) (*types.Hover, error)
+	HandleCodeAction(params types.CodeActionParams) ([]types.CodeAction, error)
+	HandlePrepareTypeHierarchy(params types.TypeHierarchyPrepareParams) ([]types.TypeHierarchyItem, error)
+	HandleTypeHierarchySupertypes(params types.TypeHierarchySupertypesParams) ([]types.TypeHierarchyItem, error)
+	HandleTypeHierarchySubtypes(params types.TypeHierarchySubtypesParams) ([]types.TypeHierarchyItem, error)
+	HandlePrepareCallHierarchy(params types.CallHierarchyPrepareParams) ([]types.CallHierarchyItem, error)
+	HandleCallHierarchyIncomingCalls(params types.CallHierarchyIncomingCallsParams) ([]types.CallHierarchyIncomingCall, error)
+	HandleCallHierarchyOutgoingCalls(params types.CallHierarchyOutgoingCallsParams) ([]types.CallHierarchyOutgoingCall, error)
+	HandleCodeLens(params types.CodeLensParams) ([]types.CodeLens, error)
+	HandleCodeLensResolve(params types.CodeLens) (types.CodeLens, error)
+	HandleInlayHint(params types.InlayHintParams) ([]types.InlayHint, error)
+	HandleDocumentLink(params types.DocumentLinkParams) ([]types.DocumentLink, error)
+	HandleFoldingRange(params types.FoldingRangeParams) ([]types.FoldingRange, error)
+	HandleSelectionRange(params types.SelectionRangeParams) ([]types.SelectionRange, error)
+	HandleFormatting(params types.DocumentFormattingParams) ([]types.TextEdit, error)
+	HandleRangeFormatting(params types.DocumentRangeFormattingParams) ([]types.TextEdit, error)
+	HandleExecuteCommand(params types.ExecuteCommandParams) (any, error)
+
+	// Diagnostics returns the diagnostics of any documents whose diagnostics
+	// have changed since the last call. It is called after each document
+	// notification, and the results are published to the client.
+	Diagnostics() ([]types.PublishDiagnosticsParams, error)
+
+	// Messages returns any messages for the user since the last call. It is
+	// called after each request and notification, and the results are sent to
+	// the client.
+	Messages() []Message
+
+	// Tasks returns a channel of work that the handler finished in the
+	// background, such as fetching a document. The server runs each task
+	// between messages, so that the state of the handler is only touched by
+	// one goroutine, and then publishes diagnostics and messages and asks the
+	// client to refresh inlay hints and code lenses.
+	Tasks() <-chan func()
+}
+
+// Message is a message for the user, such as a warning about a document that
+// could not be analyzed.
+type Message struct {
+	Type    types.MessageType
+	Message string
+
+	// Show is true if the client should show the message to the user, rather
+	// than only log it.
+	Show bool
 }
 
 // NopHandler can be embedded in a struct to provide no-op implementations of
@@ -21,6 +72,26 @@ func (NopHandler) Capabilities() types.ServerCapabilities {
 	return types.ServerCapabilities{}
 }
 
+// HandleInitialize implements Handler.
+func (NopHandler) HandleInitialize(types.InitializeParams) error {
+	return nil
+}
+
+// HandleDidChangeConfiguration implements Handler.
+func (NopHandler) HandleDidChangeConfiguration(types.DidChangeConfigurationParams) error {
+	return nil
+}
+
+// HandleDidChangeWatchedFiles implements Handler.
+func (NopHandler) HandleDidChangeWatchedFiles(types.DidChangeWatchedFilesParams) error {
+	return nil
+}
+
+// HandleDidChangeWorkspaceFolders implements Handler.
+func (NopHandler) HandleDidChangeWorkspaceFolders(types.DidChangeWorkspaceFoldersParams) error {
+	return nil
+}
+
 // HandleOpen implements Handler.
 func (NopHandler) HandleOpen(types.DidOpenTextDocumentParams) error {
 	return nil
@@ -36,6 +107,16 @@ func (NopHandler) HandleChange(types.DidChangeTextDocumentParams) error {
 	return nil
 }
 
+// HandleSave implements Handler.
+func (NopHandler) HandleSave(types.DidSaveTextDocumentParams) error {
+	return nil
+}
+
+// HandleWillSaveWaitUntil implements Handler.
+func (NopHandler) HandleWillSaveWaitUntil(types.WillSaveTextDocumentParams) ([]types.TextEdit, error) {
+	return []types.TextEdit{}, nil
+}
+
 // HandleDefinition implements Handler.
 func (NopHandler) HandleDefinition(types.DefinitionParams) ([]types.Location, error) {
 	return []types.Location{}, nil
@@ -46,4 +127,104 @@ func (NopHandler) HandleReferences(types.ReferenceParams) ([]types.Location, err
 	return []types.Location{}, nil
 }
 
+// HandleHover implements Handler.
+func (NopHandler) HandleHover(types.HoverParams) (*types.Hover, error) {
+	return nil, nil
+}
+
+// HandleCodeAction implements Handler.
+func (NopHandler) HandleCodeAction(types.CodeActionParams) ([]types.CodeAction, error) {
+	return []types.CodeAction{}, nil
+}
+
+// HandlePrepareTypeHierarchy implements Handler.
+func (NopHandler) HandlePrepareTypeHierarchy(types.TypeHierarchyPrepareParams) ([]types.TypeHierarchyItem, error) {
+	return nil, nil
+}
+
+// HandleTypeHierarchySupertypes implements Handler.
+func (NopHandler) HandleTypeHierarchySupertypes(types.TypeHierarchySupertypesParams) ([]types.TypeHierarchyItem, error) {
+	return nil, nil
+}
+
+// HandleTypeHierarchySubtypes implements Handler.
+func (NopHandler) HandleTypeHierarchySubtypes(types.TypeHierarchySubtypesParams) ([]types.TypeHierarchyItem, error) {
+	return nil, nil
+}
+
+// HandlePrepareCallHierarchy implements Handler.
+func (NopHandler) HandlePrepareCallHierarchy(types.CallHierarchyPrepareParams) ([]types.CallHierarchyItem, error) {
+	return nil, nil
+}
+
+// HandleCallHierarchyIncomingCalls implements Handler.
+func (NopHandler) HandleCallHierarchyIncomingCalls(types.CallHierarchyIncomingCallsParams) ([]types.CallHierarchyIncomingCall, error) {
+	return nil, nil
+}
+
+// HandleCallHierarchyOutgoingCalls implements Handler.
+func (NopHandler) HandleCallHierarchyOutgoingCalls(types.CallHierarchyOutgoingCallsParams) ([]types.CallHierarchyOutgoingCall, error) {
+	return nil, nil
+}
+
+// HandleCodeLens implements Handler.
+func (NopHandler) HandleCodeLens(types.CodeLensParams) ([]types.CodeLens, error) {
+	return nil, nil
+}
+
+// HandleCodeLensResolve implements Handler.
+func (NopHandler) HandleCodeLensResolve(params types.CodeLens) (types.CodeLens, error) {
+	return params, nil
+}
+
+// HandleInlayHint implements Handler.
+func (NopHandler) HandleInlayHint(types.InlayHintParams) ([]types.InlayHint, error) {
+	return nil, nil
+}
+
+// HandleDocumentLink implements Handler.
+func (NopHandler) HandleDocumentLink(types.DocumentLinkParams) ([]types.DocumentLink, error) {
+	return nil, nil
+}
+
+// HandleFoldingRange implements Handler.
+func (NopHandler) HandleFoldingRange(types.FoldingRangeParams) ([]types.FoldingRange, error) {
+	return nil, nil
+}
+
+// HandleSelectionRange implements Handler.
+func (NopHandler) HandleSelectionRange(types.SelectionRangeParams) ([]types.SelectionRange, error) {
+	return nil, nil
+}
+
+// HandleFormatting implements Handler.
+func (NopHandler) HandleFormatting(types.DocumentFormattingParams) ([]types.TextEdit, error) {
+	return nil, nil
+}
+
+// HandleRangeFormatting implements Handler.
+func (NopHandler) HandleRangeFormatting(types.DocumentRangeFormattingParams) ([]types.TextEdit, error) {
+	return nil, nil
+}
+
+// HandleExecuteCommand implements Handler.
+func (NopHandler) HandleExecuteCommand(types.ExecuteCommandParams) (any, error) {
+	return nil, nil
+}
+
+// Diagnostics implements Handler.
+func (NopHandler) Diagnostics() ([]types.PublishDiagnosticsParams, error) {
+	return nil, nil
+}
+
+// Messages implements Handler.
+func (NopHandler) Messages() []Message {
+	return nil
+}
+
+// Tasks implements Handler.
+func (NopHandler) Tasks() <-chan func() {
+	return nil
+}
+
 var _ Handler = NopHandler{}
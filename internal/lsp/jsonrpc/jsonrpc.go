@@ -1,6 +1,7 @@
 package jsonrpc
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"errors"
@@ -58,3 +59,44 @@ func WritePayload(w io.Writer, payload []byte) error {
 	_, err := w.Write(packet)
 	return err
 }
+
+// Stream reads and writes whole JSON-RPC messages, leaving the framing of the
+// messages to the transport.
+type Stream interface {
+	// ReadMessage returns the payload of the next message. It returns io.EOF
+	// when there are no more messages.
+	ReadMessage() ([]byte, error)
+
+	// WriteMessage writes the payload of a message.
+	WriteMessage(payload []byte) error
+}
+
+// NewHeaderStream returns a Stream that frames messages with a Content-Length
+// header, as in the base protocol of LSP.
+func NewHeaderStream(r io.Reader, w io.Writer) Stream {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 10*1024*1024)
+	scanner.Split(Split)
+
+	return &headerStream{scanner: scanner, writer: w}
+}
+
+type headerStream struct {
+	scanner *bufio.Scanner
+	writer  io.Writer
+}
+
+func (s *headerStream) ReadMessage() ([]byte, error) {
+	if !s.scanner.Scan() {
+		if err := s.scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+
+	return s.scanner.Bytes(), nil
+}
+
+func (s *headerStream) WriteMessage(payload []byte) error {
+	return WritePayload(s.writer, payload)
+}
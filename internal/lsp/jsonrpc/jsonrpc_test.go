@@ -3,6 +3,7 @@ package jsonrpc_test
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"io"
 	"strconv"
 	"strings"
@@ -146,3 +147,33 @@ func TestWrite(t *testing.T) {
 		})
 	}
 }
+
+func TestHeaderStream(t *testing.T) {
+	input := "Content-Length: 17\r\n\r\n{\"jsonrpc\":\"2.0\"}Content-Length: 2\r\n\r\n{}"
+	output := &bytes.Buffer{}
+
+	stream := NewHeaderStream(strings.NewReader(input), output)
+
+	for _, want := range []string{`{"jsonrpc":"2.0"}`, `{}`} {
+		got, err := stream.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(got) != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+
+	if _, err := stream.ReadMessage(); !errors.Is(err, io.EOF) {
+		t.Errorf("got error %v, want io.EOF", err)
+	}
+
+	if err := stream.WriteMessage([]byte(`null`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := output.String(), "Content-Length: 4\r\n\r\nnull"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
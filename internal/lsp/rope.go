@@ -0,0 +1,234 @@
+package lsp
+
+import (
+	"bytes"
+	"math/rand/v2"
+)
+
+// maxChunkSize is the largest number of bytes in a single node of a rope.
+const maxChunkSize = 4096
+
+// rope is an immutable sequence of bytes, stored as a treap of chunks that is
+// ordered by position. Each node also counts the line breaks in its subtree, so
+// that offsets can be converted to and from line numbers in O(log n). Edits
+// return a new rope that shares all unchanged nodes with the old one.
+type rope struct {
+	left, right *rope
+	chunk       []byte
+
+	// priority orders the nodes as a heap, which keeps the tree balanced
+	// with high probability.
+	priority uint32
+
+	// chunkLines is the number of line breaks in chunk.
+	chunkLines int
+
+	// size and lines are the number of bytes and line breaks in the subtree.
+	size  int
+	lines int
+}
+
+func newRope(left *rope, chunk []byte, chunkLines int, right *rope, priority uint32) *rope {
+	return &rope{
+		left:       left,
+		right:      right,
+		chunk:      chunk,
+		priority:   priority,
+		chunkLines: chunkLines,
+		size:       left.Len() + len(chunk) + right.Len(),
+		lines:      left.Lines() + chunkLines + right.Lines(),
+	}
+}
+
+// buildRope returns a rope with the given content. The content must not be
+// modified afterwards.
+func buildRope(s []byte) *rope {
+	// Build the treap from its chunks in order, keeping the nodes on the
+	// right edge of the tree on a stack.
+	var stack []*rope
+
+	for len(s) > 0 {
+		n := min(len(s), maxChunkSize)
+		chunk := s[:n:n]
+		s = s[n:]
+
+		node := &rope{chunk: chunk, priority: rand.Uint32(), chunkLines: bytes.Count(chunk, []byte{'\n'})} //nolint:gosec // not used for security
+
+		var last *rope
+		for len(stack) > 0 && stack[len(stack)-1].priority < node.priority {
+			last = stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+		}
+
+		node.left = last
+		if len(stack) > 0 {
+			stack[len(stack)-1].right = node
+		}
+
+		stack = append(stack, node)
+	}
+
+	if len(stack) == 0 {
+		return nil
+	}
+
+	root := stack[0]
+	root.update()
+
+	return root
+}
+
+// update computes the sizes of a subtree that is still being built.
+func (r *rope) update() {
+	if r == nil {
+		return
+	}
+
+	r.left.update()
+	r.right.update()
+	r.size = r.left.Len() + len(r.chunk) + r.right.Len()
+	r.lines = r.left.Lines() + r.chunkLines + r.right.Lines()
+}
+
+// Len returns the number of bytes in the rope.
+func (r *rope) Len() int {
+	if r == nil {
+		return 0
+	}
+
+	return r.size
+}
+
+// Lines returns the number of line breaks in the rope.
+func (r *rope) Lines() int {
+	if r == nil {
+		return 0
+	}
+
+	return r.lines
+}
+
+// Replace returns a rope with the bytes between start and end replaced by s.
+// The content of s must not be modified afterwards.
+func (r *rope) Replace(start, end int, s []byte) *rope {
+	left, rest := r.split(start)
+	_, right := rest.split(end - start)
+
+	return merge(merge(left, buildRope(s)), right)
+}
+
+// split returns the first k bytes and the remaining bytes of the rope.
+func (r *rope) split(k int) (*rope, *rope) {
+	if r == nil {
+		return nil, nil
+	}
+
+	leftSize := r.left.Len()
+
+	switch {
+	case k <= leftSize:
+		left, right := r.left.split(k)
+		return left, newRope(right, r.chunk, r.chunkLines, r.right, r.priority)
+	case k >= leftSize+len(r.chunk):
+		left, right := r.right.split(k - leftSize - len(r.chunk))
+		return newRope(r.left, r.chunk, r.chunkLines, left, r.priority), right
+	default:
+		i := k - leftSize
+		lines := bytes.Count(r.chunk[:i], []byte{'\n'})
+
+		return newRope(r.left, r.chunk[:i:i], lines, nil, r.priority),
+			newRope(nil, r.chunk[i:], r.chunkLines-lines, r.right, r.priority)
+	}
+}
+
+// merge returns the concatenation of two ropes.
+func merge(a, b *rope) *rope {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case a.priority >= b.priority:
+		return newRope(a.left, a.chunk, a.chunkLines, merge(a.right, b), a.priority)
+	default:
+		return newRope(merge(a, b.left), b.chunk, b.chunkLines, b.right, b.priority)
+	}
+}
+
+// LineStart returns the offset of the first byte after the given number of
+// line breaks. The number must not be greater than the number of line breaks
+// in the rope.
+func (r *rope) LineStart(line int) int {
+	offset := 0
+
+	for line > 0 {
+		switch leftLines := r.left.Lines(); {
+		case line <= leftLines:
+			r = r.left
+		case line <= leftLines+r.chunkLines:
+			line -= leftLines
+			offset += r.left.Len()
+
+			chunk := r.chunk
+			for {
+				i := bytes.IndexByte(chunk, '\n') + 1
+				offset += i
+				chunk = chunk[i:]
+
+				if line--; line == 0 {
+					return offset
+				}
+			}
+		default:
+			line -= leftLines + r.chunkLines
+			offset += r.left.Len() + len(r.chunk)
+			r = r.right
+		}
+	}
+
+	return offset
+}
+
+// LinesBefore returns the number of line breaks before the given offset.
+func (r *rope) LinesBefore(offset int) int {
+	lines := 0
+
+	for r != nil {
+		switch leftSize := r.left.Len(); {
+		case offset <= leftSize:
+			r = r.left
+		case offset <= leftSize+len(r.chunk):
+			return lines + r.left.Lines() + bytes.Count(r.chunk[:offset-leftSize], []byte{'\n'})
+		default:
+			offset -= leftSize + len(r.chunk)
+			lines += r.left.Lines() + r.chunkLines
+			r = r.right
+		}
+	}
+
+	return lines
+}
+
+// AppendRange appends the bytes between start and end to dst.
+func (r *rope) AppendRange(dst []byte, start, end int) []byte {
+	if r == nil || start >= end {
+		return dst
+	}
+
+	leftSize := r.left.Len()
+	chunkEnd := leftSize + len(r.chunk)
+
+	if start < leftSize {
+		dst = r.left.AppendRange(dst, start, min(end, leftSize))
+	}
+
+	if start < chunkEnd && end > leftSize {
+		dst = append(dst, r.chunk[max(start-leftSize, 0):min(end, chunkEnd)-leftSize]...)
+	}
+
+	if end > chunkEnd {
+		dst = r.right.AppendRange(dst, max(start-chunkEnd, 0), end-chunkEnd)
+	}
+
+	return dst
+}
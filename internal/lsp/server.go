@@ -1,12 +1,13 @@
 package lsp
 
 import (
-	"bufio"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
+	"strings"
+	"time"
 
 	"github.com/armsnyder/openapi-language-server/internal/lsp/jsonrpc"
 	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
@@ -19,30 +20,135 @@ type Server struct {
 	Writer     io.Writer
 	Handler    Handler
 	ServerInfo types.ServerInfo
+
+	// Stream reads and writes messages for transports that frame messages
+	// themselves. If it is nil, messages are framed with Content-Length headers
+	// over the Reader and Writer.
+	Stream jsonrpc.Stream
+
+	// ConfigurationSection is the section of the client's settings that
+	// holds the settings of the server. If it is set, settings are pulled
+	// from clients that support workspace/configuration, and settings that
+	// clients push are narrowed to the section.
+	ConfigurationSection string
+
+	// WatchedFiles are glob patterns of files that the handler is notified
+	// about when they change on disk, if the client supports watching files.
+	WatchedFiles []string
+
+	// pullConfiguration is true if the client supports
+	// workspace/configuration.
+	pullConfiguration bool
+
+	// watchFiles is true if the client supports registering file watchers.
+	watchFiles bool
+
+	// refreshInlayHints and refreshCodeLenses are true if the client can be
+	// asked to request inlay hints and code lenses again.
+	refreshInlayHints bool
+	refreshCodeLenses bool
+
+	// pending holds the callbacks of requests that were sent to the client
+	// and not yet answered, by ID.
+	pending map[types.RequestID]func(result json.RawMessage) error
+	lastID  int
+
+	// trace is the level of tracing that the client asked for.
+	trace types.TraceValue
+
+	// received is when the message that is being handled was received.
+	received time.Time
 }
 
 // Run is a blocking function that reads from the server's Reader, processes
 // requests, and writes responses to the server's Writer. It returns an error
 // if the server stops unexpectedly.
 func (s *Server) Run() error {
-	scanner := bufio.NewScanner(s.Reader)
-	scanner.Buffer(nil, 10*1024*1024)
-	scanner.Split(jsonrpc.Split)
+	if s.Stream == nil {
+		s.Stream = jsonrpc.NewHeaderStream(s.Reader, s.Writer)
+	}
 
 	log.Println("LSP server started")
 
-	for scanner.Scan() {
-		if err := s.handleRequestPayload(scanner.Bytes()); err != nil {
-			if errors.Is(err, errShutdown) {
-				log.Println("LSP server shutting down")
+	// Messages are read in the background, one at a time, so that tasks of
+	// the handler can run while the server waits for the client.
+	reads := make(chan readResult, 1)
+	next := make(chan struct{})
+	defer close(next)
+
+	go func() {
+		for range next {
+			payload, err := s.Stream.ReadMessage()
+			reads <- readResult{payload: payload, err: err}
+		}
+	}()
+
+	next <- struct{}{}
+
+	tasks := s.Handler.Tasks()
+
+	for {
+		select {
+		case read := <-reads:
+			if errors.Is(read.err, io.EOF) {
 				return nil
 			}
+			if read.err != nil {
+				return read.err
+			}
 
-			return err
+			if err := s.handleRequestPayload(read.payload); err != nil {
+				if errors.Is(err, errShutdown) {
+					log.Println("LSP server shutting down")
+					return nil
+				}
+
+				return err
+			}
+
+			next <- struct{}{}
+
+		case task := <-tasks:
+			if err := s.runTask(task); err != nil {
+				return err
+			}
 		}
 	}
+}
 
-	return scanner.Err()
+type readResult struct {
+	payload []byte
+	err     error
+}
+
+// runTask runs a task that the handler finished in the background, and
+// publishes its results. Inlay hints and code lenses may depend on the results
+// too, such as a fetched document, so the client is asked to request them
+// again.
+func (s *Server) runTask(task func()) error {
+	task()
+
+	if err := s.publishDiagnostics(); err != nil {
+		return err
+	}
+
+	for _, message := range s.Handler.Messages() {
+		s.sendMessage(message)
+	}
+
+	ignore := func(json.RawMessage) error { return nil }
+
+	if s.refreshInlayHints {
+		// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#workspace_inlayHint_refresh
+		s.request("workspace/inlayHint/refresh", nil, ignore)
+	}
+
+	if s.refreshCodeLenses {
+		// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#codeLens_refresh
+		s.request("workspace/codeLens/refresh", nil, ignore)
+	}
+
+	return nil
 }
 
 func (s *Server) handleRequestPayload(payload []byte) (err error) {
@@ -57,11 +163,53 @@ func (s *Server) handleRequestPayload(payload []byte) (err error) {
 		return errors.New("unknown jsonrpc version")
 	}
 
-	if request.Method == "" {
+	switch {
+	case request.Method != "":
+		s.received = time.Now()
+		s.traceReceived(request)
+
+		err = s.handleRequest(request)
+	case request.ID != nil:
+		// Messages with an ID but no method are responses to requests that
+		// the server sent.
+		err = s.handleResponse(payload)
+	default:
 		return errors.New("request is missing a method")
 	}
 
-	return s.handleRequest(request)
+	for _, message := range s.Handler.Messages() {
+		s.sendMessage(message)
+	}
+
+	return err
+}
+
+// handleResponse handles the response of the client to a request that the
+// server sent.
+func (s *Server) handleResponse(payload []byte) error {
+	var response struct {
+		ID     types.RequestID      `json:"id"`
+		Result json.RawMessage      `json:"result"`
+		Error  *types.ResponseError `json:"error"`
+	}
+
+	if err := json.Unmarshal(payload, &response); err != nil {
+		return err
+	}
+
+	callback, ok := s.pending[response.ID]
+	if !ok {
+		log.Printf("Warning: Response to unknown request %s", response.ID.String())
+		return nil
+	}
+	delete(s.pending, response.ID)
+
+	if response.Error != nil {
+		log.Printf("Warning: Request %s failed: %s", response.ID.String(), response.Error.Message)
+		return nil
+	}
+
+	return callback(response.Result)
 }
 
 var errShutdown = errors.New("shutdown")
@@ -77,6 +225,16 @@ func (s *Server) handleRequest(request types.RequestMessage) error {
 
 		log.Printf("Connected to: %s %s", params.ClientInfo.Name, params.ClientInfo.Version)
 
+		s.trace = params.Trace
+		s.pullConfiguration = params.Capabilities.Workspace.Configuration && s.ConfigurationSection != ""
+		s.watchFiles = params.Capabilities.Workspace.DidChangeWatchedFiles.DynamicRegistration && len(s.WatchedFiles) > 0
+		s.refreshInlayHints = params.Capabilities.Workspace.InlayHint.RefreshSupport
+		s.refreshCodeLenses = params.Capabilities.Workspace.CodeLens.RefreshSupport
+
+		if err := s.Handler.HandleInitialize(params); err != nil {
+			return err
+		}
+
 		s.write(request, types.InitializeResult{
 			Capabilities: s.Handler.Capabilities(),
 			ServerInfo:   s.ServerInfo,
@@ -84,13 +242,77 @@ func (s *Server) handleRequest(request types.RequestMessage) error {
 
 	// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#initialized
 	case "initialized":
-		// No-op
+		s.requestConfiguration()
+		s.registerFileWatchers()
+
+	// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#setTrace
+	case "$/setTrace":
+		var params types.SetTraceParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return fmt.Errorf("invalid $/setTrace params: %w", err)
+		}
+
+		s.trace = params.Value
 
 	// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#shutdown
 	case "shutdown":
 		s.write(request, nil)
 		return errShutdown
 
+	// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#workspace_didChangeConfiguration
+	case "workspace/didChangeConfiguration":
+		var params types.DidChangeConfigurationParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return fmt.Errorf("invalid workspace/didChangeConfiguration params: %w", err)
+		}
+
+		// Clients that support pulling settings often leave them out of the
+		// notification, so they are pulled instead.
+		if s.pullConfiguration {
+			s.requestConfiguration()
+			break
+		}
+
+		params.Settings = s.settingsSection(params.Settings)
+
+		if err := s.Handler.HandleDidChangeConfiguration(params); err != nil {
+			return err
+		}
+
+		if err := s.publishDiagnostics(); err != nil {
+			return err
+		}
+
+	// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#workspace_didChangeWatchedFiles
+	case "workspace/didChangeWatchedFiles":
+		var params types.DidChangeWatchedFilesParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return fmt.Errorf("invalid workspace/didChangeWatchedFiles params: %w", err)
+		}
+
+		if err := s.Handler.HandleDidChangeWatchedFiles(params); err != nil {
+			return err
+		}
+
+		if err := s.publishDiagnostics(); err != nil {
+			return err
+		}
+
+	// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#workspace_didChangeWorkspaceFolders
+	case "workspace/didChangeWorkspaceFolders":
+		var params types.DidChangeWorkspaceFoldersParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return fmt.Errorf("invalid workspace/didChangeWorkspaceFolders params: %w", err)
+		}
+
+		if err := s.Handler.HandleDidChangeWorkspaceFolders(params); err != nil {
+			return err
+		}
+
+		if err := s.publishDiagnostics(); err != nil {
+			return err
+		}
+
 	// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#textDocument_didOpen
 	case "textDocument/didOpen":
 		var params types.DidOpenTextDocumentParams
@@ -102,6 +324,10 @@ func (s *Server) handleRequest(request types.RequestMessage) error {
 			return err
 		}
 
+		if err := s.publishDiagnostics(); err != nil {
+			return err
+		}
+
 	// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#textDocument_didClose
 	case "textDocument/didClose":
 		var params types.DidCloseTextDocumentParams
@@ -113,6 +339,10 @@ func (s *Server) handleRequest(request types.RequestMessage) error {
 			return err
 		}
 
+		if err := s.publishDiagnostics(); err != nil {
+			return err
+		}
+
 	// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#textDocument_didChange
 	case "textDocument/didChange":
 		var params types.DidChangeTextDocumentParams
@@ -124,6 +354,39 @@ func (s *Server) handleRequest(request types.RequestMessage) error {
 			return err
 		}
 
+		if err := s.publishDiagnostics(); err != nil {
+			return err
+		}
+
+	// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#textDocument_didSave
+	case "textDocument/didSave":
+		var params types.DidSaveTextDocumentParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return fmt.Errorf("invalid textDocument/didSave params: %w", err)
+		}
+
+		if err := s.Handler.HandleSave(params); err != nil {
+			return err
+		}
+
+		if err := s.publishDiagnostics(); err != nil {
+			return err
+		}
+
+	// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#textDocument_willSaveWaitUntil
+	case "textDocument/willSaveWaitUntil":
+		var params types.WillSaveTextDocumentParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return fmt.Errorf("invalid textDocument/willSaveWaitUntil params: %w", err)
+		}
+
+		result, err := s.Handler.HandleWillSaveWaitUntil(params)
+		if err != nil {
+			return err
+		}
+
+		s.write(request, result)
+
 	// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#textDocument_definition
 	case "textDocument/definition":
 		var params types.DefinitionParams
@@ -152,15 +415,261 @@ func (s *Server) handleRequest(request types.RequestMessage) error {
 
 		s.write(request, locations)
 
+	// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#textDocument_hover
+	case "textDocument/hover":
+		var params types.HoverParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return fmt.Errorf("invalid textDocument/hover params: %w", err)
+		}
+
+		hover, err := s.Handler.HandleHover(params)
+		if err != nil {
+			return err
+		}
+
+		s.write(request, hover)
+
+	// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#textDocument_codeAction
+	case "textDocument/codeAction":
+		var params types.CodeActionParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return fmt.Errorf("invalid textDocument/codeAction params: %w", err)
+		}
+
+		actions, err := s.Handler.HandleCodeAction(params)
+		if err != nil {
+			return err
+		}
+
+		s.write(request, actions)
+
+	// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#textDocument_prepareTypeHierarchy
+	case "textDocument/prepareTypeHierarchy":
+		var params types.TypeHierarchyPrepareParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return fmt.Errorf("invalid textDocument/prepareTypeHierarchy params: %w", err)
+		}
+
+		items, err := s.Handler.HandlePrepareTypeHierarchy(params)
+		if err != nil {
+			return err
+		}
+
+		s.write(request, items)
+
+	// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#typeHierarchy_supertypes
+	case "typeHierarchy/supertypes":
+		var params types.TypeHierarchySupertypesParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return fmt.Errorf("invalid typeHierarchy/supertypes params: %w", err)
+		}
+
+		items, err := s.Handler.HandleTypeHierarchySupertypes(params)
+		if err != nil {
+			return err
+		}
+
+		s.write(request, items)
+
+	// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#typeHierarchy_subtypes
+	case "typeHierarchy/subtypes":
+		var params types.TypeHierarchySubtypesParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return fmt.Errorf("invalid typeHierarchy/subtypes params: %w", err)
+		}
+
+		items, err := s.Handler.HandleTypeHierarchySubtypes(params)
+		if err != nil {
+			return err
+		}
+
+		s.write(request, items)
+
+	// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#textDocument_prepareCallHierarchy
+	case "textDocument/prepareCallHierarchy":
+		var params types.CallHierarchyPrepareParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return fmt.Errorf("invalid textDocument/prepareCallHierarchy params: %w", err)
+		}
+
+		items, err := s.Handler.HandlePrepareCallHierarchy(params)
+		if err != nil {
+			return err
+		}
+
+		s.write(request, items)
+
+	// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#callHierarchy_incomingCalls
+	case "callHierarchy/incomingCalls":
+		var params types.CallHierarchyIncomingCallsParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return fmt.Errorf("invalid callHierarchy/incomingCalls params: %w", err)
+		}
+
+		calls, err := s.Handler.HandleCallHierarchyIncomingCalls(params)
+		if err != nil {
+			return err
+		}
+
+		s.write(request, calls)
+
+	// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#callHierarchy_outgoingCalls
+	case "callHierarchy/outgoingCalls":
+		var params types.CallHierarchyOutgoingCallsParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return fmt.Errorf("invalid callHierarchy/outgoingCalls params: %w", err)
+		}
+
+		calls, err := s.Handler.HandleCallHierarchyOutgoingCalls(params)
+		if err != nil {
+			return err
+		}
+
+		s.write(request, calls)
+
+	// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#textDocument_codeLens
+	case "textDocument/codeLens":
+		var params types.CodeLensParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return fmt.Errorf("invalid textDocument/codeLens params: %w", err)
+		}
+
+		lenses, err := s.Handler.HandleCodeLens(params)
+		if err != nil {
+			return err
+		}
+
+		s.write(request, lenses)
+
+	// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#codeLens_resolve
+	case "codeLens/resolve":
+		var params types.CodeLens
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return fmt.Errorf("invalid codeLens/resolve params: %w", err)
+		}
+
+		lens, err := s.Handler.HandleCodeLensResolve(params)
+		if err != nil {
+			return err
+		}
+
+		s.write(request, lens)
+
+	// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#textDocument_inlayHint
+	case "textDocument/inlayHint":
+		var params types.InlayHintParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return fmt.Errorf("invalid textDocument/inlayHint params: %w", err)
+		}
+
+		hints, err := s.Handler.HandleInlayHint(params)
+		if err != nil {
+			return err
+		}
+
+		s.write(request, hints)
+
+	// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#textDocument_documentLink
+	case "textDocument/documentLink":
+		var params types.DocumentLinkParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return fmt.Errorf("invalid textDocument/documentLink params: %w", err)
+		}
+
+		links, err := s.Handler.HandleDocumentLink(params)
+		if err != nil {
+			return err
+		}
+
+		s.write(request, links)
+
+	// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#textDocument_foldingRange
+	case "textDocument/foldingRange":
+		var params types.FoldingRangeParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return fmt.Errorf("invalid textDocument/foldingRange params: %w", err)
+		}
+
+		ranges, err := s.Handler.HandleFoldingRange(params)
+		if err != nil {
+			return err
+		}
+
+		s.write(request, ranges)
+
+	// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#textDocument_selectionRange
+	case "textDocument/selectionRange":
+		var params types.SelectionRangeParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return fmt.Errorf("invalid textDocument/selectionRange params: %w", err)
+		}
+
+		ranges, err := s.Handler.HandleSelectionRange(params)
+		if err != nil {
+			return err
+		}
+
+		s.write(request, ranges)
+
+	// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#textDocument_formatting
+	case "textDocument/formatting":
+		var params types.DocumentFormattingParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return fmt.Errorf("invalid textDocument/formatting params: %w", err)
+		}
+
+		edits, err := s.Handler.HandleFormatting(params)
+		if err != nil {
+			return err
+		}
+
+		s.write(request, edits)
+
+	// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#textDocument_rangeFormatting
+	case "textDocument/rangeFormatting":
+		var params types.DocumentRangeFormattingParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return fmt.Errorf("invalid textDocument/rangeFormatting params: %w", err)
+		}
+
+		edits, err := s.Handler.HandleRangeFormatting(params)
+		if err != nil {
+			return err
+		}
+
+		s.write(request, edits)
+
+	// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#workspace_executeCommand
+	case "workspace/executeCommand":
+		var params types.ExecuteCommandParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return fmt.Errorf("invalid workspace/executeCommand params: %w", err)
+		}
+
+		result, err := s.Handler.HandleExecuteCommand(params)
+		if err != nil {
+			return err
+		}
+
+		s.write(request, result)
+
 	default:
 		log.Printf("Warning: Request with unknown method %q", request.Method)
+
+		// Notifications starting with $/ are optional, so they are not
+		// worth a warning.
+		if request.ID != nil || !strings.HasPrefix(request.Method, "$/") {
+			s.sendMessage(Message{Type: types.MessageWarning, Message: fmt.Sprintf("Request with unknown method %q", request.Method)})
+		}
 	}
 
 	return nil
 }
 
 func (s *Server) write(request types.RequestMessage, result any) {
-	if err := jsonrpc.Write(s.Writer, types.ResponseMessage{
+	s.traceResponse(request, result)
+
+	if err := s.send(types.ResponseMessage{
 		JSONRPC: "2.0",
 		ID:      request.ID,
 		Result:  result,
@@ -168,3 +677,196 @@ func (s *Server) write(request types.RequestMessage, result any) {
 		log.Printf("Error writing response: %v", err)
 	}
 }
+
+// requestConfiguration pulls the settings of the server from the client, if
+// the client supports it.
+func (s *Server) requestConfiguration() {
+	if !s.pullConfiguration {
+		return
+	}
+
+	params := types.ConfigurationParams{
+		Items: []types.ConfigurationItem{{Section: s.ConfigurationSection}},
+	}
+
+	// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#workspace_configuration
+	s.request("workspace/configuration", params, func(result json.RawMessage) error {
+		var settings []json.RawMessage
+		if err := json.Unmarshal(result, &settings); err != nil {
+			return fmt.Errorf("invalid workspace/configuration result: %w", err)
+		}
+
+		if len(settings) == 0 || string(settings[0]) == "null" {
+			return nil
+		}
+
+		if err := s.Handler.HandleDidChangeConfiguration(types.DidChangeConfigurationParams{Settings: settings[0]}); err != nil {
+			return err
+		}
+
+		return s.publishDiagnostics()
+	})
+}
+
+// registerFileWatchers asks the client to watch the files that the handler is
+// interested in, if the client supports it.
+func (s *Server) registerFileWatchers() {
+	if !s.watchFiles {
+		return
+	}
+
+	watchers := make([]types.FileSystemWatcher, len(s.WatchedFiles))
+	for i, pattern := range s.WatchedFiles {
+		watchers[i] = types.FileSystemWatcher{GlobPattern: pattern}
+	}
+
+	// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#client_registerCapability
+	s.request("client/registerCapability", types.RegistrationParams{
+		Registrations: []types.Registration{{
+			ID:              "watched-files",
+			Method:          "workspace/didChangeWatchedFiles",
+			RegisterOptions: types.DidChangeWatchedFilesRegistrationOptions{Watchers: watchers},
+		}},
+	}, func(json.RawMessage) error {
+		return nil
+	})
+}
+
+// settingsSection returns the section of the server from settings that a
+// client pushed, if the settings have one. Otherwise the settings are assumed
+// to belong to the server already.
+func (s *Server) settingsSection(settings json.RawMessage) json.RawMessage {
+	if s.ConfigurationSection == "" {
+		return settings
+	}
+
+	var sections map[string]json.RawMessage
+	if err := json.Unmarshal(settings, &sections); err != nil {
+		return settings
+	}
+
+	if section, ok := sections[s.ConfigurationSection]; ok {
+		return section
+	}
+
+	return settings
+}
+
+// request sends a request to the client. The callback is called with the
+// result when the client responds.
+func (s *Server) request(method string, params any, callback func(result json.RawMessage) error) {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		log.Printf("Error encoding %s request: %v", method, err)
+		return
+	}
+
+	s.lastID++
+	id := types.RequestID{IntVal: s.lastID}
+
+	if s.pending == nil {
+		s.pending = make(map[types.RequestID]func(json.RawMessage) error)
+	}
+	s.pending[id] = callback
+
+	if err := s.send(types.RequestMessage{
+		JSONRPC: "2.0",
+		ID:      &id,
+		Method:  method,
+		Params:  raw,
+	}); err != nil {
+		log.Printf("Error writing request: %v", err)
+	}
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#textDocument_publishDiagnostics
+func (s *Server) publishDiagnostics() error {
+	diagnostics, err := s.Handler.Diagnostics()
+	if err != nil {
+		return err
+	}
+
+	for _, params := range diagnostics {
+		s.notify("textDocument/publishDiagnostics", params)
+	}
+
+	return nil
+}
+
+func (s *Server) notify(method string, params any) {
+	if err := s.send(types.NotificationMessage{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  params,
+	}); err != nil {
+		log.Printf("Error writing notification: %v", err)
+	}
+}
+
+func (s *Server) send(message any) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	return s.Stream.WriteMessage(payload)
+}
+
+// sendMessage sends a message for the user to the client, either to show or to
+// log.
+func (s *Server) sendMessage(message Message) {
+	if message.Show {
+		// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#window_showMessage
+		s.notify("window/showMessage", types.ShowMessageParams{Type: message.Type, Message: message.Message})
+		return
+	}
+
+	// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#window_logMessage
+	s.notify("window/logMessage", types.LogMessageParams{Type: message.Type, Message: message.Message})
+}
+
+// traceReceived sends a trace of a message from the client, if tracing is on.
+func (s *Server) traceReceived(request types.RequestMessage) {
+	if s.trace != types.TraceMessages && s.trace != types.TraceVerbose {
+		return
+	}
+
+	params := types.LogTraceParams{}
+
+	if request.ID != nil {
+		params.Message = fmt.Sprintf("Received request '%s - (%s)'.", request.Method, request.ID)
+	} else {
+		params.Message = fmt.Sprintf("Received notification '%s'.", request.Method)
+	}
+
+	if s.trace == types.TraceVerbose && len(request.Params) > 0 {
+		params.Verbose = "Params: " + string(request.Params)
+	}
+
+	s.logTrace(params)
+}
+
+// traceResponse sends a trace of a response with the time that the request
+// took, if tracing is on.
+func (s *Server) traceResponse(request types.RequestMessage, result any) {
+	if s.trace != types.TraceMessages && s.trace != types.TraceVerbose {
+		return
+	}
+
+	params := types.LogTraceParams{
+		Message: fmt.Sprintf("Sending response '%s - (%s)'. Processing request took %dms.", request.Method, request.ID, time.Since(s.received).Milliseconds()),
+	}
+
+	if s.trace == types.TraceVerbose {
+		if payload, err := json.Marshal(result); err == nil {
+			params.Verbose = "Result: " + string(payload)
+		}
+	}
+
+	s.logTrace(params)
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#logTrace
+func (s *Server) logTrace(params types.LogTraceParams) {
+	s.notify("$/logTrace", params)
+}
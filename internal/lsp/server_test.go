@@ -3,8 +3,12 @@ package lsp_test
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
+	"reflect"
+	"regexp"
+	"strings"
 	"testing"
 
 	"go.uber.org/mock/gomock"
@@ -25,6 +29,7 @@ func TestServer_Basic(t *testing.T) {
 		{
 			name: "initialize with default capabilities",
 			setup: func(t *testing.T, s *Server, h *testutil.MockHandler) {
+				h.EXPECT().HandleInitialize(types.InitializeParams{}).Return(nil)
 				h.EXPECT().Capabilities().Return(types.ServerCapabilities{})
 			},
 			requests: []string{
@@ -37,6 +42,7 @@ func TestServer_Basic(t *testing.T) {
 		{
 			name: "initialize with all capabilities",
 			setup: func(t *testing.T, s *Server, h *testutil.MockHandler) {
+				h.EXPECT().HandleInitialize(types.InitializeParams{}).Return(nil)
 				h.EXPECT().Capabilities().Return(types.ServerCapabilities{
 					TextDocumentSync: types.TextDocumentSyncOptions{
 						OpenClose: true,
@@ -77,17 +83,76 @@ func TestServer_Basic(t *testing.T) {
 						Text: "hello world",
 					},
 				}).Return(nil)
+				h.EXPECT().Diagnostics().Return(nil, nil)
 			},
 			requests: []string{
 				`{"jsonrpc":"2.0","id":1,"method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///foo.txt","text":"hello world"}}}`,
 			},
 		},
+		{
+			name: "initialize with options",
+			setup: func(t *testing.T, s *Server, h *testutil.MockHandler) {
+				h.EXPECT().HandleInitialize(types.InitializeParams{
+					RootURI:               "file:///workspace",
+					InitializationOptions: json.RawMessage(`{"tabSize":4}`),
+					WorkspaceFolders:      []types.WorkspaceFolder{{URI: "file:///workspace", Name: "workspace"}},
+				}).Return(nil)
+				h.EXPECT().Capabilities().Return(types.ServerCapabilities{})
+			},
+			requests: []string{
+				`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"rootUri":"file:///workspace","initializationOptions":{"tabSize":4},"workspaceFolders":[{"uri":"file:///workspace","name":"workspace"}]}}`,
+			},
+			wantResponses: []string{
+				`{"jsonrpc":"2.0","id":1,"result":{"capabilities":{"textDocumentSync":{"change":0}},"serverInfo":{"name":"test-lsp","version":"0.1.0"}}}`,
+			},
+		},
+		{
+			name: "workspace/didChangeConfiguration",
+			setup: func(t *testing.T, s *Server, h *testutil.MockHandler) {
+				h.EXPECT().HandleDidChangeConfiguration(types.DidChangeConfigurationParams{
+					Settings: json.RawMessage(`{"tabSize":4}`),
+				}).Return(nil)
+				h.EXPECT().Diagnostics().Return(nil, nil)
+			},
+			requests: []string{
+				`{"jsonrpc":"2.0","method":"workspace/didChangeConfiguration","params":{"settings":{"tabSize":4}}}`,
+			},
+		},
+		{
+			name: "workspace/didChangeConfiguration with section",
+			setup: func(t *testing.T, s *Server, h *testutil.MockHandler) {
+				s.ConfigurationSection = "test"
+				h.EXPECT().HandleDidChangeConfiguration(types.DidChangeConfigurationParams{
+					Settings: json.RawMessage(`{"tabSize":4}`),
+				}).Return(nil)
+				h.EXPECT().Diagnostics().Return(nil, nil)
+			},
+			requests: []string{
+				`{"jsonrpc":"2.0","method":"workspace/didChangeConfiguration","params":{"settings":{"other":{},"test":{"tabSize":4}}}}`,
+			},
+		},
+		{
+			name: "workspace/didChangeWorkspaceFolders",
+			setup: func(t *testing.T, s *Server, h *testutil.MockHandler) {
+				h.EXPECT().HandleDidChangeWorkspaceFolders(types.DidChangeWorkspaceFoldersParams{
+					Event: types.WorkspaceFoldersChangeEvent{
+						Added:   []types.WorkspaceFolder{{URI: "file:///pets", Name: "pets"}},
+						Removed: []types.WorkspaceFolder{{URI: "file:///users", Name: "users"}},
+					},
+				}).Return(nil)
+				h.EXPECT().Diagnostics().Return(nil, nil)
+			},
+			requests: []string{
+				`{"jsonrpc":"2.0","method":"workspace/didChangeWorkspaceFolders","params":{"event":{"added":[{"uri":"file:///pets","name":"pets"}],"removed":[{"uri":"file:///users","name":"users"}]}}}`,
+			},
+		},
 		{
 			name: "textDocument/didClose",
 			setup: func(t *testing.T, s *Server, h *testutil.MockHandler) {
 				h.EXPECT().HandleClose(types.DidCloseTextDocumentParams{
 					TextDocument: types.TextDocumentIdentifier{URI: "file:///foo.txt"},
 				}).Return(nil)
+				h.EXPECT().Diagnostics().Return(nil, nil)
 			},
 			requests: []string{
 				`{"jsonrpc":"2.0","id":1,"method":"textDocument/didClose","params":{"textDocument":{"uri":"file:///foo.txt"}}}`,
@@ -97,11 +162,15 @@ func TestServer_Basic(t *testing.T) {
 			name: "textDocument/didChange full sync",
 			setup: func(t *testing.T, s *Server, h *testutil.MockHandler) {
 				h.EXPECT().HandleChange(types.DidChangeTextDocumentParams{
-					TextDocument: types.TextDocumentIdentifier{URI: "file:///foo.txt"},
+					TextDocument: types.VersionedTextDocumentIdentifier{
+						TextDocumentIdentifier: types.TextDocumentIdentifier{URI: "file:///foo.txt"},
+						Version:                42,
+					},
 					ContentChanges: []types.TextDocumentContentChangeEvent{
 						{Text: "hello world"},
 					},
 				}).Return(nil)
+				h.EXPECT().Diagnostics().Return(nil, nil)
 			},
 			requests: []string{
 				`{"jsonrpc":"2.0","id":1,"method":"textDocument/didChange","params":{"textDocument":{"uri":"file:///foo.txt","version":42},"contentChanges":[{"text":"hello world"}]}}`,
@@ -111,17 +180,51 @@ func TestServer_Basic(t *testing.T) {
 			name: "textDocument/didChange incremental sync",
 			setup: func(t *testing.T, s *Server, h *testutil.MockHandler) {
 				h.EXPECT().HandleChange(types.DidChangeTextDocumentParams{
-					TextDocument: types.TextDocumentIdentifier{URI: "file:///foo.txt"},
+					TextDocument: types.VersionedTextDocumentIdentifier{
+						TextDocumentIdentifier: types.TextDocumentIdentifier{URI: "file:///foo.txt"},
+						Version:                42,
+					},
 					ContentChanges: []types.TextDocumentContentChangeEvent{{
 						Text:  "carl",
 						Range: &types.Range{Start: types.Position{Line: 0, Character: 6}, End: types.Position{Line: 0, Character: 10}},
 					}},
 				}).Return(nil)
+				h.EXPECT().Diagnostics().Return(nil, nil)
 			},
 			requests: []string{
 				`{"jsonrpc":"2.0","id":1,"method":"textDocument/didChange","params":{"textDocument":{"uri":"file:///foo.txt","version":42},"contentChanges":[{"text":"carl","range":{"start":{"line":0,"character":6},"end":{"line":0,"character":10}}}]}}`,
 			},
 		},
+		{
+			name: "textDocument/didSave",
+			setup: func(t *testing.T, s *Server, h *testutil.MockHandler) {
+				h.EXPECT().HandleSave(types.DidSaveTextDocumentParams{
+					TextDocument: types.TextDocumentIdentifier{URI: "file:///foo.txt"},
+				}).Return(nil)
+				h.EXPECT().Diagnostics().Return(nil, nil)
+			},
+			requests: []string{
+				`{"jsonrpc":"2.0","method":"textDocument/didSave","params":{"textDocument":{"uri":"file:///foo.txt"}}}`,
+			},
+		},
+		{
+			name: "textDocument/willSaveWaitUntil",
+			setup: func(t *testing.T, s *Server, h *testutil.MockHandler) {
+				h.EXPECT().HandleWillSaveWaitUntil(types.WillSaveTextDocumentParams{
+					TextDocument: types.TextDocumentIdentifier{URI: "file:///foo.yaml"},
+					Reason:       types.SaveManual,
+				}).Return([]types.TextEdit{{
+					Range:   types.Range{Start: types.Position{Line: 1, Character: 0}, End: types.Position{Line: 1, Character: 4}},
+					NewText: "  ",
+				}}, nil)
+			},
+			requests: []string{
+				`{"jsonrpc":"2.0","id":1,"method":"textDocument/willSaveWaitUntil","params":{"textDocument":{"uri":"file:///foo.yaml"},"reason":1}}`,
+			},
+			wantResponses: []string{
+				`{"jsonrpc":"2.0","id":1,"result":[{"range":{"start":{"line":1,"character":0},"end":{"line":1,"character":4}},"newText":"  "}]}`,
+			},
+		},
 		{
 			name: "textDocument/definition",
 			setup: func(t *testing.T, s *Server, h *testutil.MockHandler) {
@@ -164,10 +267,338 @@ func TestServer_Basic(t *testing.T) {
 				`{"jsonrpc":"2.0","id":1,"result":[{"uri":"file:///bar.txt","range":{"start":{"line":3,"character":4},"end":{"line":5,"character":6}}}]}`,
 			},
 		},
+		{
+			name: "textDocument/hover",
+			setup: func(t *testing.T, s *Server, h *testutil.MockHandler) {
+				h.EXPECT().HandleHover(types.HoverParams{
+					TextDocumentPositionParams: types.TextDocumentPositionParams{
+						TextDocument: types.TextDocumentIdentifier{URI: "file:///foo.txt"},
+						Position:     types.Position{Line: 1, Character: 2},
+					},
+				}).Return(&types.Hover{
+					Contents: types.MarkupContent{Kind: types.MarkupMarkdown, Value: "object"},
+				}, nil)
+			},
+			requests: []string{
+				`{"jsonrpc":"2.0","id":1,"method":"textDocument/hover","params":{"textDocument":{"uri":"file:///foo.txt"},"position":{"line":1,"character":2}}}`,
+			},
+			wantResponses: []string{
+				`{"jsonrpc":"2.0","id":1,"result":{"contents":{"kind":"markdown","value":"object"}}}`,
+			},
+		},
+		{
+			name: "textDocument/codeAction",
+			setup: func(t *testing.T, s *Server, h *testutil.MockHandler) {
+				h.EXPECT().HandleCodeAction(types.CodeActionParams{
+					TextDocument: types.TextDocumentIdentifier{URI: "file:///foo.txt"},
+					Range:        types.Range{Start: types.Position{Line: 1, Character: 2}, End: types.Position{Line: 1, Character: 2}},
+				}).Return([]types.CodeAction{{
+					Title: "Fix it",
+					Kind:  types.CodeActionQuickFix,
+					Edit: &types.WorkspaceEdit{Changes: map[string][]types.TextEdit{
+						"file:///foo.txt": {{NewText: "x"}},
+					}},
+				}}, nil)
+			},
+			requests: []string{
+				`{"jsonrpc":"2.0","id":1,"method":"textDocument/codeAction","params":{"textDocument":{"uri":"file:///foo.txt"},"range":{"start":{"line":1,"character":2},"end":{"line":1,"character":2}},"context":{"diagnostics":null}}}`,
+			},
+			wantResponses: []string{
+				`{"jsonrpc":"2.0","id":1,"result":[{"title":"Fix it","kind":"quickfix","edit":{"changes":{"file:///foo.txt":[{"range":{"start":{"line":0,"character":0},"end":{"line":0,"character":0}},"newText":"x"}]}}}]}`,
+			},
+		},
+		{
+			name: "textDocument/prepareTypeHierarchy",
+			setup: func(t *testing.T, s *Server, h *testutil.MockHandler) {
+				h.EXPECT().HandlePrepareTypeHierarchy(types.TypeHierarchyPrepareParams{
+					TextDocumentPositionParams: types.TextDocumentPositionParams{
+						TextDocument: types.TextDocumentIdentifier{URI: "file:///foo.yaml"},
+						Position:     types.Position{Line: 1, Character: 2},
+					},
+				}).Return([]types.TypeHierarchyItem{testTypeHierarchyItem}, nil)
+			},
+			requests: []string{
+				`{"jsonrpc":"2.0","id":1,"method":"textDocument/prepareTypeHierarchy","params":{"textDocument":{"uri":"file:///foo.yaml"},"position":{"line":1,"character":2}}}`,
+			},
+			wantResponses: []string{
+				`{"jsonrpc":"2.0","id":1,"result":[{"name":"Pet","kind":5,"uri":"file:///foo.yaml","range":{"start":{"line":1,"character":2},"end":{"line":3,"character":0}},"selectionRange":{"start":{"line":1,"character":2},"end":{"line":1,"character":5}}}]}`,
+			},
+		},
+		{
+			name: "typeHierarchy/supertypes",
+			setup: func(t *testing.T, s *Server, h *testutil.MockHandler) {
+				h.EXPECT().HandleTypeHierarchySupertypes(types.TypeHierarchySupertypesParams{Item: testTypeHierarchyItem}).Return(nil, nil)
+			},
+			requests: []string{
+				`{"jsonrpc":"2.0","id":1,"method":"typeHierarchy/supertypes","params":{"item":{"name":"Pet","kind":5,"uri":"file:///foo.yaml","range":{"start":{"line":1,"character":2},"end":{"line":3,"character":0}},"selectionRange":{"start":{"line":1,"character":2},"end":{"line":1,"character":5}}}}}`,
+			},
+			wantResponses: []string{
+				`{"jsonrpc":"2.0","id":1,"result":null}`,
+			},
+		},
+		{
+			name: "typeHierarchy/subtypes",
+			setup: func(t *testing.T, s *Server, h *testutil.MockHandler) {
+				h.EXPECT().HandleTypeHierarchySubtypes(types.TypeHierarchySubtypesParams{Item: testTypeHierarchyItem}).Return([]types.TypeHierarchyItem{testTypeHierarchyItem}, nil)
+			},
+			requests: []string{
+				`{"jsonrpc":"2.0","id":1,"method":"typeHierarchy/subtypes","params":{"item":{"name":"Pet","kind":5,"uri":"file:///foo.yaml","range":{"start":{"line":1,"character":2},"end":{"line":3,"character":0}},"selectionRange":{"start":{"line":1,"character":2},"end":{"line":1,"character":5}}}}}`,
+			},
+			wantResponses: []string{
+				`{"jsonrpc":"2.0","id":1,"result":[{"name":"Pet","kind":5,"uri":"file:///foo.yaml","range":{"start":{"line":1,"character":2},"end":{"line":3,"character":0}},"selectionRange":{"start":{"line":1,"character":2},"end":{"line":1,"character":5}}}]}`,
+			},
+		},
+		{
+			name: "textDocument/prepareCallHierarchy",
+			setup: func(t *testing.T, s *Server, h *testutil.MockHandler) {
+				h.EXPECT().HandlePrepareCallHierarchy(types.CallHierarchyPrepareParams{
+					TextDocumentPositionParams: types.TextDocumentPositionParams{
+						TextDocument: types.TextDocumentIdentifier{URI: "file:///foo.yaml"},
+						Position:     types.Position{Line: 1, Character: 2},
+					},
+				}).Return([]types.CallHierarchyItem{testCallHierarchyItem}, nil)
+			},
+			requests: []string{
+				`{"jsonrpc":"2.0","id":1,"method":"textDocument/prepareCallHierarchy","params":{"textDocument":{"uri":"file:///foo.yaml"},"position":{"line":1,"character":2}}}`,
+			},
+			wantResponses: []string{
+				`{"jsonrpc":"2.0","id":1,"result":[{"name":"GET /pets","kind":12,"uri":"file:///foo.yaml","range":{"start":{"line":1,"character":2},"end":{"line":3,"character":0}},"selectionRange":{"start":{"line":1,"character":2},"end":{"line":1,"character":5}},"data":"#/paths/~1pets/get"}]}`,
+			},
+		},
+		{
+			name: "callHierarchy/incomingCalls",
+			setup: func(t *testing.T, s *Server, h *testutil.MockHandler) {
+				h.EXPECT().HandleCallHierarchyIncomingCalls(types.CallHierarchyIncomingCallsParams{Item: testCallHierarchyItem}).Return([]types.CallHierarchyIncomingCall{{
+					From:       testCallHierarchyItem,
+					FromRanges: []types.Range{types.Range{Start: types.Position{Line: 2, Character: 4}, End: types.Position{Line: 2, Character: 8}}},
+				}}, nil)
+			},
+			requests: []string{
+				`{"jsonrpc":"2.0","id":1,"method":"callHierarchy/incomingCalls","params":{"item":{"name":"GET /pets","kind":12,"uri":"file:///foo.yaml","range":{"start":{"line":1,"character":2},"end":{"line":3,"character":0}},"selectionRange":{"start":{"line":1,"character":2},"end":{"line":1,"character":5}},"data":"#/paths/~1pets/get"}}}`,
+			},
+			wantResponses: []string{
+				`{"jsonrpc":"2.0","id":1,"result":[{"from":{"name":"GET /pets","kind":12,"uri":"file:///foo.yaml","range":{"start":{"line":1,"character":2},"end":{"line":3,"character":0}},"selectionRange":{"start":{"line":1,"character":2},"end":{"line":1,"character":5}},"data":"#/paths/~1pets/get"},"fromRanges":[{"start":{"line":2,"character":4},"end":{"line":2,"character":8}}]}]}`,
+			},
+		},
+		{
+			name: "callHierarchy/outgoingCalls",
+			setup: func(t *testing.T, s *Server, h *testutil.MockHandler) {
+				h.EXPECT().HandleCallHierarchyOutgoingCalls(types.CallHierarchyOutgoingCallsParams{Item: testCallHierarchyItem}).Return([]types.CallHierarchyOutgoingCall{{
+					To:         testCallHierarchyItem,
+					FromRanges: []types.Range{types.Range{Start: types.Position{Line: 2, Character: 4}, End: types.Position{Line: 2, Character: 8}}},
+				}}, nil)
+			},
+			requests: []string{
+				`{"jsonrpc":"2.0","id":1,"method":"callHierarchy/outgoingCalls","params":{"item":{"name":"GET /pets","kind":12,"uri":"file:///foo.yaml","range":{"start":{"line":1,"character":2},"end":{"line":3,"character":0}},"selectionRange":{"start":{"line":1,"character":2},"end":{"line":1,"character":5}},"data":"#/paths/~1pets/get"}}}`,
+			},
+			wantResponses: []string{
+				`{"jsonrpc":"2.0","id":1,"result":[{"to":{"name":"GET /pets","kind":12,"uri":"file:///foo.yaml","range":{"start":{"line":1,"character":2},"end":{"line":3,"character":0}},"selectionRange":{"start":{"line":1,"character":2},"end":{"line":1,"character":5}},"data":"#/paths/~1pets/get"},"fromRanges":[{"start":{"line":2,"character":4},"end":{"line":2,"character":8}}]}]}`,
+			},
+		},
+		{
+			name: "textDocument/codeLens",
+			setup: func(t *testing.T, s *Server, h *testutil.MockHandler) {
+				h.EXPECT().HandleCodeLens(types.CodeLensParams{
+					TextDocument: types.TextDocumentIdentifier{URI: "file:///foo.yaml"},
+				}).Return([]types.CodeLens{{
+					Range: types.Range{Start: types.Position{Line: 1, Character: 2}, End: types.Position{Line: 1, Character: 5}},
+					Data:  "#/components/schemas/Pet",
+				}}, nil)
+			},
+			requests: []string{
+				`{"jsonrpc":"2.0","id":1,"method":"textDocument/codeLens","params":{"textDocument":{"uri":"file:///foo.yaml"}}}`,
+			},
+			wantResponses: []string{
+				`{"jsonrpc":"2.0","id":1,"result":[{"range":{"start":{"line":1,"character":2},"end":{"line":1,"character":5}},"data":"#/components/schemas/Pet"}]}`,
+			},
+		},
+		{
+			name: "codeLens/resolve",
+			setup: func(t *testing.T, s *Server, h *testutil.MockHandler) {
+				h.EXPECT().HandleCodeLensResolve(types.CodeLens{
+					Range: types.Range{Start: types.Position{Line: 1, Character: 2}, End: types.Position{Line: 1, Character: 5}},
+					Data:  "#/components/schemas/Pet",
+				}).Return(types.CodeLens{
+					Range:   types.Range{Start: types.Position{Line: 1, Character: 2}, End: types.Position{Line: 1, Character: 5}},
+					Command: &types.Command{Title: "2 references", Command: "editor.action.showReferences"},
+				}, nil)
+			},
+			requests: []string{
+				`{"jsonrpc":"2.0","id":1,"method":"codeLens/resolve","params":{"range":{"start":{"line":1,"character":2},"end":{"line":1,"character":5}},"data":"#/components/schemas/Pet"}}`,
+			},
+			wantResponses: []string{
+				`{"jsonrpc":"2.0","id":1,"result":{"range":{"start":{"line":1,"character":2},"end":{"line":1,"character":5}},"command":{"title":"2 references","command":"editor.action.showReferences"}}}`,
+			},
+		},
+		{
+			name: "textDocument/inlayHint",
+			setup: func(t *testing.T, s *Server, h *testutil.MockHandler) {
+				h.EXPECT().HandleInlayHint(types.InlayHintParams{
+					TextDocument: types.TextDocumentIdentifier{URI: "file:///foo.yaml"},
+					Range:        types.Range{Start: types.Position{Line: 0, Character: 0}, End: types.Position{Line: 10, Character: 0}},
+				}).Return([]types.InlayHint{{
+					Position:    types.Position{Line: 2, Character: 20},
+					Label:       "string enum(3)",
+					Kind:        types.InlayHintKindType,
+					PaddingLeft: true,
+				}}, nil)
+			},
+			requests: []string{
+				`{"jsonrpc":"2.0","id":1,"method":"textDocument/inlayHint","params":{"textDocument":{"uri":"file:///foo.yaml"},"range":{"start":{"line":0,"character":0},"end":{"line":10,"character":0}}}}`,
+			},
+			wantResponses: []string{
+				`{"jsonrpc":"2.0","id":1,"result":[{"position":{"line":2,"character":20},"label":"string enum(3)","kind":1,"paddingLeft":true}]}`,
+			},
+		},
+		{
+			name: "textDocument/documentLink",
+			setup: func(t *testing.T, s *Server, h *testutil.MockHandler) {
+				h.EXPECT().HandleDocumentLink(types.DocumentLinkParams{
+					TextDocument: types.TextDocumentIdentifier{URI: "file:///foo.yaml"},
+				}).Return([]types.DocumentLink{{
+					Range:  types.Range{Start: types.Position{Line: 2, Character: 10}, End: types.Position{Line: 2, Character: 20}},
+					Target: "file:///pet.yaml#L3",
+				}}, nil)
+			},
+			requests: []string{
+				`{"jsonrpc":"2.0","id":1,"method":"textDocument/documentLink","params":{"textDocument":{"uri":"file:///foo.yaml"}}}`,
+			},
+			wantResponses: []string{
+				`{"jsonrpc":"2.0","id":1,"result":[{"range":{"start":{"line":2,"character":10},"end":{"line":2,"character":20}},"target":"file:///pet.yaml#L3"}]}`,
+			},
+		},
+		{
+			name: "textDocument/foldingRange",
+			setup: func(t *testing.T, s *Server, h *testutil.MockHandler) {
+				h.EXPECT().HandleFoldingRange(types.FoldingRangeParams{
+					TextDocument: types.TextDocumentIdentifier{URI: "file:///foo.yaml"},
+				}).Return([]types.FoldingRange{
+					{StartLine: 0, EndLine: 1, Kind: types.FoldingRangeComment},
+					{StartLine: 2, EndLine: 5},
+				}, nil)
+			},
+			requests: []string{
+				`{"jsonrpc":"2.0","id":1,"method":"textDocument/foldingRange","params":{"textDocument":{"uri":"file:///foo.yaml"}}}`,
+			},
+			wantResponses: []string{
+				`{"jsonrpc":"2.0","id":1,"result":[{"startLine":0,"endLine":1,"kind":"comment"},{"startLine":2,"endLine":5}]}`,
+			},
+		},
+		{
+			name: "textDocument/selectionRange",
+			setup: func(t *testing.T, s *Server, h *testutil.MockHandler) {
+				h.EXPECT().HandleSelectionRange(types.SelectionRangeParams{
+					TextDocument: types.TextDocumentIdentifier{URI: "file:///foo.yaml"},
+					Positions:    []types.Position{{Line: 1, Character: 4}},
+				}).Return([]types.SelectionRange{{
+					Range: types.Range{Start: types.Position{Line: 1, Character: 2}, End: types.Position{Line: 1, Character: 6}},
+					Parent: &types.SelectionRange{
+						Range: types.Range{Start: types.Position{Line: 0, Character: 0}, End: types.Position{Line: 2, Character: 0}},
+					},
+				}}, nil)
+			},
+			requests: []string{
+				`{"jsonrpc":"2.0","id":1,"method":"textDocument/selectionRange","params":{"textDocument":{"uri":"file:///foo.yaml"},"positions":[{"line":1,"character":4}]}}`,
+			},
+			wantResponses: []string{
+				`{"jsonrpc":"2.0","id":1,"result":[{"range":{"start":{"line":1,"character":2},"end":{"line":1,"character":6}},"parent":{"range":{"start":{"line":0,"character":0},"end":{"line":2,"character":0}}}}]}`,
+			},
+		},
+		{
+			name: "textDocument/formatting",
+			setup: func(t *testing.T, s *Server, h *testutil.MockHandler) {
+				h.EXPECT().HandleFormatting(types.DocumentFormattingParams{
+					TextDocument: types.TextDocumentIdentifier{URI: "file:///foo.yaml"},
+					Options:      types.FormattingOptions{TabSize: 2, InsertSpaces: true},
+				}).Return([]types.TextEdit{{
+					Range:   types.Range{Start: types.Position{Line: 1, Character: 0}, End: types.Position{Line: 2, Character: 0}},
+					NewText: "  title: Pets\n",
+				}}, nil)
+			},
+			requests: []string{
+				`{"jsonrpc":"2.0","id":1,"method":"textDocument/formatting","params":{"textDocument":{"uri":"file:///foo.yaml"},"options":{"tabSize":2,"insertSpaces":true}}}`,
+			},
+			wantResponses: []string{
+				`{"jsonrpc":"2.0","id":1,"result":[{"range":{"start":{"line":1,"character":0},"end":{"line":2,"character":0}},"newText":"  title: Pets\n"}]}`,
+			},
+		},
+		{
+			name: "textDocument/rangeFormatting",
+			setup: func(t *testing.T, s *Server, h *testutil.MockHandler) {
+				h.EXPECT().HandleRangeFormatting(types.DocumentRangeFormattingParams{
+					TextDocument: types.TextDocumentIdentifier{URI: "file:///foo.yaml"},
+					Range:        types.Range{Start: types.Position{Line: 1, Character: 0}, End: types.Position{Line: 2, Character: 0}},
+					Options:      types.FormattingOptions{TabSize: 4, InsertSpaces: true},
+				}).Return([]types.TextEdit{}, nil)
+			},
+			requests: []string{
+				`{"jsonrpc":"2.0","id":1,"method":"textDocument/rangeFormatting","params":{"textDocument":{"uri":"file:///foo.yaml"},"range":{"start":{"line":1,"character":0},"end":{"line":2,"character":0}},"options":{"tabSize":4,"insertSpaces":true}}}`,
+			},
+			wantResponses: []string{
+				`{"jsonrpc":"2.0","id":1,"result":[]}`,
+			},
+		},
+		{
+			name: "workspace/executeCommand",
+			setup: func(t *testing.T, s *Server, h *testutil.MockHandler) {
+				h.EXPECT().HandleExecuteCommand(types.ExecuteCommandParams{
+					Command:   "openapi.bundle",
+					Arguments: []json.RawMessage{json.RawMessage(`{"uri":"file:///foo.yaml"}`)},
+				}).Return(map[string]string{"content": "openapi: 3.0.0\n"}, nil)
+			},
+			requests: []string{
+				`{"jsonrpc":"2.0","id":1,"method":"workspace/executeCommand","params":{"command":"openapi.bundle","arguments":[{"uri":"file:///foo.yaml"}]}}`,
+			},
+			wantResponses: []string{
+				`{"jsonrpc":"2.0","id":1,"result":{"content":"openapi: 3.0.0\n"}}`,
+			},
+		},
+		{
+			name: "textDocument/publishDiagnostics",
+			setup: func(t *testing.T, s *Server, h *testutil.MockHandler) {
+				h.EXPECT().HandleOpen(gomock.Any()).Return(nil)
+				h.EXPECT().Diagnostics().Return([]types.PublishDiagnosticsParams{{
+					URI: "file:///foo.txt",
+					Diagnostics: []types.Diagnostic{{
+						Range:    types.Range{Start: types.Position{Line: 0, Character: 0}, End: types.Position{Line: 0, Character: 5}},
+						Severity: types.SeverityError,
+						Message:  "bad",
+					}},
+				}}, nil)
+			},
+			requests: []string{
+				`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///foo.txt","text":"hello world"}}}`,
+			},
+			wantResponses: []string{
+				`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"file:///foo.txt","diagnostics":[{"range":{"start":{"line":0,"character":0},"end":{"line":0,"character":5}},"severity":1,"message":"bad"}]}}`,
+			},
+		},
 		{
 			name: "unknown method",
 			requests: []string{
 				`{"jsonrpc":"2.0","id":1,"method":"foo","params":{}}`,
+				`{"jsonrpc":"2.0","method":"$/cancelRequest","params":{"id":1}}`,
+			},
+			wantResponses: []string{
+				`{"jsonrpc":"2.0","method":"window/logMessage","params":{"type":2,"message":"Request with unknown method \"foo\""}}`,
+			},
+		},
+		{
+			name: "window/showMessage",
+			setup: func(t *testing.T, s *Server, h *testutil.MockHandler) {
+				h.EXPECT().HandleExecuteCommand(gomock.Any()).Return(nil, nil)
+				h.EXPECT().Messages().Return([]Message{
+					{Type: types.MessageInfo, Message: "bundling"},
+					{Type: types.MessageError, Message: "bundle failed", Show: true},
+				})
+			},
+			requests: []string{
+				`{"jsonrpc":"2.0","id":1,"method":"workspace/executeCommand","params":{"command":"openapi.bundle"}}`,
+			},
+			wantResponses: []string{
+				`{"jsonrpc":"2.0","id":1,"result":null}`,
+				`{"jsonrpc":"2.0","method":"window/logMessage","params":{"type":3,"message":"bundling"}}`,
+				`{"jsonrpc":"2.0","method":"window/showMessage","params":{"type":1,"message":"bundle failed"}}`,
 			},
 		},
 	}
@@ -178,6 +609,7 @@ func TestServer_Basic(t *testing.T) {
 			defer ctrl.Finish()
 
 			handler := testutil.NewMockHandler(ctrl)
+			handler.EXPECT().Tasks().Return(nil).AnyTimes()
 			reader := &bytes.Buffer{}
 			writer := &bytes.Buffer{}
 			server := Server{
@@ -194,6 +626,8 @@ func TestServer_Basic(t *testing.T) {
 				tt.setup(t, &server, handler)
 			}
 
+			handler.EXPECT().Messages().Return(nil).AnyTimes()
+
 			send := RPCWriter{Writer: reader}
 			for _, req := range tt.requests {
 				fmt.Fprint(send, req)
@@ -236,3 +670,306 @@ func (w RPCWriter) Write(p []byte) (n int, err error) {
 }
 
 var _ io.Writer = RPCWriter{}
+
+var testTypeHierarchyItem = types.TypeHierarchyItem{
+	Name:           "Pet",
+	Kind:           types.SymbolKindClass,
+	URI:            "file:///foo.yaml",
+	Range:          types.Range{Start: types.Position{Line: 1, Character: 2}, End: types.Position{Line: 3, Character: 0}},
+	SelectionRange: types.Range{Start: types.Position{Line: 1, Character: 2}, End: types.Position{Line: 1, Character: 5}},
+}
+
+var testCallHierarchyItem = types.CallHierarchyItem{
+	Name:           "GET /pets",
+	Kind:           types.SymbolKindFunction,
+	URI:            "file:///foo.yaml",
+	Range:          types.Range{Start: types.Position{Line: 1, Character: 2}, End: types.Position{Line: 3, Character: 0}},
+	SelectionRange: types.Range{Start: types.Position{Line: 1, Character: 2}, End: types.Position{Line: 1, Character: 5}},
+	Data:           "#/paths/~1pets/get",
+}
+
+// messageStream is a Stream that exchanges whole messages, like a WebSocket.
+type messageStream struct {
+	in  []string
+	out []string
+}
+
+func (s *messageStream) ReadMessage() ([]byte, error) {
+	if len(s.in) == 0 {
+		return nil, io.EOF
+	}
+
+	message := s.in[0]
+	s.in = s.in[1:]
+
+	return []byte(message), nil
+}
+
+func (s *messageStream) WriteMessage(payload []byte) error {
+	s.out = append(s.out, string(payload))
+	return nil
+}
+
+func TestServer_Stream(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	handler := testutil.NewMockHandler(ctrl)
+	handler.EXPECT().Tasks().Return(nil).AnyTimes()
+	handler.EXPECT().HandleFormatting(gomock.Any()).Return([]types.TextEdit{}, nil)
+	handler.EXPECT().Messages().Return(nil).AnyTimes()
+
+	stream := &messageStream{in: []string{
+		`{"jsonrpc":"2.0","id":1,"method":"textDocument/formatting","params":{"textDocument":{"uri":"file:///foo.yaml"},"options":{"tabSize":2}}}`,
+	}}
+
+	server := Server{Handler: handler, Stream: stream}
+
+	if err := server.Run(); err != nil {
+		t.Fatal("server.Run() error: ", err)
+	}
+
+	want := []string{`{"jsonrpc":"2.0","id":1,"result":[]}`}
+
+	if !reflect.DeepEqual(stream.out, want) {
+		t.Errorf("got messages %q, want %q", stream.out, want)
+	}
+}
+
+func TestServer_Trace(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	handler := testutil.NewMockHandler(ctrl)
+	handler.EXPECT().Tasks().Return(nil).AnyTimes()
+	handler.EXPECT().HandleInitialize(gomock.Any()).Return(nil)
+	handler.EXPECT().Capabilities().Return(types.ServerCapabilities{}).AnyTimes()
+	handler.EXPECT().HandleFormatting(gomock.Any()).Return([]types.TextEdit{}, nil).Times(3)
+	handler.EXPECT().Messages().Return(nil).AnyTimes()
+
+	formatting := `{"jsonrpc":"2.0","id":%d,"method":"textDocument/formatting","params":{"textDocument":{"uri":"file:///foo.yaml"},"options":{"tabSize":2}}}`
+
+	stream := &messageStream{in: []string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"trace":"messages"}}`,
+		fmt.Sprintf(formatting, 2),
+		`{"jsonrpc":"2.0","method":"$/setTrace","params":{"value":"verbose"}}`,
+		fmt.Sprintf(formatting, 3),
+		`{"jsonrpc":"2.0","method":"$/setTrace","params":{"value":"off"}}`,
+		fmt.Sprintf(formatting, 4),
+	}}
+
+	server := Server{Handler: handler, Stream: stream}
+
+	if err := server.Run(); err != nil {
+		t.Fatal("server.Run() error: ", err)
+	}
+
+	// Processing times vary, so they are replaced with 0.
+	took := regexp.MustCompile(`took \d+ms`)
+
+	var got []string
+	for _, message := range stream.out {
+		got = append(got, took.ReplaceAllString(message, "took 0ms"))
+	}
+
+	want := []string{
+		`{"jsonrpc":"2.0","method":"$/logTrace","params":{"message":"Sending response 'initialize - (1)'. Processing request took 0ms."}}`,
+		`{"jsonrpc":"2.0","id":1,"result":{"capabilities":{"textDocumentSync":{"change":0}},"serverInfo":{"name":"","version":""}}}`,
+		`{"jsonrpc":"2.0","method":"$/logTrace","params":{"message":"Received request 'textDocument/formatting - (2)'."}}`,
+		`{"jsonrpc":"2.0","method":"$/logTrace","params":{"message":"Sending response 'textDocument/formatting - (2)'. Processing request took 0ms."}}`,
+		`{"jsonrpc":"2.0","id":2,"result":[]}`,
+		`{"jsonrpc":"2.0","method":"$/logTrace","params":{"message":"Received notification '$/setTrace'."}}`,
+		`{"jsonrpc":"2.0","method":"$/logTrace","params":{"message":"Received request 'textDocument/formatting - (3)'.","verbose":"Params: {\"textDocument\":{\"uri\":\"file:///foo.yaml\"},\"options\":{\"tabSize\":2}}"}}`,
+		`{"jsonrpc":"2.0","method":"$/logTrace","params":{"message":"Sending response 'textDocument/formatting - (3)'. Processing request took 0ms.","verbose":"Result: []"}}`,
+		`{"jsonrpc":"2.0","id":3,"result":[]}`,
+		`{"jsonrpc":"2.0","method":"$/logTrace","params":{"message":"Received notification '$/setTrace'.","verbose":"Params: {\"value\":\"off\"}"}}`,
+		`{"jsonrpc":"2.0","id":4,"result":[]}`,
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got messages:\n%s\n\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestServer_Configuration(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	handler := testutil.NewMockHandler(ctrl)
+	handler.EXPECT().Tasks().Return(nil).AnyTimes()
+	handler.EXPECT().HandleInitialize(gomock.Any()).Return(nil)
+	handler.EXPECT().Capabilities().Return(types.ServerCapabilities{})
+	handler.EXPECT().Messages().Return(nil).AnyTimes()
+
+	gomock.InOrder(
+		handler.EXPECT().HandleDidChangeConfiguration(types.DidChangeConfigurationParams{
+			Settings: json.RawMessage(`{"tabSize":4}`),
+		}).Return(nil),
+		handler.EXPECT().Diagnostics().Return(nil, nil),
+		handler.EXPECT().HandleDidChangeConfiguration(types.DidChangeConfigurationParams{
+			Settings: json.RawMessage(`{"tabSize":8}`),
+		}).Return(nil),
+		handler.EXPECT().Diagnostics().Return(nil, nil),
+	)
+
+	stream := &messageStream{in: []string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"capabilities":{"workspace":{"configuration":true}}}}`,
+		`{"jsonrpc":"2.0","method":"initialized","params":{}}`,
+		`{"jsonrpc":"2.0","id":1,"result":[{"tabSize":4}]}`,
+
+		// Settings in the notification are ignored, since they are pulled.
+		`{"jsonrpc":"2.0","method":"workspace/didChangeConfiguration","params":{"settings":null}}`,
+		`{"jsonrpc":"2.0","id":2,"result":[{"tabSize":8}]}`,
+
+		// Errors and responses to unknown requests are logged.
+		`{"jsonrpc":"2.0","method":"workspace/didChangeConfiguration","params":{"settings":null}}`,
+		`{"jsonrpc":"2.0","id":3,"error":{"code":-32603,"message":"internal error"}}`,
+		`{"jsonrpc":"2.0","id":4,"result":null}`,
+	}}
+
+	server := Server{Handler: handler, Stream: stream, ConfigurationSection: "test"}
+
+	if err := server.Run(); err != nil {
+		t.Fatal("server.Run() error: ", err)
+	}
+
+	want := []string{
+		`{"jsonrpc":"2.0","id":1,"result":{"capabilities":{"textDocumentSync":{"change":0}},"serverInfo":{"name":"","version":""}}}`,
+		`{"jsonrpc":"2.0","id":1,"method":"workspace/configuration","params":{"items":[{"section":"test"}]}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"workspace/configuration","params":{"items":[{"section":"test"}]}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"workspace/configuration","params":{"items":[{"section":"test"}]}}`,
+	}
+
+	if !reflect.DeepEqual(stream.out, want) {
+		t.Errorf("got messages:\n%s\n\nwant:\n%s", strings.Join(stream.out, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestServer_WatchedFiles(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	handler := testutil.NewMockHandler(ctrl)
+	handler.EXPECT().Tasks().Return(nil).AnyTimes()
+	handler.EXPECT().HandleInitialize(gomock.Any()).Return(nil)
+	handler.EXPECT().Capabilities().Return(types.ServerCapabilities{})
+	handler.EXPECT().Messages().Return(nil).AnyTimes()
+	handler.EXPECT().HandleDidChangeWatchedFiles(types.DidChangeWatchedFilesParams{
+		Changes: []types.FileEvent{{URI: "file:///workspace/.config.yaml", Type: types.FileChanged}},
+	}).Return(nil)
+	handler.EXPECT().Diagnostics().Return(nil, nil)
+
+	stream := &messageStream{in: []string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"capabilities":{"workspace":{"didChangeWatchedFiles":{"dynamicRegistration":true}}}}}`,
+		`{"jsonrpc":"2.0","method":"initialized","params":{}}`,
+		`{"jsonrpc":"2.0","id":1,"result":null}`,
+		`{"jsonrpc":"2.0","method":"workspace/didChangeWatchedFiles","params":{"changes":[{"uri":"file:///workspace/.config.yaml","type":2}]}}`,
+	}}
+
+	server := Server{Handler: handler, Stream: stream, WatchedFiles: []string{"**/.config.yaml"}}
+
+	if err := server.Run(); err != nil {
+		t.Fatal("server.Run() error: ", err)
+	}
+
+	want := []string{
+		`{"jsonrpc":"2.0","id":1,"result":{"capabilities":{"textDocumentSync":{"change":0}},"serverInfo":{"name":"","version":""}}}`,
+		`{"jsonrpc":"2.0","id":1,"method":"client/registerCapability","params":{"registrations":[{"id":"watched-files","method":"workspace/didChangeWatchedFiles","registerOptions":{"watchers":[{"globPattern":"**/.config.yaml"}]}}]}}`,
+	}
+
+	if !reflect.DeepEqual(stream.out, want) {
+		t.Errorf("got messages:\n%s\n\nwant:\n%s", strings.Join(stream.out, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// waitStream is a Stream that has no messages, and that ends once the given
+// channel is closed.
+type waitStream struct {
+	messageStream
+	done chan struct{}
+}
+
+func (s *waitStream) ReadMessage() ([]byte, error) {
+	<-s.done
+	return nil, io.EOF
+}
+
+func TestServer_Tasks(t *testing.T) {
+	stream := &waitStream{done: make(chan struct{})}
+
+	tasks := make(chan func(), 1)
+	tasks <- func() { close(stream.done) }
+
+	ctrl := gomock.NewController(t)
+	handler := testutil.NewMockHandler(ctrl)
+	handler.EXPECT().Tasks().Return(tasks)
+	handler.EXPECT().Messages().Return([]Message{{Type: types.MessageWarning, Message: "fetched"}})
+	handler.EXPECT().Diagnostics().Return([]types.PublishDiagnosticsParams{
+		{URI: "file:///foo.yaml", Diagnostics: []types.Diagnostic{}},
+	}, nil)
+
+	server := Server{Handler: handler, Stream: stream}
+
+	if err := server.Run(); err != nil {
+		t.Fatal("server.Run() error: ", err)
+	}
+
+	// The server runs the task while it waits for the client, and publishes
+	// its results.
+
+	want := []string{
+		`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"file:///foo.yaml","diagnostics":[]}}`,
+		`{"jsonrpc":"2.0","method":"window/logMessage","params":{"type":2,"message":"fetched"}}`,
+	}
+
+	if !reflect.DeepEqual(stream.out, want) {
+		t.Errorf("got messages:\n%s\n\nwant:\n%s", strings.Join(stream.out, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// taskStream is a Stream that reads its messages, then queues a task and ends
+// once the task has run. The task is queued only when the server waits for the
+// next message, so that the messages are handled first.
+type taskStream struct {
+	messageStream
+	tasks chan<- func()
+}
+
+func (s *taskStream) ReadMessage() ([]byte, error) {
+	if len(s.in) > 0 {
+		return s.messageStream.ReadMessage()
+	}
+
+	done := make(chan struct{})
+	s.tasks <- func() { close(done) }
+	<-done
+
+	return nil, io.EOF
+}
+
+func TestServer_Tasks_Refresh(t *testing.T) {
+	tasks := make(chan func())
+
+	ctrl := gomock.NewController(t)
+	handler := testutil.NewMockHandler(ctrl)
+	handler.EXPECT().Tasks().Return(tasks)
+	handler.EXPECT().HandleInitialize(gomock.Any()).Return(nil)
+	handler.EXPECT().Capabilities().Return(types.ServerCapabilities{})
+	handler.EXPECT().Messages().Return(nil).AnyTimes()
+	handler.EXPECT().Diagnostics().Return(nil, nil)
+
+	stream := &taskStream{
+		messageStream: messageStream{in: []string{
+			`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"capabilities":{"workspace":{"inlayHint":{"refreshSupport":true},"codeLens":{"refreshSupport":true}}}}}`,
+		}},
+		tasks: tasks,
+	}
+
+	server := Server{Handler: handler, Stream: stream}
+
+	if err := server.Run(); err != nil {
+		t.Fatal("server.Run() error: ", err)
+	}
+
+	want := []string{
+		`{"jsonrpc":"2.0","id":1,"result":{"capabilities":{"textDocumentSync":{"change":0}},"serverInfo":{"name":"","version":""}}}`,
+		`{"jsonrpc":"2.0","id":1,"method":"workspace/inlayHint/refresh","params":null}`,
+		`{"jsonrpc":"2.0","id":2,"method":"workspace/codeLens/refresh","params":null}`,
+	}
+
+	if !reflect.DeepEqual(stream.out, want) {
+		t.Errorf("got messages:\n%s\n\nwant:\n%s", strings.Join(stream.out, "\n"), strings.Join(want, "\n"))
+	}
+}
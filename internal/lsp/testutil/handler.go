@@ -12,6 +12,7 @@ package testutil
 import (
 	reflect "reflect"
 
+	lsp "github.com/armsnyder/openapi-language-server/internal/lsp"
 	types "github.com/armsnyder/openapi-language-server/internal/lsp/types"
 	gomock "go.uber.org/mock/gomock"
 )
@@ -53,6 +54,51 @@ func (mr *MockHandlerMockRecorder) Capabilities() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Capabilities", reflect.TypeOf((*MockHandler)(nil).Capabilities))
 }
 
+// Diagnostics mocks base method.
+func (m *MockHandler) Diagnostics() ([]types.PublishDiagnosticsParams, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Diagnostics")
+	ret0, _ := ret[0].([]types.PublishDiagnosticsParams)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Diagnostics indicates an expected call of Diagnostics.
+func (mr *MockHandlerMockRecorder) Diagnostics() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Diagnostics", reflect.TypeOf((*MockHandler)(nil).Diagnostics))
+}
+
+// HandleCallHierarchyIncomingCalls mocks base method.
+func (m *MockHandler) HandleCallHierarchyIncomingCalls(params types.CallHierarchyIncomingCallsParams) ([]types.CallHierarchyIncomingCall, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandleCallHierarchyIncomingCalls", params)
+	ret0, _ := ret[0].([]types.CallHierarchyIncomingCall)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HandleCallHierarchyIncomingCalls indicates an expected call of HandleCallHierarchyIncomingCalls.
+func (mr *MockHandlerMockRecorder) HandleCallHierarchyIncomingCalls(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleCallHierarchyIncomingCalls", reflect.TypeOf((*MockHandler)(nil).HandleCallHierarchyIncomingCalls), params)
+}
+
+// HandleCallHierarchyOutgoingCalls mocks base method.
+func (m *MockHandler) HandleCallHierarchyOutgoingCalls(params types.CallHierarchyOutgoingCallsParams) ([]types.CallHierarchyOutgoingCall, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandleCallHierarchyOutgoingCalls", params)
+	ret0, _ := ret[0].([]types.CallHierarchyOutgoingCall)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HandleCallHierarchyOutgoingCalls indicates an expected call of HandleCallHierarchyOutgoingCalls.
+func (mr *MockHandlerMockRecorder) HandleCallHierarchyOutgoingCalls(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleCallHierarchyOutgoingCalls", reflect.TypeOf((*MockHandler)(nil).HandleCallHierarchyOutgoingCalls), params)
+}
+
 // HandleChange mocks base method.
 func (m *MockHandler) HandleChange(params types.DidChangeTextDocumentParams) error {
 	m.ctrl.T.Helper()
@@ -81,6 +127,51 @@ func (mr *MockHandlerMockRecorder) HandleClose(params any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleClose", reflect.TypeOf((*MockHandler)(nil).HandleClose), params)
 }
 
+// HandleCodeAction mocks base method.
+func (m *MockHandler) HandleCodeAction(params types.CodeActionParams) ([]types.CodeAction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandleCodeAction", params)
+	ret0, _ := ret[0].([]types.CodeAction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HandleCodeAction indicates an expected call of HandleCodeAction.
+func (mr *MockHandlerMockRecorder) HandleCodeAction(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleCodeAction", reflect.TypeOf((*MockHandler)(nil).HandleCodeAction), params)
+}
+
+// HandleCodeLens mocks base method.
+func (m *MockHandler) HandleCodeLens(params types.CodeLensParams) ([]types.CodeLens, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandleCodeLens", params)
+	ret0, _ := ret[0].([]types.CodeLens)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HandleCodeLens indicates an expected call of HandleCodeLens.
+func (mr *MockHandlerMockRecorder) HandleCodeLens(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleCodeLens", reflect.TypeOf((*MockHandler)(nil).HandleCodeLens), params)
+}
+
+// HandleCodeLensResolve mocks base method.
+func (m *MockHandler) HandleCodeLensResolve(params types.CodeLens) (types.CodeLens, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandleCodeLensResolve", params)
+	ret0, _ := ret[0].(types.CodeLens)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HandleCodeLensResolve indicates an expected call of HandleCodeLensResolve.
+func (mr *MockHandlerMockRecorder) HandleCodeLensResolve(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleCodeLensResolve", reflect.TypeOf((*MockHandler)(nil).HandleCodeLensResolve), params)
+}
+
 // HandleDefinition mocks base method.
 func (m *MockHandler) HandleDefinition(params types.DefinitionParams) ([]types.Location, error) {
 	m.ctrl.T.Helper()
@@ -96,6 +187,152 @@ func (mr *MockHandlerMockRecorder) HandleDefinition(params any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleDefinition", reflect.TypeOf((*MockHandler)(nil).HandleDefinition), params)
 }
 
+// HandleDidChangeConfiguration mocks base method.
+func (m *MockHandler) HandleDidChangeConfiguration(params types.DidChangeConfigurationParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandleDidChangeConfiguration", params)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// HandleDidChangeConfiguration indicates an expected call of HandleDidChangeConfiguration.
+func (mr *MockHandlerMockRecorder) HandleDidChangeConfiguration(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleDidChangeConfiguration", reflect.TypeOf((*MockHandler)(nil).HandleDidChangeConfiguration), params)
+}
+
+// HandleDidChangeWatchedFiles mocks base method.
+func (m *MockHandler) HandleDidChangeWatchedFiles(params types.DidChangeWatchedFilesParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandleDidChangeWatchedFiles", params)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// HandleDidChangeWatchedFiles indicates an expected call of HandleDidChangeWatchedFiles.
+func (mr *MockHandlerMockRecorder) HandleDidChangeWatchedFiles(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleDidChangeWatchedFiles", reflect.TypeOf((*MockHandler)(nil).HandleDidChangeWatchedFiles), params)
+}
+
+// HandleDidChangeWorkspaceFolders mocks base method.
+func (m *MockHandler) HandleDidChangeWorkspaceFolders(params types.DidChangeWorkspaceFoldersParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandleDidChangeWorkspaceFolders", params)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// HandleDidChangeWorkspaceFolders indicates an expected call of HandleDidChangeWorkspaceFolders.
+func (mr *MockHandlerMockRecorder) HandleDidChangeWorkspaceFolders(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleDidChangeWorkspaceFolders", reflect.TypeOf((*MockHandler)(nil).HandleDidChangeWorkspaceFolders), params)
+}
+
+// HandleDocumentLink mocks base method.
+func (m *MockHandler) HandleDocumentLink(params types.DocumentLinkParams) ([]types.DocumentLink, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandleDocumentLink", params)
+	ret0, _ := ret[0].([]types.DocumentLink)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HandleDocumentLink indicates an expected call of HandleDocumentLink.
+func (mr *MockHandlerMockRecorder) HandleDocumentLink(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleDocumentLink", reflect.TypeOf((*MockHandler)(nil).HandleDocumentLink), params)
+}
+
+// HandleExecuteCommand mocks base method.
+func (m *MockHandler) HandleExecuteCommand(params types.ExecuteCommandParams) (any, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandleExecuteCommand", params)
+	ret0, _ := ret[0].(any)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HandleExecuteCommand indicates an expected call of HandleExecuteCommand.
+func (mr *MockHandlerMockRecorder) HandleExecuteCommand(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleExecuteCommand", reflect.TypeOf((*MockHandler)(nil).HandleExecuteCommand), params)
+}
+
+// HandleFoldingRange mocks base method.
+func (m *MockHandler) HandleFoldingRange(params types.FoldingRangeParams) ([]types.FoldingRange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandleFoldingRange", params)
+	ret0, _ := ret[0].([]types.FoldingRange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HandleFoldingRange indicates an expected call of HandleFoldingRange.
+func (mr *MockHandlerMockRecorder) HandleFoldingRange(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleFoldingRange", reflect.TypeOf((*MockHandler)(nil).HandleFoldingRange), params)
+}
+
+// HandleFormatting mocks base method.
+func (m *MockHandler) HandleFormatting(params types.DocumentFormattingParams) ([]types.TextEdit, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandleFormatting", params)
+	ret0, _ := ret[0].([]types.TextEdit)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HandleFormatting indicates an expected call of HandleFormatting.
+func (mr *MockHandlerMockRecorder) HandleFormatting(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleFormatting", reflect.TypeOf((*MockHandler)(nil).HandleFormatting), params)
+}
+
+// HandleHover mocks base method.
+func (m *MockHandler) HandleHover(params types.HoverParams) (*types.Hover, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandleHover", params)
+	ret0, _ := ret[0].(*types.Hover)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HandleHover indicates an expected call of HandleHover.
+func (mr *MockHandlerMockRecorder) HandleHover(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleHover", reflect.TypeOf((*MockHandler)(nil).HandleHover), params)
+}
+
+// HandleInitialize mocks base method.
+func (m *MockHandler) HandleInitialize(params types.InitializeParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandleInitialize", params)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// HandleInitialize indicates an expected call of HandleInitialize.
+func (mr *MockHandlerMockRecorder) HandleInitialize(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleInitialize", reflect.TypeOf((*MockHandler)(nil).HandleInitialize), params)
+}
+
+// HandleInlayHint mocks base method.
+func (m *MockHandler) HandleInlayHint(params types.InlayHintParams) ([]types.InlayHint, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandleInlayHint", params)
+	ret0, _ := ret[0].([]types.InlayHint)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HandleInlayHint indicates an expected call of HandleInlayHint.
+func (mr *MockHandlerMockRecorder) HandleInlayHint(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleInlayHint", reflect.TypeOf((*MockHandler)(nil).HandleInlayHint), params)
+}
+
 // HandleOpen mocks base method.
 func (m *MockHandler) HandleOpen(params types.DidOpenTextDocumentParams) error {
 	m.ctrl.T.Helper()
@@ -110,6 +347,51 @@ func (mr *MockHandlerMockRecorder) HandleOpen(params any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleOpen", reflect.TypeOf((*MockHandler)(nil).HandleOpen), params)
 }
 
+// HandlePrepareCallHierarchy mocks base method.
+func (m *MockHandler) HandlePrepareCallHierarchy(params types.CallHierarchyPrepareParams) ([]types.CallHierarchyItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandlePrepareCallHierarchy", params)
+	ret0, _ := ret[0].([]types.CallHierarchyItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HandlePrepareCallHierarchy indicates an expected call of HandlePrepareCallHierarchy.
+func (mr *MockHandlerMockRecorder) HandlePrepareCallHierarchy(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandlePrepareCallHierarchy", reflect.TypeOf((*MockHandler)(nil).HandlePrepareCallHierarchy), params)
+}
+
+// HandlePrepareTypeHierarchy mocks base method.
+func (m *MockHandler) HandlePrepareTypeHierarchy(params types.TypeHierarchyPrepareParams) ([]types.TypeHierarchyItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandlePrepareTypeHierarchy", params)
+	ret0, _ := ret[0].([]types.TypeHierarchyItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HandlePrepareTypeHierarchy indicates an expected call of HandlePrepareTypeHierarchy.
+func (mr *MockHandlerMockRecorder) HandlePrepareTypeHierarchy(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandlePrepareTypeHierarchy", reflect.TypeOf((*MockHandler)(nil).HandlePrepareTypeHierarchy), params)
+}
+
+// HandleRangeFormatting mocks base method.
+func (m *MockHandler) HandleRangeFormatting(params types.DocumentRangeFormattingParams) ([]types.TextEdit, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandleRangeFormatting", params)
+	ret0, _ := ret[0].([]types.TextEdit)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HandleRangeFormatting indicates an expected call of HandleRangeFormatting.
+func (mr *MockHandlerMockRecorder) HandleRangeFormatting(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleRangeFormatting", reflect.TypeOf((*MockHandler)(nil).HandleRangeFormatting), params)
+}
+
 // HandleReferences mocks base method.
 func (m *MockHandler) HandleReferences(params types.ReferenceParams) ([]types.Location, error) {
 	m.ctrl.T.Helper()
@@ -124,3 +406,105 @@ func (mr *MockHandlerMockRecorder) HandleReferences(params any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleReferences", reflect.TypeOf((*MockHandler)(nil).HandleReferences), params)
 }
+
+// HandleSave mocks base method.
+func (m *MockHandler) HandleSave(params types.DidSaveTextDocumentParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandleSave", params)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// HandleSave indicates an expected call of HandleSave.
+func (mr *MockHandlerMockRecorder) HandleSave(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleSave", reflect.TypeOf((*MockHandler)(nil).HandleSave), params)
+}
+
+// HandleSelectionRange mocks base method.
+func (m *MockHandler) HandleSelectionRange(params types.SelectionRangeParams) ([]types.SelectionRange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandleSelectionRange", params)
+	ret0, _ := ret[0].([]types.SelectionRange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HandleSelectionRange indicates an expected call of HandleSelectionRange.
+func (mr *MockHandlerMockRecorder) HandleSelectionRange(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleSelectionRange", reflect.TypeOf((*MockHandler)(nil).HandleSelectionRange), params)
+}
+
+// HandleTypeHierarchySubtypes mocks base method.
+func (m *MockHandler) HandleTypeHierarchySubtypes(params types.TypeHierarchySubtypesParams) ([]types.TypeHierarchyItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandleTypeHierarchySubtypes", params)
+	ret0, _ := ret[0].([]types.TypeHierarchyItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HandleTypeHierarchySubtypes indicates an expected call of HandleTypeHierarchySubtypes.
+func (mr *MockHandlerMockRecorder) HandleTypeHierarchySubtypes(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleTypeHierarchySubtypes", reflect.TypeOf((*MockHandler)(nil).HandleTypeHierarchySubtypes), params)
+}
+
+// HandleTypeHierarchySupertypes mocks base method.
+func (m *MockHandler) HandleTypeHierarchySupertypes(params types.TypeHierarchySupertypesParams) ([]types.TypeHierarchyItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandleTypeHierarchySupertypes", params)
+	ret0, _ := ret[0].([]types.TypeHierarchyItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HandleTypeHierarchySupertypes indicates an expected call of HandleTypeHierarchySupertypes.
+func (mr *MockHandlerMockRecorder) HandleTypeHierarchySupertypes(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleTypeHierarchySupertypes", reflect.TypeOf((*MockHandler)(nil).HandleTypeHierarchySupertypes), params)
+}
+
+// HandleWillSaveWaitUntil mocks base method.
+func (m *MockHandler) HandleWillSaveWaitUntil(params types.WillSaveTextDocumentParams) ([]types.TextEdit, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandleWillSaveWaitUntil", params)
+	ret0, _ := ret[0].([]types.TextEdit)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HandleWillSaveWaitUntil indicates an expected call of HandleWillSaveWaitUntil.
+func (mr *MockHandlerMockRecorder) HandleWillSaveWaitUntil(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleWillSaveWaitUntil", reflect.TypeOf((*MockHandler)(nil).HandleWillSaveWaitUntil), params)
+}
+
+// Messages mocks base method.
+func (m *MockHandler) Messages() []lsp.Message {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Messages")
+	ret0, _ := ret[0].([]lsp.Message)
+	return ret0
+}
+
+// Messages indicates an expected call of Messages.
+func (mr *MockHandlerMockRecorder) Messages() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Messages", reflect.TypeOf((*MockHandler)(nil).Messages))
+}
+
+// Tasks mocks base method.
+func (m *MockHandler) Tasks() <-chan func() {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Tasks")
+	ret0, _ := ret[0].(<-chan func())
+	return ret0
+}
+
+// Tasks indicates an expected call of Tasks.
+func (mr *MockHandlerMockRecorder) Tasks() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Tasks", reflect.TypeOf((*MockHandler)(nil).Tasks))
+}
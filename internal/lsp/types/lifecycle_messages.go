@@ -1,11 +1,53 @@
 package types
 
+import "encoding/json"
+
 // https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#initializeParams.
 type InitializeParams struct {
 	ClientInfo struct {
 		Name    string `json:"name"`
 		Version string `json:"version"`
 	} `json:"clientInfo"`
+	RootURI               string             `json:"rootUri,omitempty"`
+	InitializationOptions json.RawMessage    `json:"initializationOptions,omitempty"`
+	Capabilities          ClientCapabilities `json:"capabilities"`
+	Trace                 TraceValue         `json:"trace,omitempty"`
+	WorkspaceFolders      []WorkspaceFolder  `json:"workspaceFolders,omitempty"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#clientCapabilities.
+type ClientCapabilities struct {
+	Workspace WorkspaceClientCapabilities `json:"workspace"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#clientCapabilities.
+type WorkspaceClientCapabilities struct {
+	Configuration         bool                                    `json:"configuration,omitempty"`
+	DidChangeWatchedFiles DidChangeWatchedFilesClientCapabilities `json:"didChangeWatchedFiles"`
+	InlayHint             RefreshClientCapabilities               `json:"inlayHint"`
+	CodeLens              RefreshClientCapabilities               `json:"codeLens"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#inlayHintWorkspaceClientCapabilities.
+type RefreshClientCapabilities struct {
+	RefreshSupport bool `json:"refreshSupport,omitempty"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#didChangeWatchedFilesClientCapabilities.
+type DidChangeWatchedFilesClientCapabilities struct {
+	DynamicRegistration bool `json:"dynamicRegistration,omitempty"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#registrationParams.
+type RegistrationParams struct {
+	Registrations []Registration `json:"registrations"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#registration.
+type Registration struct {
+	ID              string `json:"id"`
+	Method          string `json:"method"`
+	RegisterOptions any    `json:"registerOptions,omitempty"`
 }
 
 // https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#initializeResult.
@@ -16,9 +58,33 @@ type InitializeResult struct {
 
 // https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#serverCapabilities.
 type ServerCapabilities struct {
-	TextDocumentSync   TextDocumentSyncOptions `json:"textDocumentSync"`
-	DefinitionProvider bool                    `json:"definitionProvider,omitempty"`
-	ReferencesProvider bool                    `json:"referencesProvider,omitempty"`
+	TextDocumentSync                TextDocumentSyncOptions      `json:"textDocumentSync"`
+	DefinitionProvider              bool                         `json:"definitionProvider,omitempty"`
+	ReferencesProvider              bool                         `json:"referencesProvider,omitempty"`
+	HoverProvider                   bool                         `json:"hoverProvider,omitempty"`
+	CodeActionProvider              bool                         `json:"codeActionProvider,omitempty"`
+	TypeHierarchyProvider           bool                         `json:"typeHierarchyProvider,omitempty"`
+	CallHierarchyProvider           bool                         `json:"callHierarchyProvider,omitempty"`
+	CodeLensProvider                *CodeLensOptions             `json:"codeLensProvider,omitempty"`
+	InlayHintProvider               bool                         `json:"inlayHintProvider,omitempty"`
+	DocumentLinkProvider            *DocumentLinkOptions         `json:"documentLinkProvider,omitempty"`
+	FoldingRangeProvider            bool                         `json:"foldingRangeProvider,omitempty"`
+	SelectionRangeProvider          bool                         `json:"selectionRangeProvider,omitempty"`
+	DocumentFormattingProvider      bool                         `json:"documentFormattingProvider,omitempty"`
+	DocumentRangeFormattingProvider bool                         `json:"documentRangeFormattingProvider,omitempty"`
+	ExecuteCommandProvider          *ExecuteCommandOptions       `json:"executeCommandProvider,omitempty"`
+	Workspace                       *WorkspaceServerCapabilities `json:"workspace,omitempty"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#serverCapabilities.
+type WorkspaceServerCapabilities struct {
+	WorkspaceFolders WorkspaceFoldersServerCapabilities `json:"workspaceFolders"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#workspaceFoldersServerCapabilities.
+type WorkspaceFoldersServerCapabilities struct {
+	Supported           bool `json:"supported,omitempty"`
+	ChangeNotifications bool `json:"changeNotifications,omitempty"`
 }
 
 // https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#initializeResult.
@@ -26,3 +92,14 @@ type ServerInfo struct {
 	Name    string `json:"name"`
 	Version string `json:"version"`
 }
+
+// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#setTrace.
+type SetTraceParams struct {
+	Value TraceValue `json:"value"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#logTrace.
+type LogTraceParams struct {
+	Message string `json:"message"`
+	Verbose string `json:"verbose,omitempty"`
+}
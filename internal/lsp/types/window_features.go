@@ -0,0 +1,23 @@
+package types
+
+// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#messageType.
+type MessageType int
+
+const (
+	MessageError   MessageType = 1
+	MessageWarning MessageType = 2
+	MessageInfo    MessageType = 3
+	MessageLog     MessageType = 4
+)
+
+// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#showMessageParams.
+type ShowMessageParams struct {
+	Type    MessageType `json:"type"`
+	Message string      `json:"message"`
+}
+
+// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#logMessageParams.
+type LogMessageParams struct {
+	Type    MessageType `json:"type"`
+	Message string      `json:"message"`
+}
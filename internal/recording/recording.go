@@ -0,0 +1,248 @@
+// Package recording records the messages of a language server session, and
+// replays the client side of a recording against a fresh server so that the
+// session can be reproduced.
+package recording
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/armsnyder/openapi-language-server/internal/lsp/jsonrpc"
+)
+
+// Direction is whether a message was received or sent by the server.
+type Direction string
+
+const (
+	Received Direction = "received"
+	Sent     Direction = "sent"
+)
+
+// Entry is a single message of a recording. A recording is a file with one
+// JSON-encoded entry per line.
+type Entry struct {
+	Time      time.Time       `json:"time"`
+	Direction Direction       `json:"direction"`
+	Message   json.RawMessage `json:"message"`
+}
+
+// Recorder is a jsonrpc.Stream that records every message that passes through
+// another stream.
+type Recorder struct {
+	stream jsonrpc.Stream
+
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+// NewRecorder returns a Recorder that writes the messages of a stream to w.
+func NewRecorder(stream jsonrpc.Stream, w io.Writer) *Recorder {
+	return &Recorder{stream: stream, encoder: json.NewEncoder(w)}
+}
+
+// ReadMessage implements jsonrpc.Stream.
+func (r *Recorder) ReadMessage() ([]byte, error) {
+	payload, err := r.stream.ReadMessage()
+	if err == nil {
+		r.record(Received, payload)
+	}
+
+	return payload, err
+}
+
+// WriteMessage implements jsonrpc.Stream.
+func (r *Recorder) WriteMessage(payload []byte) error {
+	r.record(Sent, payload)
+
+	return r.stream.WriteMessage(payload)
+}
+
+// record writes an entry. Failing to record does not interrupt the session.
+func (r *Recorder) record(direction Direction, payload []byte) {
+	message := json.RawMessage(payload)
+
+	// Keep payloads that are not JSON, so that the recording reproduces them,
+	// by storing them as strings.
+	if !json.Valid(payload) {
+		message, _ = json.Marshal(string(payload))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_ = r.encoder.Encode(Entry{Time: time.Now(), Direction: direction, Message: message})
+}
+
+// Read reads the entries of a recording.
+func Read(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64*1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if entry.Direction != Received && entry.Direction != Sent {
+			return nil, fmt.Errorf("line %d: unknown direction %q", line, entry.Direction)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
+
+// payload returns the message of an entry as it was sent.
+func (e Entry) payload() []byte {
+	var s string
+	if len(e.Message) > 0 && e.Message[0] == '"' && json.Unmarshal(e.Message, &s) == nil {
+		return []byte(s)
+	}
+
+	return e.Message
+}
+
+// Difference is a sent message of a replay that does not match the recording.
+type Difference struct {
+	// After is the index of the last received entry before the difference,
+	// or -1 if there is none.
+	After int
+
+	// Want is the recorded message, or nil if the replay sent an extra
+	// message.
+	Want json.RawMessage
+
+	// Got is the replayed message, or nil if the replay did not send the
+	// recorded message.
+	Got json.RawMessage
+}
+
+// Replay runs a server on a stream that plays back the received messages of a
+// recording, and returns how the messages that the server sends differ from
+// the recorded ones. Messages are compared as JSON values. Traces are not
+// compared, since they contain timings.
+func Replay(entries []Entry, run func(stream jsonrpc.Stream) error) ([]Difference, error) {
+	player := &player{entries: entries, last: -1}
+
+	if err := run(player); err != nil {
+		return nil, err
+	}
+
+	// Pair the sent messages of the recording with those of the replay, in
+	// order. Each message is attributed to the received message before it.
+	var want []sentMessage
+	last := -1
+
+	for i, entry := range entries {
+		switch {
+		case entry.Direction == Received:
+			last = i
+		case !isTrace(entry.Message):
+			want = append(want, sentMessage{after: last, message: entry.Message})
+		}
+	}
+
+	player.mu.Lock()
+	defer player.mu.Unlock()
+
+	var got []sentMessage
+	for _, m := range player.sent {
+		if !isTrace(m.message) {
+			got = append(got, m)
+		}
+	}
+
+	var differences []Difference
+
+	for i := 0; i < max(len(want), len(got)); i++ {
+		var d Difference
+
+		switch {
+		case i >= len(got):
+			d = Difference{After: want[i].after, Want: want[i].message}
+		case i >= len(want):
+			d = Difference{After: got[i].after, Got: got[i].message}
+		case !jsonEqual(want[i].message, got[i].message):
+			d = Difference{After: want[i].after, Want: want[i].message, Got: got[i].message}
+		default:
+			continue
+		}
+
+		differences = append(differences, d)
+	}
+
+	return differences, nil
+}
+
+type sentMessage struct {
+	after   int
+	message json.RawMessage
+}
+
+// player is a jsonrpc.Stream that plays back the received messages of a
+// recording and collects the messages that the server sends. The server reads
+// and writes messages on different goroutines.
+type player struct {
+	entries []Entry
+	next    int
+
+	mu   sync.Mutex
+	last int
+	sent []sentMessage
+}
+
+func (p *player) ReadMessage() ([]byte, error) {
+	for ; p.next < len(p.entries); p.next++ {
+		if p.entries[p.next].Direction == Received {
+			p.mu.Lock()
+			p.last = p.next
+			p.mu.Unlock()
+
+			p.next++
+			return p.entries[p.next-1].payload(), nil
+		}
+	}
+
+	return nil, io.EOF
+}
+
+func (p *player) WriteMessage(payload []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.sent = append(p.sent, sentMessage{after: p.last, message: append(json.RawMessage(nil), payload...)})
+	return nil
+}
+
+func isTrace(message json.RawMessage) bool {
+	var m struct {
+		Method string `json:"method"`
+	}
+
+	return json.Unmarshal(message, &m) == nil && m.Method == "$/logTrace"
+}
+
+func jsonEqual(a, b json.RawMessage) bool {
+	var x, y any
+
+	if errors.Join(json.Unmarshal(a, &x), json.Unmarshal(b, &y)) != nil {
+		return bytes.Equal(a, b)
+	}
+
+	return reflect.DeepEqual(x, y)
+}
@@ -0,0 +1,200 @@
+package recording_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/armsnyder/openapi-language-server/internal/lsp"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/jsonrpc"
+	. "github.com/armsnyder/openapi-language-server/internal/recording"
+)
+
+// queueStream is a Stream with a fixed list of incoming messages.
+type queueStream struct {
+	in  []string
+	out []string
+}
+
+func (s *queueStream) ReadMessage() ([]byte, error) {
+	if len(s.in) == 0 {
+		return nil, io.EOF
+	}
+
+	message := s.in[0]
+	s.in = s.in[1:]
+
+	return []byte(message), nil
+}
+
+func (s *queueStream) WriteMessage(payload []byte) error {
+	s.out = append(s.out, string(payload))
+	return nil
+}
+
+func runServer(stream jsonrpc.Stream) error {
+	server := lsp.Server{Handler: lsp.NopHandler{}, Stream: stream}
+	return server.Run()
+}
+
+func TestRecorder(t *testing.T) {
+	stream := &queueStream{in: []string{
+		`{"jsonrpc":"2.0","id":1,"method":"foo"}`,
+		`not json`,
+	}}
+
+	var buf bytes.Buffer
+
+	recorder := NewRecorder(stream, &buf)
+
+	for range 2 {
+		if _, err := recorder.ReadMessage(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := recorder.WriteMessage([]byte(`{"jsonrpc":"2.0","id":1,"result":null}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(stream.out) != 1 {
+		t.Errorf("got %d messages written through, want 1", len(stream.out))
+	}
+
+	entries, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		direction Direction
+		message   string
+	}{
+		{Received, `{"jsonrpc":"2.0","id":1,"method":"foo"}`},
+		{Received, `"not json"`},
+		{Sent, `{"jsonrpc":"2.0","id":1,"result":null}`},
+	}
+
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+
+	for i, entry := range entries {
+		if entry.Direction != want[i].direction || string(entry.Message) != want[i].message {
+			t.Errorf("entry %d = %s %s, want %s %s", i, entry.Direction, entry.Message, want[i].direction, want[i].message)
+		}
+
+		if entry.Time.IsZero() {
+			t.Errorf("entry %d has no time", i)
+		}
+	}
+}
+
+func TestRead_Invalid(t *testing.T) {
+	for _, input := range []string{
+		`{"direction":"sideways","message":{}}`,
+		`{"direction":`,
+	} {
+		if _, err := Read(strings.NewReader(input)); err == nil {
+			t.Errorf("Read(%q): expected an error", input)
+		}
+	}
+}
+
+func TestReplay(t *testing.T) {
+	recording := func(response string) []Entry {
+		return []Entry{
+			{Direction: Received, Message: json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"textDocument/formatting","params":{}}`)},
+			{Direction: Sent, Message: json.RawMessage(response)},
+			{Direction: Sent, Message: json.RawMessage(`{"jsonrpc":"2.0","method":"$/logTrace","params":{"message":"took 5ms"}}`)},
+			{Direction: Received, Message: json.RawMessage(`{"jsonrpc":"2.0","id":2,"method":"shutdown"}`)},
+			{Direction: Sent, Message: json.RawMessage(`{"jsonrpc":"2.0","id":2,"result":null}`)},
+		}
+	}
+
+	t.Run("same", func(t *testing.T) {
+		differences, err := Replay(recording(`{"id":1,"jsonrpc":"2.0", "result":null}`), runServer)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(differences) != 0 {
+			t.Errorf("got differences %+v, want none", differences)
+		}
+	})
+
+	t.Run("different", func(t *testing.T) {
+		differences, err := Replay(recording(`{"jsonrpc":"2.0","id":1,"result":[]}`), runServer)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(differences) != 1 {
+			t.Fatalf("got %d differences, want 1", len(differences))
+		}
+
+		d := differences[0]
+		if d.After != 0 || string(d.Want) != `{"jsonrpc":"2.0","id":1,"result":[]}` || string(d.Got) != `{"jsonrpc":"2.0","id":1,"result":null}` {
+			t.Errorf("got difference after %d: want %s, got %s", d.After, d.Want, d.Got)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		entries := append(recording(`{"jsonrpc":"2.0","id":1,"result":null}`), Entry{
+			Direction: Sent,
+			Message:   json.RawMessage(`{"jsonrpc":"2.0","method":"window/logMessage","params":{}}`),
+		})
+
+		differences, err := Replay(entries, runServer)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(differences) != 1 || differences[0].Got != nil || differences[0].After != 3 {
+			t.Errorf("got differences %+v, want the last message to be missing", differences)
+		}
+	})
+}
+
+func TestReplay_Concurrent(t *testing.T) {
+	entries := []Entry{
+		{Direction: Received, Message: json.RawMessage(`{"jsonrpc":"2.0","method":"initialized","params":{}}`)},
+		{Direction: Received, Message: json.RawMessage(`{"jsonrpc":"2.0","method":"exit"}`)},
+	}
+
+	// The server reads messages on one goroutine and writes on another, such
+	// as when it runs background tasks while it waits for the client.
+	run := func(stream jsonrpc.Stream) error {
+		done := make(chan struct{})
+
+		go func() {
+			defer close(done)
+
+			for {
+				if _, err := stream.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+
+		for range 10 {
+			if err := stream.WriteMessage([]byte(`{"jsonrpc":"2.0","method":"window/logMessage","params":{}}`)); err != nil {
+				return err
+			}
+		}
+
+		<-done
+		return nil
+	}
+
+	differences, err := Replay(entries, run)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(differences) != 10 {
+		t.Errorf("got %d differences, want 10 extra messages", len(differences))
+	}
+}
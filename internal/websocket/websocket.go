@@ -0,0 +1,322 @@
+// Package websocket implements the server side of the WebSocket protocol, as
+// far as it is needed to exchange whole messages with a browser.
+// See https://www.rfc-editor.org/rfc/rfc6455.
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1" //nolint:gosec // required by the protocol, not used for security
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// acceptGUID is combined with the key of the client to prove that the server
+// understands the protocol.
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// MaxMessageSize is the largest message that a Conn reads.
+const MaxMessageSize = 10 * 1024 * 1024
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+const (
+	closeNormal        = 1000
+	closeProtocolError = 1002
+	closeTooBig        = 1009
+)
+
+// Conn is a WebSocket connection. It implements jsonrpc.Stream, with one
+// message per WebSocket message.
+type Conn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+
+	writeMu sync.Mutex
+	closed  bool
+}
+
+// Upgrader upgrades HTTP requests to WebSocket connections.
+type Upgrader struct {
+	// AllowedOrigins are the origins, such as http://localhost:3000, of the
+	// pages that may connect, besides the page of the server itself. An origin
+	// of "*" allows any page.
+	//
+	// Browsers send the origin of the page that opens a connection, and do not
+	// apply the same-origin policy to WebSockets. Without this check, any site
+	// that the user visits could connect to a server on localhost.
+	AllowedOrigins []string
+}
+
+// Upgrade upgrades an HTTP request to a WebSocket connection, only allowing
+// pages of the same origin as the server. Clients that are not browsers send no
+// origin, and are always allowed.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	return (&Upgrader{}).Upgrade(w, r)
+}
+
+// Upgrade upgrades an HTTP request to a WebSocket connection. If the request
+// is not a valid WebSocket handshake, or comes from an origin that is not
+// allowed, Upgrade replies with an HTTP error and returns an error.
+func (u *Upgrader) Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	origin := r.Header.Get("Origin")
+
+	switch {
+	case origin != "" && !u.allowed(origin, r.Host):
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return nil, fmt.Errorf("origin %q is not allowed", origin)
+	case r.Method != http.MethodGet:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return nil, fmt.Errorf("unexpected method %s", r.Method)
+	case !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket"):
+		http.Error(w, "expected a WebSocket upgrade", http.StatusBadRequest)
+		return nil, errors.New("not a WebSocket handshake")
+	case r.Header.Get("Sec-WebSocket-Version") != "13":
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported WebSocket version", http.StatusUpgradeRequired)
+		return nil, errors.New("unsupported WebSocket version")
+	case key == "":
+		http.Error(w, "missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, errors.New("missing Sec-WebSocket-Key")
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "WebSocket is not supported", http.StatusInternalServerError)
+		return nil, errors.New("response writer cannot be hijacked")
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n"
+
+	if _, err := conn.Write([]byte(response)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &Conn{conn: conn, reader: rw.Reader}, nil
+}
+
+// allowed returns true if a page of the origin may connect to the server at
+// the host.
+func (u *Upgrader) allowed(origin, host string) bool {
+	for _, allowed := range u.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+
+	parsed, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(parsed.Host, host)
+}
+
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID)) //nolint:gosec // see import
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// headerContains returns true if a comma-separated header contains the given
+// token, ignoring case.
+func headerContains(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, t := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// ReadMessage returns the payload of the next text or binary message. Control
+// frames are handled as they arrive. It returns io.EOF when the client closes
+// the connection.
+func (c *Conn) ReadMessage() ([]byte, error) {
+	var message []byte
+	started := false
+
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		switch opcode {
+		case opClose:
+			// Echo the status code, as the protocol requires.
+			code := payload
+			if len(code) > 2 {
+				code = code[:2]
+			}
+			_ = c.writeFrame(opClose, code)
+			c.conn.Close()
+			return nil, io.EOF
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opText, opBinary:
+			if started {
+				return nil, c.fail(closeProtocolError, "new message before the previous one ended")
+			}
+			started = true
+		case opContinuation:
+			if !started {
+				return nil, c.fail(closeProtocolError, "continuation frame without a message")
+			}
+		default:
+			return nil, c.fail(closeProtocolError, fmt.Sprintf("unknown opcode %#x", opcode))
+		}
+
+		if len(message)+len(payload) > MaxMessageSize {
+			return nil, c.fail(closeTooBig, "message is too big")
+		}
+
+		message = append(message, payload...)
+
+		if fin {
+			return message, nil
+		}
+	}
+}
+
+// readFrame reads a single frame and unmasks its payload.
+func (c *Conn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	fin = header[0]&0x80 != 0
+	opcode = header[0] & 0x0F
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7F)
+
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, c.fail(closeProtocolError, "reserved bits are set")
+	}
+
+	if !masked {
+		return false, 0, nil, c.fail(closeProtocolError, "client frames must be masked")
+	}
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+
+	if opcode >= opClose && (!fin || length > 125) {
+		return false, 0, nil, c.fail(closeProtocolError, "invalid control frame")
+	}
+
+	if length > MaxMessageSize {
+		return false, 0, nil, c.fail(closeTooBig, "message is too big")
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return fin, opcode, payload, nil
+}
+
+// WriteMessage writes a payload as a single text frame.
+func (c *Conn) WriteMessage(payload []byte) error {
+	return c.writeFrame(opText, payload)
+}
+
+func (c *Conn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closed {
+		return net.ErrClosed
+	}
+
+	if opcode == opClose {
+		c.closed = true
+	}
+
+	// Frames from the server are not masked.
+	frame := []byte{0x80 | opcode}
+
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, byte(n))
+	case n <= 0xFFFF:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+
+	frame = append(frame, payload...)
+
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// fail closes the connection with a status code and returns an error with
+// the reason.
+func (c *Conn) fail(code uint16, reason string) error {
+	_ = c.writeFrame(opClose, binary.BigEndian.AppendUint16(nil, code))
+	c.conn.Close()
+	return errors.New("websocket: " + reason)
+}
+
+// Close closes the connection normally.
+func (c *Conn) Close() error {
+	_ = c.writeFrame(opClose, binary.BigEndian.AppendUint16(nil, closeNormal))
+	return c.conn.Close()
+}
@@ -0,0 +1,293 @@
+package websocket_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/armsnyder/openapi-language-server/internal/websocket"
+)
+
+// echoServer starts a server that echoes each message back to the client,
+// and returns a connected client.
+func echoServer(t *testing.T) (net.Conn, *bufio.Reader) {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(message); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	handshake := "GET / HTTP/1.1\r\n" +
+		"Host: example.com\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: keep-alive, Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n" +
+		"Sec-WebSocket-Version: 13\r\n\r\n"
+
+	if _, err := conn.Write([]byte(handshake)); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := bufio.NewReader(conn)
+
+	response, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status = %d, want %d", response.StatusCode, http.StatusSwitchingProtocols)
+	}
+
+	// The example from RFC 6455, section 1.3.
+	if got := response.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Sec-WebSocket-Accept = %q", got)
+	}
+
+	return conn, reader
+}
+
+// writeFrame writes a masked frame, as a client does.
+func writeFrame(t *testing.T, w io.Writer, fin bool, opcode byte, payload []byte) {
+	t.Helper()
+
+	first := opcode
+	if fin {
+		first |= 0x80
+	}
+
+	frame := []byte{first}
+
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, 0x80|byte(n))
+	case n <= 0xFFFF:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+
+	mask := []byte{1, 2, 3, 4}
+	frame = append(frame, mask...)
+
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+
+	if _, err := w.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readFrame reads an unmasked frame, as a client does.
+func readFrame(t *testing.T, r io.Reader) (opcode byte, payload []byte) {
+	t.Helper()
+
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		t.Fatal(err)
+	}
+
+	if header[0]&0x80 == 0 {
+		t.Error("expected the FIN bit to be set")
+	}
+
+	if header[1]&0x80 != 0 {
+		t.Error("server frames must not be masked")
+	}
+
+	length := uint64(header[1] & 0x7F)
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			t.Fatal(err)
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			t.Fatal(err)
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		t.Fatal(err)
+	}
+
+	return header[0] & 0x0F, payload
+}
+
+func TestConn_Echo(t *testing.T) {
+	conn, reader := echoServer(t)
+
+	for _, message := range [][]byte{
+		[]byte(`{"jsonrpc":"2.0","id":1,"method":"initialize"}`),
+		bytes.Repeat([]byte("a"), 300),
+		bytes.Repeat([]byte("b"), 70000),
+	} {
+		writeFrame(t, conn, true, 0x1, message)
+
+		opcode, payload := readFrame(t, reader)
+		if opcode != 0x1 {
+			t.Errorf("opcode = %#x, want text", opcode)
+		}
+		if !bytes.Equal(payload, message) {
+			t.Errorf("echoed %d bytes, want %d", len(payload), len(message))
+		}
+	}
+}
+
+func TestConn_Fragmented(t *testing.T) {
+	conn, reader := echoServer(t)
+
+	writeFrame(t, conn, false, 0x1, []byte(`{"jsonrpc":`))
+	writeFrame(t, conn, true, 0x9, []byte("ping"))
+	writeFrame(t, conn, true, 0x0, []byte(`"2.0"}`))
+
+	// The ping is answered in the middle of the fragmented message.
+	opcode, payload := readFrame(t, reader)
+	if opcode != 0xA || string(payload) != "ping" {
+		t.Errorf("got opcode %#x with %q, want a pong", opcode, payload)
+	}
+
+	opcode, payload = readFrame(t, reader)
+	if opcode != 0x1 || string(payload) != `{"jsonrpc":"2.0"}` {
+		t.Errorf("got opcode %#x with %q", opcode, payload)
+	}
+}
+
+func TestConn_Close(t *testing.T) {
+	conn, reader := echoServer(t)
+
+	writeFrame(t, conn, true, 0x8, []byte{0x03, 0xE8})
+
+	opcode, payload := readFrame(t, reader)
+	if opcode != 0x8 || !bytes.Equal(payload, []byte{0x03, 0xE8}) {
+		t.Errorf("got opcode %#x with %v, want the close to be echoed", opcode, payload)
+	}
+}
+
+func TestConn_Unmasked(t *testing.T) {
+	conn, reader := echoServer(t)
+
+	if _, err := conn.Write([]byte{0x81, 0x02, 'h', 'i'}); err != nil {
+		t.Fatal(err)
+	}
+
+	opcode, payload := readFrame(t, reader)
+	if opcode != 0x8 || binary.BigEndian.Uint16(payload) != 1002 {
+		t.Errorf("got opcode %#x with %v, want a protocol error", opcode, payload)
+	}
+}
+
+func TestUpgrade_NotWebSocket(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := Upgrade(w, r); err == nil {
+			t.Error("expected an error")
+		}
+	}))
+	defer server.Close()
+
+	request, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, http.NoBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", response.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestUpgrader_Origin(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		origin  string
+		want    int
+	}{
+		{name: "no origin", want: http.StatusSwitchingProtocols},
+		{name: "same origin", origin: "http://{host}", want: http.StatusSwitchingProtocols},
+		{name: "foreign origin", origin: "https://evil.example", want: http.StatusForbidden},
+		{name: "allowed origin", allowed: []string{"http://localhost:3000"}, origin: "http://localhost:3000", want: http.StatusSwitchingProtocols},
+		{name: "other origin", allowed: []string{"http://localhost:3000"}, origin: "http://localhost:3001", want: http.StatusForbidden},
+		{name: "any origin", allowed: []string{"*"}, origin: "https://evil.example", want: http.StatusSwitchingProtocols},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upgrader := &Upgrader{AllowedOrigins: tt.allowed}
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				conn, err := upgrader.Upgrade(w, r)
+				if err != nil {
+					return
+				}
+				conn.Close()
+			}))
+			defer server.Close()
+
+			request, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, http.NoBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			request.Header.Set("Upgrade", "websocket")
+			request.Header.Set("Connection", "Upgrade")
+			request.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+			request.Header.Set("Sec-WebSocket-Version", "13")
+			if tt.origin != "" {
+				request.Header.Set("Origin", strings.ReplaceAll(tt.origin, "{host}", request.URL.Host))
+			}
+
+			response, err := http.DefaultClient.Do(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer response.Body.Close()
+
+			if response.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", response.StatusCode, tt.want)
+			}
+		})
+	}
+}
@@ -6,22 +6,46 @@ import (
 	"io"
 	"log"
 	"os"
+	"strings"
 
-	"github.com/armsnyder/openapi-language-server/internal/analysis"
-	"github.com/armsnyder/openapi-language-server/internal/lsp"
-	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/jsonrpc"
+	"github.com/armsnyder/openapi-language-server/internal/recording"
 )
 
 // NOTE(asnyder): version is set by goreleaser using ldflags.
 var version = "development"
 
+// commands are the subcommands of the CLI. Without a subcommand, the language
+// server is started.
+var commands = map[string]func(args []string) int{
+	"bundle": bundleCommand,
+	"check":  checkCommand,
+	"format": formatCommand,
+	"replay": replayCommand,
+}
+
 func main() {
+	// Run a subcommand.
+
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			os.Exit(command(os.Args[2:]))
+		}
+	}
+
 	// Parse command line flags.
 
 	var args struct {
-		version  bool
-		help     bool
-		testdata string
+		version   bool
+		help      bool
+		testdata  string
+		record    string
+		stdio     bool
+		listen    string
+		socket    string
+		pipe      string
+		websocket string
+		origins   string
 	}
 
 	flag.BoolVar(&args.version, "version", false, "Print the version and exit")
@@ -29,6 +53,13 @@ func main() {
 	flag.BoolVar(&args.help, "help", false, "Print this help message and exit")
 	flag.BoolVar(&args.help, "h", false, "Print this help message and exit")
 	flag.StringVar(&args.testdata, "testdata", "", "Capture a copy of all input and output to the specified directory. Useful for debugging or generating test data.")
+	flag.StringVar(&args.record, "record", "", "Record the session to the specified file, with one JSON message per line, for the replay command")
+	flag.BoolVar(&args.stdio, "stdio", false, "Communicate over standard input and output (default)")
+	flag.StringVar(&args.listen, "listen", "", "Listen for clients on an address, either tcp://HOST:PORT or unix:///PATH")
+	flag.StringVar(&args.socket, "socket", "", "Listen for clients on a Unix socket at the specified path")
+	flag.StringVar(&args.pipe, "pipe", "", "Connect to a client that is listening on the specified named pipe")
+	flag.StringVar(&args.websocket, "websocket", "", "Listen for WebSocket clients on an address, such as 127.0.0.1:7777")
+	flag.StringVar(&args.origins, "websocket-origins", "", "Comma-separated origins of the pages that may connect to -websocket, such as http://localhost:3000, or * for any")
 
 	flag.Parse()
 
@@ -49,11 +80,44 @@ func main() {
 
 	log.SetFlags(log.Lshortfile)
 
+	// Serve clients that connect over a socket.
+
+	if countSet(args.stdio, args.listen != "", args.socket != "", args.pipe != "", args.websocket != "") > 1 {
+		log.Fatal("Only one of -stdio, -listen, -socket, -pipe and -websocket may be set")
+	}
+
+	if (args.testdata != "" || args.record != "") && (args.listen != "" || args.socket != "" || args.websocket != "") {
+		log.Fatal("-testdata and -record cannot be used with -listen, -socket or -websocket")
+	}
+
+	switch {
+	case args.listen != "":
+		network, address, err := parseListenAddress(args.listen)
+		if err != nil {
+			log.Fatal("Invalid -listen address: ", err)
+		}
+		log.Fatal("Listener error: ", listen(network, address))
+	case args.socket != "":
+		log.Fatal("Listener error: ", listen("unix", args.socket))
+	case args.websocket != "":
+		log.Fatal("WebSocket server error: ", listenWebSocket(args.websocket, splitList(args.origins)))
+	}
+
 	// Configure input and output.
 
 	var reader io.Reader = os.Stdin
 	var writer io.Writer = os.Stdout
 
+	if args.pipe != "" {
+		conn, err := dialPipe(args.pipe)
+		if err != nil {
+			log.Fatal("Failed to connect to pipe: ", err)
+		}
+		defer conn.Close()
+
+		reader, writer = conn, conn
+	}
+
 	if args.testdata != "" {
 		if err := os.MkdirAll(args.testdata, 0o755); err != nil {
 			log.Fatal("Failed to create testdata directory: ", err)
@@ -78,47 +142,42 @@ func main() {
 
 	// Run the LSP server.
 
-	server := &lsp.Server{
-		ServerInfo: types.ServerInfo{
-			Name:    "openapi-language-server",
-			Version: version,
-		},
-		Reader:  reader,
-		Writer:  writer,
-		Handler: &analysis.Handler{},
+	stream := jsonrpc.NewHeaderStream(reader, writer)
+
+	if args.record != "" {
+		recordFile, err := os.Create(args.record)
+		if err != nil {
+			log.Fatal("Failed to create recording: ", err)
+		}
+		defer recordFile.Close()
+
+		stream = recording.NewRecorder(stream, recordFile)
 	}
 
-	if err := server.Run(); err != nil {
+	if err := serve(stream); err != nil {
 		log.Fatal("LSP server error: ", err)
 	}
 }
 
-// shadowReader wraps a primary reader and splits the input to a secondary
-// writer.
-type shadowReader struct {
-	reader io.Reader
-	shadow io.Writer
-}
-
-func (r shadowReader) Read(p []byte) (n int, err error) {
-	n, err = r.reader.Read(p)
-	_, _ = r.shadow.Write(p[:n])
-	return n, err
-}
-
-var _ io.Reader = shadowReader{}
-
-// shadowWriter wraps a primary writer and splits the output to a secondary
-// writer.
-type shadowWriter struct {
-	writer io.Writer
-	shadow io.Writer
+// countSet returns the number of flags that are set.
+func countSet(flags ...bool) int {
+	n := 0
+	for _, set := range flags {
+		if set {
+			n++
+		}
+	}
+	return n
 }
 
-func (w shadowWriter) Write(p []byte) (n int, err error) {
-	n, err = w.writer.Write(p)
-	_, _ = w.shadow.Write(p)
-	return n, err
+// splitList splits a comma-separated flag into its items, ignoring spaces and
+// empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
-
-var _ io.Writer = shadowWriter{}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/armsnyder/openapi-language-server/internal/recording"
+)
+
+// replayCommand drives a fresh server with the client messages of a session
+// that was recorded with -record, and reports where the server's messages
+// differ from the recording.
+func replayCommand(args []string) int {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	verbose := flags.Bool("v", false, "Print the log of the server to standard error")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: openapi-language-server replay [flags] FILE")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	f, err := os.Open(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error opening recording:", err)
+		return 2
+	}
+	defer f.Close()
+
+	entries, err := recording.Read(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", flags.Arg(0), err)
+		return 2
+	}
+
+	if *verbose {
+		log.SetFlags(log.Lshortfile)
+	} else {
+		log.SetOutput(io.Discard)
+	}
+
+	differences, err := recording.Replay(entries, serve)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "LSP server error:", err)
+		return 1
+	}
+
+	for _, d := range differences {
+		after := "Before the first message"
+		if d.After >= 0 {
+			after = fmt.Sprintf("After message %d (%s)", d.After+1, describeMessage(entries[d.After].Message))
+		}
+
+		fmt.Fprintf(os.Stdout, "%s:\n  want: %s\n  got:  %s\n", after, orNone(d.Want), orNone(d.Got))
+	}
+
+	switch len(differences) {
+	case 0:
+		return 0
+	case 1:
+		fmt.Fprintln(os.Stdout, "1 message differs from the recording")
+	default:
+		fmt.Fprintf(os.Stdout, "%d messages differ from the recording\n", len(differences))
+	}
+
+	return 1
+}
+
+// describeMessage returns the method and ID of a message.
+func describeMessage(message json.RawMessage) string {
+	var m struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+	}
+
+	if err := json.Unmarshal(message, &m); err != nil || m.Method == "" {
+		return "unknown method"
+	}
+
+	if len(m.ID) > 0 {
+		return fmt.Sprintf("%s - %s", m.Method, m.ID)
+	}
+
+	return m.Method
+}
+
+func orNone(message json.RawMessage) string {
+	if message == nil {
+		return "(none)"
+	}
+
+	return string(message)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/armsnyder/openapi-language-server/internal/analysis"
+	"github.com/armsnyder/openapi-language-server/internal/lsp"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/jsonrpc"
+	"github.com/armsnyder/openapi-language-server/internal/lsp/types"
+	"github.com/armsnyder/openapi-language-server/internal/websocket"
+)
+
+// serve runs a language server on a single connection until the client shuts
+// it down. Each connection has its own handler, so clients do not share open
+// documents.
+func serve(stream jsonrpc.Stream) error {
+	handler := &analysis.Handler{}
+	defer handler.Close()
+
+	server := &lsp.Server{
+		ServerInfo: types.ServerInfo{
+			Name:    "openapi-language-server",
+			Version: version,
+		},
+		ConfigurationSection: "openapi",
+		WatchedFiles:         []string{"**/" + analysis.ConfigFileName},
+		Stream:               stream,
+		Handler:              handler,
+	}
+
+	return server.Run()
+}
+
+// parseListenAddress parses the address of the --listen flag, which is either
+// tcp://HOST:PORT or unix:///PATH, into the arguments of net.Listen.
+func parseListenAddress(address string) (network, addr string, err error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", "", err
+	}
+
+	switch u.Scheme {
+	case "tcp":
+		if u.Host == "" {
+			return "", "", fmt.Errorf("missing host and port in %q", address)
+		}
+		return "tcp", u.Host, nil
+	case "unix":
+		if u.Path == "" {
+			return "", "", fmt.Errorf("missing path in %q", address)
+		}
+		return "unix", u.Path, nil
+	default:
+		return "", "", fmt.Errorf("unsupported address %q: expected tcp://HOST:PORT or unix:///PATH", address)
+	}
+}
+
+// listen accepts connections on the given address and serves each of them
+// concurrently. It only returns if the listener fails.
+func listen(network, address string) error {
+	if network == "unix" {
+		if err := removeStaleSocket(address); err != nil {
+			return err
+		}
+	}
+
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	log.Printf("Listening on %s %s", network, listener.Addr())
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+
+		go func() {
+			defer conn.Close()
+
+			log.Printf("Accepted connection from %s", conn.RemoteAddr())
+
+			if err := serve(jsonrpc.NewHeaderStream(conn, conn)); err != nil {
+				log.Printf("LSP server error on connection from %s: %v", conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+// listenWebSocket serves clients that connect over WebSocket, such as editors
+// that run in a browser. Each message is sent in its own text frame, without
+// Content-Length headers. Pages may only connect from the origin of the server
+// or one of the allowed origins. It only returns if the server fails.
+func listenWebSocket(address string, origins []string) error {
+	upgrader := &websocket.Upgrader{AllowedOrigins: origins}
+
+	server := &http.Server{
+		Addr:              address,
+		ReadHeaderTimeout: 10 * time.Second,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			conn, err := upgrader.Upgrade(w, r)
+			if err != nil {
+				log.Printf("Rejected WebSocket connection from %s: %v", r.RemoteAddr, err)
+				return
+			}
+			defer conn.Close()
+
+			log.Printf("Accepted WebSocket connection from %s", r.RemoteAddr)
+
+			if err := serve(conn); err != nil {
+				log.Printf("LSP server error on WebSocket connection from %s: %v", r.RemoteAddr, err)
+			}
+		}),
+	}
+
+	log.Printf("Listening for WebSocket connections on %s", address)
+
+	return server.ListenAndServe()
+}
+
+// removeStaleSocket removes a Unix socket that was left behind by a server
+// that did not exit cleanly, so that the address can be reused. Sockets that a
+// server still listens on, and other files, are left alone.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if info.Mode()&fs.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is in use by another server", path)
+	}
+
+	return os.Remove(path)
+}
+
+// dialPipe connects to a client that is listening on a named pipe, which is a
+// Unix socket on Unix-like systems.
+func dialPipe(path string) (net.Conn, error) {
+	return net.Dial("unix", path)
+}
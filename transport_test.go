@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseListenAddress(t *testing.T) {
+	tests := []struct {
+		address     string
+		wantNetwork string
+		wantAddr    string
+		wantErr     string
+	}{
+		{address: "tcp://127.0.0.1:7777", wantNetwork: "tcp", wantAddr: "127.0.0.1:7777"},
+		{address: "tcp://localhost:0", wantNetwork: "tcp", wantAddr: "localhost:0"},
+		{address: "unix:///tmp/openapi.sock", wantNetwork: "unix", wantAddr: "/tmp/openapi.sock"},
+		{address: "tcp://", wantErr: "missing host and port"},
+		{address: "unix://", wantErr: "missing path"},
+		{address: "http://127.0.0.1:7777", wantErr: "unsupported address"},
+		{address: "/tmp/openapi.sock", wantErr: "unsupported address"},
+		{address: "127.0.0.1:7777", wantErr: "first path segment"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			network, addr, err := parseListenAddress(tt.address)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("parseListenAddress() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if network != tt.wantNetwork || addr != tt.wantAddr {
+				t.Errorf("parseListenAddress() = %q, %q, want %q, %q", network, addr, tt.wantNetwork, tt.wantAddr)
+			}
+		})
+	}
+}
+
+// listenUnix listens on a Unix socket at the given path until the test ends.
+func listenUnix(t *testing.T, path string) *net.UnixListener {
+	t.Helper()
+
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	return listener
+}
+
+func TestRemoveStaleSocket(t *testing.T) {
+	tests := []struct {
+		name       string
+		setup      func(t *testing.T, path string)
+		wantErr    string
+		wantExists bool
+	}{
+		{
+			name:  "missing",
+			setup: func(*testing.T, string) {},
+		},
+		{
+			name: "stale socket",
+			setup: func(t *testing.T, path string) {
+				// Closing the listener without unlinking leaves the socket
+				// behind, as a server that crashed would.
+				listener := listenUnix(t, path)
+				listener.SetUnlinkOnClose(false)
+				listener.Close()
+			},
+		},
+		{
+			name: "live socket",
+			setup: func(t *testing.T, path string) {
+				listenUnix(t, path)
+			},
+			wantErr:    "in use by another server",
+			wantExists: true,
+		},
+		{
+			name: "regular file",
+			setup: func(t *testing.T, path string) {
+				if err := os.WriteFile(path, nil, 0o600); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr:    "is not a socket",
+			wantExists: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "server.sock")
+			tt.setup(t, path)
+
+			err := removeStaleSocket(path)
+
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("removeStaleSocket() error = %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("removeStaleSocket() error = %v, want %q", err, tt.wantErr)
+			}
+
+			_, err = os.Lstat(path)
+			if exists := !errors.Is(err, fs.ErrNotExist); exists != tt.wantExists {
+				t.Errorf("socket exists = %v, want %v", exists, tt.wantExists)
+			}
+		})
+	}
+}
+
+func TestListen_LiveSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.sock")
+	listenUnix(t, path)
+
+	if err := listen("unix", path); err == nil || !strings.Contains(err.Error(), "in use by another server") {
+		t.Errorf("listen() error = %v, want the socket to be in use", err)
+	}
+}
+
+func TestListen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.sock")
+
+	// The listener only returns if it fails, so it outlives the test.
+	errs := make(chan error, 1)
+	go func() { errs <- listen("unix", path) }()
+
+	var conn net.Conn
+	for deadline := time.Now().Add(5 * time.Second); conn == nil; {
+		var err error
+		if conn, err = net.Dial("unix", path); err != nil {
+			select {
+			case err := <-errs:
+				t.Fatalf("listen() error = %v", err)
+			default:
+			}
+
+			if time.Now().After(deadline) {
+				t.Fatal(err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	defer conn.Close()
+
+	request := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`
+	if _, err := fmt.Fprintf(conn, "Content-Length: %d\r\n\r\n%s", len(request), request); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := bufio.NewReader(conn)
+
+	var length int
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if line == "\r\n" {
+			break
+		}
+
+		_, _ = fmt.Sscanf(line, "Content-Length: %d", &length)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(body), `"id":1`) || !strings.Contains(string(body), `"capabilities"`) {
+		t.Errorf("response = %s, want the capabilities of the server", body)
+	}
+}